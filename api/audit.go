@@ -0,0 +1,64 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/logging"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type auditedKey struct{}
+
+// recordAudit adds a change made by the request to the audit trail. before
+// and after are the target as it was and as it is now, nil when it did not or
+// no longer exists. The change has happened by then, so a failure to record
+// it is logged rather than returned to the client.
+func recordAudit(c *fiber.Ctx, store db.AuditStore, action, targetType, targetID string, before, after any) {
+	c.Context().SetUserValue(auditedKey{}, true)
+
+	changes, err := types.AuditDiff(before, after)
+	if err != nil {
+		slog.ErrorContext(c.Context(), "diffing audit record failed", "action", action, "err", err)
+	}
+	record := &types.AuditRecord{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    changes,
+		IP:         c.IP(),
+		RequestID:  logging.RequestID(c.Context()),
+		CreatedAt:  time.Now(),
+	}
+	if user, ok := c.Context().Value("user").(*types.User); ok {
+		record.ActorID = user.ID
+	}
+	if _, err := store.AddRecord(c.Context(), record); err != nil {
+		slog.ErrorContext(c.Context(), "recording audit record failed", "action", action, "targetID", targetID, "err", err)
+	}
+}
+
+// AuditAdmin records the successful mutating requests whose handlers did not
+// record a more detailed audit record themselves, so every admin endpoint is
+// audited, including ones added later.
+func AuditAdmin(store db.AuditStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return c.Next()
+		}
+
+		if err := c.Next(); err != nil {
+			sendError(c, err)
+		}
+		if c.Response().StatusCode() >= http.StatusBadRequest || c.Context().UserValue(auditedKey{}) != nil {
+			return nil
+		}
+		recordAudit(c, store, c.Method()+" "+c.Route().Path, "", c.Params("id"), nil, nil)
+
+		return nil
+	}
+}
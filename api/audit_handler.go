@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type AuditHandler struct {
+	auditStore db.AuditStore
+}
+
+func NewAuditHandler(auditStore db.AuditStore) *AuditHandler {
+	return &AuditHandler{
+		auditStore: auditStore,
+	}
+}
+
+// admin auth, newest records first. The next page starts after the seq of
+// the last record received, passed as beforeSeq.
+func (h *AuditHandler) HandleListRecords(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if actorID := c.Query("actorID"); len(actorID) > 0 {
+		oid, err := primitive.ObjectIDFromHex(actorID)
+		if err != nil {
+			return ErrorInvalidID()
+		}
+		filter["actorID"] = oid
+	}
+	for _, field := range []string{"action", "targetType", "targetID", "ip", "requestID"} {
+		if value := c.Query(field); len(value) > 0 {
+			filter[field] = value
+		}
+	}
+
+	createdAt := bson.M{}
+	for param, op := range map[string]string{"from": "$gte", "till": "$lt"} {
+		value := c.Query(param)
+		if len(value) == 0 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return NewError(http.StatusBadRequest, "Invalid "+param+": expected an RFC 3339 time")
+		}
+		createdAt[op] = t
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	if beforeSeq := c.Query("beforeSeq"); len(beforeSeq) > 0 {
+		seq, err := strconv.ParseInt(beforeSeq, 10, 64)
+		if err != nil {
+			return NewError(http.StatusBadRequest, "Invalid beforeSeq")
+		}
+		filter["seq"] = bson.M{"$lt": seq}
+	}
+
+	limit := c.QueryInt("limit", defaultAuditLimit)
+	if limit <= 0 || limit > maxAuditLimit {
+		return NewError(http.StatusBadRequest, "Invalid limit: expected 1 to "+strconv.Itoa(maxAuditLimit))
+	}
+
+	records, err := h.auditStore.GetRecords(c.Context(), filter, int64(limit))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(records)
+}
+
+// admin auth
+func (h *AuditHandler) HandleVerifyChain(c *fiber.Ctx) error {
+	verification, err := h.auditStore.VerifyChain(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(verification)
+}
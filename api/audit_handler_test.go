@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAuditTrailRecordsChangesAndDetectsTampering(t *testing.T) {
+	tdb := setup(t)
+	defer tdb.teardown(t)
+
+	var (
+		app         = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		adminUser   = fixtures.AddUser(tdb.store, "jack", "bauer", true)
+		user        = fixtures.AddUser(tdb.store, "james", "bond", false)
+		userHandler = NewUserHandler(tdb.store.User, tdb.store.Audit)
+		handler     = NewAuditHandler(tdb.store.Audit)
+		apiv1       = app.Group("/", JWTAuthentication(tdb.store.User, testConfig.Auth))
+		admin       = apiv1.Group("/admin", AdminAuth, AuditAdmin(tdb.store.Audit))
+	)
+	apiv1.Put("/user/:id", userHandler.HandleUpdateUser)
+	admin.Post("/thing/:id", func(c *fiber.Ctx) error {
+		return c.JSON(map[string]string{"msg": "done"})
+	})
+	admin.Get("/audit", handler.HandleListRecords)
+	admin.Get("/audit/verify", handler.HandleVerifyChain)
+
+	get := func(path string, v any) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200 from %s but got %d", path, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, _ := json.Marshal(types.UpdateUserParams{FirstName: "Jimmy"})
+	req := httptest.NewRequest(http.MethodPut, "/user/"+user.ID.Hex(), bytes.NewReader(b))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	postJSON(t, app, "/admin/thing/42", adminUser, nil)
+
+	var records []*types.AuditRecord
+	get("/admin/audit?action="+types.AuditUserUpdate+"&targetID="+user.ID.Hex(), &records)
+	if len(records) != 1 || records[0].ActorID != adminUser.ID {
+		t.Fatalf("expected the update by the admin to be recorded, got %+v", records)
+	}
+	changes := records[0].Changes
+	if len(changes) != 1 || changes[0].Field != "firstName" || string(changes[0].Before) != `"james"` || string(changes[0].After) != `"Jimmy"` {
+		t.Fatalf("expected the first name change, got %+v", changes)
+	}
+
+	get("/admin/audit?targetID=42", &records)
+	if len(records) != 1 || records[0].Action != "POST /admin/thing/:id" {
+		t.Fatalf("expected the admin call to be recorded by the middleware, got %+v", records)
+	}
+
+	var verification types.AuditVerification
+	get("/admin/audit/verify", &verification)
+	if !verification.Valid || verification.Checked != 2 {
+		t.Fatalf("expected an intact chain of two records, got %+v", verification)
+	}
+
+	coll := tdb.client.Database(testConfig.DB.Name).Collection(db.AUDIT_COLLECTION)
+	if _, err := coll.UpdateOne(context.Background(), bson.M{"seq": 1}, bson.M{"$set": bson.M{"actorID": user.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	get("/admin/audit/verify", &verification)
+	if verification.Valid || verification.Error != "record 1 was modified" {
+		t.Fatalf("expected the edited record to break the chain, got %+v", verification)
+	}
+}
@@ -2,12 +2,16 @@ package api
 
 import (
 	"errors"
-	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
+	"github.com/aboronilov/go-hotel-reservation/authguard"
+	"github.com/aboronilov/go-hotel-reservation/config"
 	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/metrics"
 	"github.com/aboronilov/go-hotel-reservation/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt"
@@ -16,11 +20,15 @@ import (
 
 type AuthHandler struct {
 	userStore db.UserStore
+	guard     *authguard.Guard
+	auth      config.Auth
 }
 
-func NewAuthHandler(userStore db.UserStore) *AuthHandler {
+func NewAuthHandler(userStore db.UserStore, guard *authguard.Guard, auth config.Auth) *AuthHandler {
 	return &AuthHandler{
 		userStore: userStore,
+		guard:     guard,
+		auth:      auth,
 	}
 }
 
@@ -34,6 +42,19 @@ type AuthResponse struct {
 	User  *types.User `json:"user"`
 }
 
+// ChallengeResponse is returned instead of a token when the user has to
+// enter a two-factor code. ChallengeToken is exchanged for the token by
+// HandleVerifyTwoFactor.
+type ChallengeResponse struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+}
+
+type TwoFactorParams struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
 type genericResponse struct {
 	Message string `json:"message"`
 	Type    string `json:"type"`
@@ -52,39 +73,201 @@ func (h *AuthHandler) HandleAuthenticate(c *fiber.Ctx) error {
 		return err
 	}
 
+	if err := throttle(c, h.guard, authParams.Email); err != nil {
+		return err
+	}
+
 	user, err := h.userStore.GetUserByEmail(c.Context(), authParams.Email)
 	if err != nil {
-		fmt.Println("err: ", err)
 		if errors.Is(err, mongo.ErrNoDocuments) {
+			slog.InfoContext(c.Context(), "login failed", "reason", "unknown email")
+			metrics.FailedLogins.WithLabelValues("unknown_email").Inc()
+			if err := h.guard.Failed(c.Context(), c.IP(), authParams.Email, nil); err != nil {
+				return err
+			}
 			return invalidCredentials(c)
 		}
 		return err
 	}
 
+	if user.IsLocked(time.Now()) {
+		return locked(c, h.guard, user)
+	}
+
 	if !types.IsValidPassword(user.HashedPassword, authParams.Password) {
+		slog.InfoContext(c.Context(), "login failed", "reason", "wrong password", "userID", user.ID.Hex())
+		metrics.FailedLogins.WithLabelValues("wrong_password").Inc()
+		if err := h.guard.Failed(c.Context(), c.IP(), authParams.Email, user); err != nil {
+			return err
+		}
 		return invalidCredentials(c)
 	}
+	// failures in a row are only forgotten once the second factor passed
+	// too, so logging in again does not reset the count of wrong codes
+	if user.TwoFactorEnabled {
+		return c.JSON(ChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    createChallengeToken(user, h.auth),
+		})
+	}
+	if err := h.guard.Succeeded(c.Context(), authParams.Email); err != nil {
+		return err
+	}
 
 	response := AuthResponse{
-		Token: CreateTokenFromUser(user),
+		Token: CreateTokenFromUser(user, h.auth),
 		User:  user,
 	}
 
 	return c.JSON(response)
 }
 
-func CreateTokenFromUser(user *types.User) string {
+// HandleVerifyTwoFactor completes a two-factor login: it exchanges the
+// challenge token and a code from the authenticator app, or a recovery code,
+// for an auth token. Wrong codes count as failed logins.
+func (h *AuthHandler) HandleVerifyTwoFactor(c *fiber.Ctx) error {
+	var params TwoFactorParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+
+	claims, err := validateToken(params.ChallengeToken, h.auth.JWTSecret)
+	if err != nil || claims["purpose"] != challengePurpose {
+		return ErrorUnauthorized()
+	}
+	expires, _ := claims["expires"].(float64)
+	if time.Now().Unix() > int64(expires) {
+		return NewError(http.StatusUnauthorized, "Login expired, log in again")
+	}
+	userID, _ := claims["id"].(string)
+	user, err := h.userStore.GetUserByID(c.Context(), userID)
+	if err != nil || !user.TwoFactorEnabled {
+		return ErrorUnauthorized()
+	}
+
+	if err := throttle(c, h.guard, user.Email); err != nil {
+		return err
+	}
+	if user.IsLocked(time.Now()) {
+		return locked(c, h.guard, user)
+	}
+
+	recovery, ok, err := verifySecondFactor(c.Context(), h.userStore, user, params.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return wrongCode(c, h.guard, user)
+	}
+	if recovery {
+		if err := h.guard.RecoveryCodeUsed(c.Context(), c.IP(), user); err != nil {
+			return err
+		}
+	}
+	if err := h.guard.Succeeded(c.Context(), user.Email); err != nil {
+		return err
+	}
+
+	return c.JSON(AuthResponse{
+		Token: CreateTokenFromUser(user, h.auth),
+		User:  user,
+	})
+}
+
+// throttle applies the login budgets of the guard and slows down answers to
+// guessing. Every endpoint that checks a password or code goes through it.
+func throttle(c *fiber.Ctx, guard *authguard.Guard, email string) error {
+	delay, err := guard.Attempt(c.Context(), c.IP(), email)
+	var limited *authguard.LimitError
+	if errors.As(err, &limited) {
+		metrics.FailedLogins.WithLabelValues("rate_limited").Inc()
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+		return NewError(http.StatusTooManyRequests, "Too many login attempts, try again later")
+	}
+	if err != nil {
+		return err
+	}
+	// without holding the request past a shutdown
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-c.Context().Done():
+		}
+	}
+
+	return nil
+}
+
+func locked(c *fiber.Ctx, guard *authguard.Guard, user *types.User) error {
+	slog.InfoContext(c.Context(), "login failed", "reason", "account locked", "userID", user.ID.Hex())
+	metrics.FailedLogins.WithLabelValues("locked").Inc()
+	if err := guard.Locked(c.Context(), c.IP(), user); err != nil {
+		return err
+	}
+	return NewError(http.StatusLocked, "Account is locked after too many failed logins, follow the link we emailed you to unlock it")
+}
+
+// wrongCode counts a wrong two-factor code as a failed login, so guessing
+// codes locks the account like guessing passwords does.
+func wrongCode(c *fiber.Ctx, guard *authguard.Guard, user *types.User) error {
+	slog.InfoContext(c.Context(), "login failed", "reason", "wrong two-factor code", "userID", user.ID.Hex())
+	metrics.FailedLogins.WithLabelValues("wrong_code").Inc()
+	if err := guard.Failed(c.Context(), c.IP(), user.Email, user); err != nil {
+		return err
+	}
+	return NewError(http.StatusBadRequest, "Invalid code")
+}
+
+// HandleUnlock unlocks an account from the link in the lockout email.
+func (h *AuthHandler) HandleUnlock(c *fiber.Ctx) error {
+	_, err := h.guard.Unlock(c.Context(), c.IP(), c.Params("token"))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return NewError(http.StatusNotFound, "Unlock link is invalid or was used already")
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(genericResponse{
+		Message: "Account unlocked, you can log in again",
+		Type:    "success",
+	})
+}
+
+// CreateTokenFromUser issues an auth token. Users with two-factor
+// authentication only get one after entering a code, so their tokens are
+// marked as such.
+func CreateTokenFromUser(user *types.User, auth config.Auth) string {
 	now := time.Now()
-	expires := now.Add(time.Hour * 72).Unix()
+	expires := now.Add(auth.TokenTTL).Unix()
 	claims := jwt.MapClaims{
 		"id":      user.ID,
 		"expires": expires,
+		"mfa":     user.TwoFactorEnabled,
 	}
 
+	return signToken(claims, auth)
+}
+
+// challengePurpose marks the tokens that are only good for entering a
+// two-factor code.
+const challengePurpose = "2fa"
+
+func createChallengeToken(user *types.User, auth config.Auth) string {
+	claims := jwt.MapClaims{
+		"id":      user.ID,
+		"expires": time.Now().Add(auth.ChallengeTTL).Unix(),
+		"purpose": challengePurpose,
+	}
+
+	return signToken(claims, auth)
+}
+
+func signToken(claims jwt.MapClaims, auth config.Auth) string {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokenStr, err := token.SignedString([]byte(auth.JWTSecret))
 	if err != nil {
-		fmt.Println("Failed signing token")
+		slog.Error("signing auth token failed", "err", err)
 	}
 
 	return tokenStr
@@ -2,14 +2,20 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
 
+	"github.com/aboronilov/go-hotel-reservation/authguard"
+	"github.com/aboronilov/go-hotel-reservation/config"
+	"github.com/aboronilov/go-hotel-reservation/db"
 	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestAuthenticateSuccess(t *testing.T) {
@@ -20,7 +26,7 @@ func TestAuthenticateSuccess(t *testing.T) {
 	// fmt.Println("insertedUser --->", insertedUser)
 
 	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
-	authHandler := NewAuthHandler(tdb.store.User)
+	authHandler := NewAuthHandler(tdb.store.User, newTestGuard(tdb.store, testConfig.Auth, &lockoutRecorder{}), testConfig.Auth)
 	app.Post("/auth", authHandler.HandleAuthenticate)
 
 	authParams := AuthParams{
@@ -63,7 +69,7 @@ func TestAuthenticateFailsWithWrongPassword(t *testing.T) {
 	fixtures.AddUser(tdb.store, "james", "bond", true)
 
 	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
-	authHandler := NewAuthHandler(tdb.store.User)
+	authHandler := NewAuthHandler(tdb.store.User, newTestGuard(tdb.store, testConfig.Auth, &lockoutRecorder{}), testConfig.Auth)
 	app.Post("/auth", authHandler.HandleAuthenticate)
 
 	authParams := AuthParams{
@@ -102,7 +108,7 @@ func TestAuthenticateFailsWithWrongEmail(t *testing.T) {
 	fixtures.AddUser(tdb.store, "james", "bond", true)
 
 	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
-	authHandler := NewAuthHandler(tdb.store.User)
+	authHandler := NewAuthHandler(tdb.store.User, newTestGuard(tdb.store, testConfig.Auth, &lockoutRecorder{}), testConfig.Auth)
 	app.Post("/auth", authHandler.HandleAuthenticate)
 
 	authParams := AuthParams{
@@ -134,3 +140,73 @@ func TestAuthenticateFailsWithWrongEmail(t *testing.T) {
 		t.Fatalf("expected type 'error', got '%s'", genericResponse.Type)
 	}
 }
+
+type lockoutRecorder struct {
+	tokens []string
+}
+
+func (r *lockoutRecorder) NotifyLockout(ctx context.Context, user *types.User, unlockToken string) error {
+	r.tokens = append(r.tokens, unlockToken)
+	return nil
+}
+
+func newTestGuard(store *db.Store, auth config.Auth, notifier authguard.Notifier) *authguard.Guard {
+	return authguard.NewGuard(store, authguard.NewMemoryCounter(), notifier, auth)
+}
+
+func TestAuthenticateLocksOutAndUnlocks(t *testing.T) {
+	tdb := setup(t)
+	defer tdb.teardown(t)
+	fixtures.AddUser(tdb.store, "james", "bond", false)
+
+	var (
+		app      = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		auth     = testConfig.Auth
+		notifier = &lockoutRecorder{}
+	)
+	auth.MaxFailures = 2
+	authHandler := NewAuthHandler(tdb.store.User, newTestGuard(tdb.store, auth, notifier), auth)
+	app.Post("/auth", authHandler.HandleAuthenticate)
+	app.Get("/auth/unlock/:token", authHandler.HandleUnlock)
+
+	login := func(password string) int {
+		b, _ := json.Marshal(AuthParams{Email: "james_bond@ctu.com", Password: password})
+		req := httptest.NewRequest("POST", "/auth", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	for range auth.MaxFailures {
+		if status := login("wrong_password"); status != http.StatusBadRequest {
+			t.Fatalf("expected status code 400, got %d", status)
+		}
+	}
+	if status := login("james_bond"); status != http.StatusLocked {
+		t.Fatalf("expected the account to be locked, got %d", status)
+	}
+	if len(notifier.tokens) != 1 {
+		t.Fatalf("expected one unlock email, got %d", len(notifier.tokens))
+	}
+	records, err := tdb.client.Database(testConfig.DB.Name).Collection(db.AUDIT_COLLECTION).CountDocuments(context.Background(), bson.M{"action": types.AuditLoginLockout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records != 1 {
+		t.Fatalf("expected the lockout in the audit trail, got %d records", records)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/auth/unlock/"+notifier.tokens[0], nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the account to be unlocked, got %d", resp.StatusCode)
+	}
+	if status := login("james_bond"); status != http.StatusOK {
+		t.Fatalf("expected to log in after unlocking, got %d", status)
+	}
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/availability"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/metrics"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const blockCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+type BlockHandler struct {
+	store    *db.Store
+	checker  *availability.Checker
+	waitlist *waitlist.Manager
+}
+
+func NewBlockHandler(store *db.Store, waitlist *waitlist.Manager) *BlockHandler {
+	return &BlockHandler{
+		store:    store,
+		checker:  availability.NewChecker(store),
+		waitlist: waitlist,
+	}
+}
+
+// admin auth
+func (h *BlockHandler) HandleCreateBlock(c *fiber.Ctx) error {
+	var params types.CreateRoomBlockParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	code, err := newBlockCode()
+	if err != nil {
+		return err
+	}
+	block := types.NewRoomBlockFromParams(code, params)
+
+	filter := bson.M{"hotelID": block.HotelID}
+	if len(block.RoomType) > 0 {
+		filter["size"] = block.RoomType
+	}
+	rooms, err := h.store.Room.GetRooms(c.Context(), filter)
+	if err != nil {
+		return err
+	}
+	for _, room := range rooms {
+		if len(block.RoomIDs) == block.RoomsPerNight {
+			break
+		}
+		ok, err := h.checker.IsRoomAvailable(c.Context(), availability.Request{
+			RoomID:   room.ID,
+			FromDate: block.FromDate,
+			TillDate: block.TillDate,
+		})
+		if err != nil {
+			return err
+		}
+		if ok {
+			block.RoomIDs = append(block.RoomIDs, room.ID)
+		}
+	}
+	if len(block.RoomIDs) < block.RoomsPerNight {
+		message := fmt.Sprintf("Only %d rooms are available for the block, %d requested", len(block.RoomIDs), block.RoomsPerNight)
+		return NewError(http.StatusBadRequest, message)
+	}
+
+	inserted, err := h.store.Block.CreateBlock(c.Context(), block)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(inserted)
+}
+
+// admin auth
+func (h *BlockHandler) HandleListBlocks(c *fiber.Ctx) error {
+	blocks, err := h.store.Block.GetBlocks(c.Context(), bson.M{})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(blocks)
+}
+
+func (h *BlockHandler) HandleRetrieveBlock(c *fiber.Ctx) error {
+	block, err := h.getBlock(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(block)
+}
+
+func (h *BlockHandler) HandleBookBlock(c *fiber.Ctx) error {
+	var params BookRoomParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if err := params.validate(); err != nil {
+		return ErrorBadRequest()
+	}
+
+	user, err := getAuthUser(c)
+	if err != nil {
+		return ErrorUnauthorized()
+	}
+
+	block, err := h.getBlock(c)
+	if err != nil {
+		return err
+	}
+	if !block.IsActive(time.Now()) {
+		return NewError(http.StatusBadRequest, "Room block has been released")
+	}
+	if !block.Covers(params.FromDate, params.TillDate) {
+		return NewError(http.StatusBadRequest, "Invalid dates: stay should be within the room block dates")
+	}
+
+	// a room the stay restrictions close is skipped for the next one; the
+	// restriction is only reported when no room could be booked
+	var (
+		restrictedRoom *types.Room
+		restricted     error
+	)
+	for _, roomID := range block.RoomIDs {
+		room, err := h.store.Room.GetRoomByID(c.Context(), roomID.Hex())
+		if err != nil {
+			return err
+		}
+		err = h.checker.CheckRestrictions(c.Context(), room, params.FromDate, params.TillDate)
+		var restriction *availability.RestrictionError
+		if errors.As(err, &restriction) {
+			restrictedRoom, restricted = room, err
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		ok, err := h.checker.IsRoomAvailable(c.Context(), availability.Request{
+			RoomID:     roomID,
+			UserID:     user.ID,
+			BlockID:    block.ID,
+			FromDate:   params.FromDate,
+			TillDate:   params.TillDate,
+			NumPersons: params.NumPersons,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		booking := types.Booking{
+			UserID:     user.ID,
+			RoomID:     roomID,
+			BlockID:    block.ID,
+			FromDate:   params.FromDate,
+			TillDate:   params.TillDate,
+			NumPersons: params.NumPersons,
+			Price:      float64(types.Nights(params.FromDate, params.TillDate)) * block.Rate,
+		}
+		inserted, err := h.store.Booking.BookRoom(c.Context(), &booking)
+		if err != nil {
+			return ErrorBadRequest()
+		}
+		recordAudit(c, h.store.Audit, types.AuditBookingCreate, "booking", inserted.ID.Hex(), nil, inserted)
+		if err := h.waitlist.Claim(c.Context(), user.ID, roomID); err != nil {
+			return err
+		}
+		return c.JSON(inserted)
+	}
+
+	if restricted != nil {
+		return restrictionError(restrictedRoom, restricted)
+	}
+	metrics.AvailabilityConflicts.WithLabelValues(metrics.ConflictUnavailable).Inc()
+	return NewError(http.StatusBadRequest, "No rooms left in the room block for these dates")
+}
+
+func (h *BlockHandler) getBlock(c *fiber.Ctx) (*types.RoomBlock, error) {
+	block, err := h.store.Block.GetBlockByCode(c.Context(), c.Params("code"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrorNotFound()
+		}
+		return nil, err
+	}
+
+	return block, nil
+}
+
+func newBlockCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = blockCodeAlphabet[int(b[i])%len(blockCodeAlphabet)]
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOnlyBlockAttendeesCanBookBlockedRooms(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user         = fixtures.AddUser(db.store, "john", "smith", false)
+		hotel        = fixtures.AddHotel(db.store, "ibis", "paris")
+		from         = time.Now().AddDate(0, 0, 10)
+		till         = time.Now().AddDate(0, 0, 12)
+		app          = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		route        = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		manager      = waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour)
+		blockHandler = NewBlockHandler(db.store, manager)
+		roomHandler  = NewRoomHandler(db.store, manager)
+	)
+	route.Post("/block/:code/book", blockHandler.HandleBookBlock)
+	route.Post("/room/:id/book", roomHandler.HandleBookRoom)
+
+	block, err := db.store.Block.CreateBlock(context.TODO(), &types.RoomBlock{
+		Code:          "CONF2026",
+		Name:          "conference",
+		HotelID:       hotel.ID,
+		RoomsPerNight: 1,
+		RoomIDs:       hotel.Rooms[:1],
+		Rate:          80,
+		FromDate:      from.AddDate(0, 0, -1),
+		TillDate:      till.AddDate(0, 0, 1),
+		ReleaseDate:   time.Now().AddDate(0, 0, 5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := BookRoomParams{
+		FromDate:   from,
+		TillDate:   till,
+		NumPersons: 1,
+	}
+	b, _ := json.Marshal(params)
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/room/%s/book", hotel.Rooms[0].Hex()), bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected blocked room to be unavailable, got %d", resp.StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/block/%s/book", block.Code), bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+
+	var booking types.Booking
+	if err = json.NewDecoder(resp.Body).Decode(&booking); err != nil {
+		t.Fatal(err)
+	}
+	if booking.BlockID != block.ID || booking.RoomID != hotel.Rooms[0] {
+		t.Fatalf("expected booking against block room, got %+v", booking)
+	}
+}
+
+func TestBookBlockAppliesStayRestrictions(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user         = fixtures.AddUser(db.store, "john", "smith", false)
+		hotel        = fixtures.AddHotel(db.store, "ibis", "paris")
+		from         = time.Now().AddDate(0, 0, 10)
+		app          = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		route        = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		blockHandler = NewBlockHandler(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour))
+	)
+	route.Post("/block/:code/book", blockHandler.HandleBookBlock)
+
+	block, err := db.store.Block.CreateBlock(context.TODO(), &types.RoomBlock{
+		Code:          "CONF2026",
+		Name:          "conference",
+		HotelID:       hotel.ID,
+		RoomsPerNight: 1,
+		RoomIDs:       hotel.Rooms[:1],
+		Rate:          80,
+		FromDate:      from.AddDate(0, 0, -1),
+		TillDate:      from.AddDate(0, 0, 4),
+		ReleaseDate:   time.Now().AddDate(0, 0, 5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.store.Restriction.CreateRestriction(context.TODO(), &types.StayRestriction{
+		HotelID:  hotel.ID,
+		FromDate: types.Night(from),
+		TillDate: types.Night(from.AddDate(0, 0, 2)),
+		MinStay:  3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the restricted stay goes first, while the block's room is still free
+	cases := []struct {
+		nights int
+		status int
+		error  string
+	}{
+		{2, http.StatusBadRequest, "a minimum stay of 3 nights is required"},
+		{3, http.StatusOK, ""},
+	}
+	for _, tc := range cases {
+		params := BookRoomParams{
+			FromDate:   from,
+			TillDate:   from.AddDate(0, 0, tc.nights),
+			NumPersons: 1,
+		}
+		resp := postJSON(t, app, "/block/"+block.Code+"/book", user, params)
+		if resp.StatusCode != tc.status {
+			t.Fatalf("expected status code %d for a %d night stay, got %d", tc.status, tc.nights, resp.StatusCode)
+		}
+		if len(tc.error) == 0 {
+			continue
+		}
+		var body map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(body["error"], tc.error) {
+			t.Fatalf("expected the stay restriction to be reported, got %q", body["error"])
+		}
+	}
+}
@@ -2,8 +2,11 @@ package api
 
 import (
 	"errors"
+	"net/http"
 
 	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,12 +14,14 @@ import (
 )
 
 type BookinHandler struct {
-	store *db.Store
+	store    *db.Store
+	waitlist *waitlist.Manager
 }
 
-func NewBookingHandler(store *db.Store) *BookinHandler {
+func NewBookingHandler(store *db.Store, waitlist *waitlist.Manager) *BookinHandler {
 	return &BookinHandler{
-		store: store,
+		store:    store,
+		waitlist: waitlist,
 	}
 }
 
@@ -47,10 +52,31 @@ func (h *BookinHandler) HandleCancelBooking(c *fiber.Ctx) error {
 		return ErrorUnauthorized()
 	}
 
-	err = h.store.Booking.UpdateBooking(c.Context(), oid, bson.M{"canceled": true})
+	if booking.Canceled {
+		return NewError(http.StatusBadRequest, "Booking is canceled already")
+	}
+
+	// a concurrent cancellation may have won since the booking was read
+	canceled, err := h.store.Booking.CancelBooking(c.Context(), oid)
 	if err != nil {
 		return ErrorBadRequest()
 	}
+	if !canceled {
+		return NewError(http.StatusBadRequest, "Booking is canceled already")
+	}
+	after := *booking
+	after.Canceled = true
+	recordAudit(c, h.store.Audit, types.AuditBookingCancel, "booking", id, booking, &after)
+
+	if !booking.ItineraryID.IsZero() {
+		if err := refreshItinerary(c.Context(), h.store, booking.ItineraryID); err != nil {
+			return err
+		}
+	}
+
+	if err := h.waitlist.Release(c.Context(), booking.RoomID); err != nil {
+		return err
+	}
 
 	return c.JSON(map[string]string{"message": "Booking canceled"})
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
 	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -20,19 +21,19 @@ func TestAdminCanGetBookings(t *testing.T) {
 	var (
 		user           = fixtures.AddUser(db.store, "john", "smith", false)
 		adminUser      = fixtures.AddUser(db.store, "james", "bond", true)
-		hotel          = fixtures.AddHotel(db.store, "ibis", "paris", 5)
+		hotel          = fixtures.AddHotel(db.store, "ibis", "paris")
 		from           = time.Now().AddDate(0, 0, 1)
 		till           = time.Now().AddDate(0, 0, 6)
 		booking        = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], from, till)
 		app            = fiber.New()
-		admin          = app.Group("/", JWTAuthentication(db.store.User), AdminAuth)
-		bookingHandler = NewBookingHandler(db.store)
+		admin          = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth), AdminAuth)
+		bookingHandler = NewBookingHandler(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, waitlist.DefaultHoldDuration))
 	)
 
 	admin.Get("/", bookingHandler.HandleListBookings)
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
-	req.Header.Add("Authorization", CreateTokenFromUser(adminUser))
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
 	resp, err := app.Test(req)
 	if err != nil {
 		t.Fatal(err)
@@ -69,13 +70,13 @@ func TestUserCannotGetBookingsOfOtherUsers(t *testing.T) {
 
 	var (
 		user           = fixtures.AddUser(db.store, "john", "smith", false)
-		hotel          = fixtures.AddHotel(db.store, "ibis", "paris", 5)
+		hotel          = fixtures.AddHotel(db.store, "ibis", "paris")
 		from           = time.Now().AddDate(0, 0, 1)
 		till           = time.Now().AddDate(0, 0, 6)
 		booking        = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], from, till)
 		app            = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
-		admin          = app.Group("/", JWTAuthentication(db.store.User), AdminAuth)
-		bookingHandler = NewBookingHandler(db.store)
+		admin          = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth), AdminAuth)
+		bookingHandler = NewBookingHandler(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, waitlist.DefaultHoldDuration))
 	)
 
 	_ = booking
@@ -83,7 +84,7 @@ func TestUserCannotGetBookingsOfOtherUsers(t *testing.T) {
 	admin.Get("/", bookingHandler.HandleListBookings)
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
-	req.Header.Add("Authorization", CreateTokenFromUser(user))
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
 	resp, err := app.Test(req)
 	if err != nil {
 		t.Fatal(err)
@@ -99,19 +100,19 @@ func TestUserCanGetOwnBookings(t *testing.T) {
 
 	var (
 		user           = fixtures.AddUser(db.store, "john", "smith", false)
-		hotel          = fixtures.AddHotel(db.store, "ibis", "paris", 5)
+		hotel          = fixtures.AddHotel(db.store, "ibis", "paris")
 		from           = time.Now().AddDate(0, 0, 1)
 		till           = time.Now().AddDate(0, 0, 6)
 		booking        = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], from, till)
 		app            = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
-		route          = app.Group("/", JWTAuthentication(db.store.User))
-		bookingHandler = NewBookingHandler(db.store)
+		route          = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		bookingHandler = NewBookingHandler(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, waitlist.DefaultHoldDuration))
 	)
 
 	route.Get("/:id", bookingHandler.HandleRetrieveBooking)
 
 	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s", booking.ID.Hex()), nil)
-	req.Header.Add("Authorization", CreateTokenFromUser(user))
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
 	resp, err := app.Test(req)
 	if err != nil {
 		t.Fatal(err)
@@ -148,13 +149,13 @@ func TestUserWithoutAuthCantGetBookings(t *testing.T) {
 
 	var (
 		user           = fixtures.AddUser(db.store, "john", "smith", false)
-		hotel          = fixtures.AddHotel(db.store, "ibis", "paris", 5)
+		hotel          = fixtures.AddHotel(db.store, "ibis", "paris")
 		from           = time.Now().AddDate(0, 0, 1)
 		till           = time.Now().AddDate(0, 0, 6)
 		booking        = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], from, till)
 		app            = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
-		route          = app.Group("/", JWTAuthentication(db.store.User))
-		bookingHandler = NewBookingHandler(db.store)
+		route          = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		bookingHandler = NewBookingHandler(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, waitlist.DefaultHoldDuration))
 	)
 
 	route.Get("/:id", bookingHandler.HandleRetrieveBooking)
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/aboronilov/go-hotel-reservation/bulk"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/logging"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BulkHandler struct {
+	store *db.Store
+}
+
+type ImportResponse struct {
+	DryRun   bool            `json:"dryRun"`
+	Valid    int             `json:"valid"`
+	Imported int             `json:"imported"`
+	Errors   []bulk.RowError `json:"errors"`
+}
+
+func NewBulkHandler(store *db.Store) *BulkHandler {
+	return &BulkHandler{
+		store: store,
+	}
+}
+
+// admin auth
+func (h *BulkHandler) HandleImportHotels(c *fiber.Ctx) error {
+	format, err := bulk.ParseFormat(c.Query("format"))
+	if err != nil {
+		return NewError(http.StatusBadRequest, err.Error())
+	}
+
+	rows, rowErrors, err := bulk.DecodeHotels(bytes.NewReader(c.Body()), format)
+	if err != nil {
+		return NewError(http.StatusBadRequest, err.Error())
+	}
+
+	response := ImportResponse{
+		DryRun: c.QueryBool("dryRun"),
+		Valid:  len(rows),
+		Errors: rowErrors,
+	}
+	if response.DryRun || len(rowErrors) > 0 {
+		return importResult(c, response)
+	}
+
+	for _, row := range rows {
+		hotel := &types.Hotel{
+			Name:     row.Name,
+			Location: row.Location,
+			Rooms:    []primitive.ObjectID{},
+		}
+		if _, err := h.store.Hotel.CreateHotel(c.Context(), hotel); err != nil {
+			return err
+		}
+		response.Imported++
+	}
+
+	return importResult(c, response)
+}
+
+// admin auth
+func (h *BulkHandler) HandleImportRooms(c *fiber.Ctx) error {
+	format, err := bulk.ParseFormat(c.Query("format"))
+	if err != nil {
+		return NewError(http.StatusBadRequest, err.Error())
+	}
+
+	rows, rowErrors, err := bulk.DecodeRooms(bytes.NewReader(c.Body()), format)
+	if err != nil {
+		return NewError(http.StatusBadRequest, err.Error())
+	}
+
+	rooms := []*types.Room{}
+	for _, row := range rows {
+		hotelID, rowError := h.resolveHotel(c.Context(), row)
+		if rowError != nil {
+			rowErrors = append(rowErrors, *rowError)
+			continue
+		}
+		rooms = append(rooms, &types.Room{
+			Size:    row.Size,
+			Seaside: row.Seaside,
+			Price:   row.Price,
+			HotelID: hotelID,
+		})
+	}
+
+	response := ImportResponse{
+		DryRun: c.QueryBool("dryRun"),
+		Valid:  len(rooms),
+		Errors: rowErrors,
+	}
+	if response.DryRun || len(rowErrors) > 0 {
+		return importResult(c, response)
+	}
+
+	for _, room := range rooms {
+		if _, err := h.store.Room.CreateRoom(c.Context(), room); err != nil {
+			return err
+		}
+		response.Imported++
+	}
+
+	return importResult(c, response)
+}
+
+// admin auth
+func (h *BulkHandler) HandleExportBookings(c *fiber.Ctx) error {
+	format, err := bulk.ParseFormat(c.Query("format"))
+	if err != nil {
+		return NewError(http.StatusBadRequest, err.Error())
+	}
+	from, err := parseDateQuery(c, "fromDate")
+	if err != nil {
+		return err
+	}
+	till, err := parseDateQuery(c, "tillDate")
+	if err != nil {
+		return err
+	}
+	if !from.Before(till) {
+		return NewError(http.StatusBadRequest, "Invalid dates: fromDate should be before tillDate")
+	}
+
+	filter := bson.M{
+		"fromDate": bson.M{"$lt": till},
+		"tillDate": bson.M{"$gt": from},
+	}
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="bookings.%s"`, format))
+	// the request context is recycled before the body is streamed
+	ctx := logging.WithRequestID(context.Background(), logging.RequestID(c.Context()))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writer := bulk.NewBookingWriter(w, format)
+		// w sends a chunk whenever its buffer fills, flushing every row
+		// would cost a chunk per booking
+		if err := h.store.Booking.StreamBookings(ctx, filter, writer.Write); err != nil {
+			slog.ErrorContext(ctx, "booking export aborted", "err", err)
+			return
+		}
+		if err := writer.Flush(); err != nil {
+			slog.ErrorContext(ctx, "booking export aborted", "err", err)
+		}
+	})
+
+	return nil
+}
+
+func (h *BulkHandler) resolveHotel(ctx context.Context, row *bulk.RoomRow) (primitive.ObjectID, *bulk.RowError) {
+	if len(row.HotelID) > 0 {
+		oid, _ := primitive.ObjectIDFromHex(row.HotelID)
+		if _, err := h.store.Hotel.GetHotelByID(ctx, oid); err != nil {
+			return primitive.NilObjectID, &bulk.RowError{Line: row.Line, Field: "hotelID", Message: "hotel not found"}
+		}
+		return oid, nil
+	}
+
+	hotels, err := h.store.Hotel.GetHotels(ctx, bson.M{"name": row.HotelName})
+	if err != nil || len(hotels) == 0 {
+		return primitive.NilObjectID, &bulk.RowError{Line: row.Line, Field: "hotelName", Message: "hotel not found"}
+	}
+	if len(hotels) > 1 {
+		return primitive.NilObjectID, &bulk.RowError{Line: row.Line, Field: "hotelName", Message: "hotel name is ambiguous, use hotelID"}
+	}
+
+	return hotels[0].ID, nil
+}
+
+// importResult answers with the import summary. Nothing is imported when any
+// row is invalid, which is reported as a bad request.
+func importResult(c *fiber.Ctx, response ImportResponse) error {
+	if response.Errors == nil {
+		response.Errors = []bulk.RowError{}
+	}
+	if len(response.Errors) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(response)
+	}
+	return c.JSON(response)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestExportBookings(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		adminUser = fixtures.AddUser(db.store, "jack", "bauer", true)
+		user      = fixtures.AddUser(db.store, "james", "bond", false)
+		hotel     = fixtures.AddHotel(db.store, "ibis", "paris")
+		from      = time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC)
+		inside    = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], from, from.AddDate(0, 0, 2))
+		app       = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		admin     = app.Group("/admin", JWTAuthentication(db.store.User, testConfig.Auth), AdminAuth)
+		handler   = NewBulkHandler(db.store)
+	)
+	fixtures.AddBooking(db.store, user.ID, hotel.Rooms[1], from.AddDate(0, 1, 0), from.AddDate(0, 1, 2))
+	admin.Get("/export/bookings", handler.HandleExportBookings)
+
+	export := func(fromDate, tillDate string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/admin/export/bookings?format=csv&fromDate=%s&tillDate=%s", fromDate, tillDate), nil)
+		req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := export("2030-01-20", "2030-01-01"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected reversed dates to be rejected, got %d", resp.StatusCode)
+	}
+
+	resp := export("2030-01-01", "2030-01-31")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0][0] != "id" || rows[1][0] != inside.ID.Hex() {
+		t.Fatalf("expected the header and the booking within the dates, got %v", rows)
+	}
+}
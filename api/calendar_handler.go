@@ -0,0 +1,205 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/ical"
+	"github.com/aboronilov/go-hotel-reservation/random"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// feedHistory is how far back a feed lists past stays.
+const feedHistory = 30 * 24 * time.Hour
+
+type CalendarHandler struct {
+	store    *db.Store
+	importer *ical.Importer
+}
+
+type CalendarFeedResponse struct {
+	Feed *types.CalendarFeed `json:"feed"`
+	URL  string              `json:"url"`
+}
+
+type CalendarImportResponse struct {
+	Import *types.CalendarImport `json:"import"`
+	Result *ical.SyncResult      `json:"result,omitempty"`
+}
+
+func NewCalendarHandler(store *db.Store, importer *ical.Importer) *CalendarHandler {
+	return &CalendarHandler{
+		store:    store,
+		importer: importer,
+	}
+}
+
+// admin auth
+func (h *CalendarHandler) HandleCreateFeed(c *fiber.Ctx) error {
+	room, err := h.store.Room.GetRoomByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	token, err := random.Token()
+	if err != nil {
+		return err
+	}
+	feed, err := h.store.Calendar.SetFeedToken(c.Context(), room.ID, token)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(CalendarFeedResponse{
+		Feed: feed,
+		URL:  fmt.Sprintf("%s/api/calendar/%s.ics", c.BaseURL(), feed.Token),
+	})
+}
+
+// public, protected by the secret token in the URL
+func (h *CalendarHandler) HandleGetFeed(c *fiber.Ctx) error {
+	feed, err := h.store.Calendar.GetFeedByToken(c.Context(), c.Params("token"))
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	bookings, err := h.store.Booking.GetBookings(c.Context(), bson.M{
+		"roomID":   feed.RoomID,
+		"canceled": bson.M{"$ne": true},
+		"walk":     bson.M{"$exists": false},
+		"tillDate": bson.M{"$gte": time.Now().Add(-feedHistory)},
+	})
+	if err != nil {
+		return err
+	}
+
+	events := make([]ical.Event, len(bookings))
+	for i, booking := range bookings {
+		events[i] = ical.Event{
+			UID:     booking.ID.Hex() + "@hotel-reservation",
+			Start:   types.Night(booking.FromDate),
+			End:     types.Night(booking.TillDate),
+			Summary: "Booked",
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	return ical.Encode(c, "Room "+feed.RoomID.Hex(), events)
+}
+
+// admin auth
+func (h *CalendarHandler) HandleCreateImport(c *fiber.Ctx) error {
+	var params types.CreateCalendarImportParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+	if err := h.importer.CheckSource(params.Source); err != nil {
+		return NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	room, err := h.store.Room.GetRoomByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	imp, err := h.store.Calendar.CreateImport(c.Context(), &types.CalendarImport{
+		RoomID: room.ID,
+		Source: params.Source,
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.sync(c, imp)
+}
+
+// admin auth
+func (h *CalendarHandler) HandleListImports(c *fiber.Ctx) error {
+	imports, err := h.store.Calendar.GetImports(c.Context(), bson.M{})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(imports)
+}
+
+// admin auth
+func (h *CalendarHandler) HandleSyncImport(c *fiber.Ctx) error {
+	imp, err := h.getImport(c)
+	if err != nil {
+		return err
+	}
+
+	return h.sync(c, imp)
+}
+
+// admin auth
+func (h *CalendarHandler) HandleDeleteImport(c *fiber.Ctx) error {
+	imp, err := h.getImport(c)
+	if err != nil {
+		return err
+	}
+
+	bookings, err := h.store.Booking.GetBookings(c.Context(), bson.M{
+		"importID": imp.ID,
+		"canceled": bson.M{"$ne": true},
+		"tillDate": bson.M{"$gte": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	for _, booking := range bookings {
+		if err := h.importer.Cancel(c.Context(), booking); err != nil {
+			return err
+		}
+	}
+	if err := h.store.Calendar.DeleteImportByID(c.Context(), imp.ID); err != nil {
+		return err
+	}
+
+	return c.JSON(map[string]string{"msg": fmt.Sprintf("calendar import %s deleted", imp.ID.Hex())})
+}
+
+func (h *CalendarHandler) sync(c *fiber.Ctx, imp *types.CalendarImport) error {
+	result, err := h.importer.Sync(c.Context(), imp)
+	if err != nil {
+		imp.LastError = err.Error()
+		return c.Status(fiber.StatusBadGateway).JSON(CalendarImportResponse{Import: imp})
+	}
+
+	imp, err = h.store.Calendar.GetImportByID(c.Context(), imp.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(CalendarImportResponse{
+		Import: imp,
+		Result: result,
+	})
+}
+
+func (h *CalendarHandler) getImport(c *fiber.Ctx) (*types.CalendarImport, error) {
+	oid, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, ErrorInvalidID()
+	}
+
+	imp, err := h.store.Calendar.GetImportByID(c.Context(), oid)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrorNotFound()
+		}
+		return nil, err
+	}
+
+	return imp, nil
+}
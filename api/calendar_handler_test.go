@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/ical"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func writeCalendar(t *testing.T, path string, events ...string) {
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"
+	for _, event := range events {
+		body += event
+	}
+	body += "END:VCALENDAR\r\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCalendarImportFollowsSourceChanges(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user     = fixtures.AddUser(db.store, "james", "bond", false)
+		hotel    = fixtures.AddHotel(db.store, "ibis", "paris")
+		dir      = t.TempDir()
+		path     = filepath.Join(dir, "external.ics")
+		importer = ical.NewImporter(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour), dir)
+		first    = "BEGIN:VEVENT\r\nUID:a\r\nDTSTART;VALUE=DATE:20300105\r\nDTEND;VALUE=DATE:20300108\r\nEND:VEVENT\r\n"
+		moved    = "BEGIN:VEVENT\r\nUID:a\r\nDTSTART;VALUE=DATE:20300106\r\nDTEND;VALUE=DATE:20300109\r\nEND:VEVENT\r\n"
+		second   = "BEGIN:VEVENT\r\nUID:b\r\nDTSTART;VALUE=DATE:20300201\r\nDTEND;VALUE=DATE:20300203\r\nEND:VEVENT\r\n"
+	)
+	imp, err := db.store.Calendar.CreateImport(context.TODO(), &types.CalendarImport{
+		RoomID: hotel.Rooms[0],
+		Source: path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeCalendar(t, path, first, second)
+	result, err := importer.Sync(context.TODO(), imp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 2 {
+		t.Fatalf("expected 2 blocking bookings to be created, got %+v", result)
+	}
+
+	// waiting for the dates of the event that goes away
+	entry, err := db.store.Waitlist.CreateEntry(context.TODO(), &types.WaitlistEntry{
+		UserID:     user.ID,
+		RoomID:     hotel.Rooms[0],
+		FromDate:   time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC),
+		TillDate:   time.Date(2030, 2, 3, 0, 0, 0, 0, time.UTC),
+		NumPersons: 1,
+		Status:     types.WaitlistStatusWaiting,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeCalendar(t, path, moved)
+	result, err = importer.Sync(context.TODO(), imp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 0 || result.Updated != 1 || result.Canceled != 1 {
+		t.Fatalf("expected one update and one cancellation, got %+v", result)
+	}
+
+	bookings, err := db.store.Booking.GetBookings(context.TODO(), bson.M{"importID": imp.ID, "canceled": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookings) != 1 || bookings[0].ExternalUID != "a" || bookings[0].FromDate.Day() != 6 {
+		t.Fatalf("expected the moved event to remain, got %+v", bookings)
+	}
+	entry, err = db.store.Waitlist.GetEntryByID(context.TODO(), entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Status != types.WaitlistStatusOffered {
+		t.Fatalf("expected the freed room to be offered to the waitlist, got %+v", entry)
+	}
+}
+
+func TestCalendarImportReportsConflicts(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user     = fixtures.AddUser(db.store, "james", "bond", false)
+		hotel    = fixtures.AddHotel(db.store, "ibis", "paris")
+		dir      = t.TempDir()
+		importer = ical.NewImporter(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour), dir)
+		taken    = "BEGIN:VEVENT\r\nUID:a\r\nDTSTART;VALUE=DATE:20300105\r\nDTEND;VALUE=DATE:20300108\r\nEND:VEVENT\r\n"
+		free     = "BEGIN:VEVENT\r\nUID:b\r\nDTSTART;VALUE=DATE:20300201\r\nDTEND;VALUE=DATE:20300203\r\nEND:VEVENT\r\n"
+	)
+	fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], time.Date(2030, 1, 6, 0, 0, 0, 0, time.UTC), time.Date(2030, 1, 9, 0, 0, 0, 0, time.UTC))
+
+	writeCalendar(t, filepath.Join(dir, "external.ics"), taken, free)
+	imp, err := db.store.Calendar.CreateImport(context.TODO(), &types.CalendarImport{
+		RoomID: hotel.Rooms[0],
+		Source: "external.ics",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := importer.Sync(context.TODO(), imp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 1 || len(result.Conflicts) != 1 || result.Conflicts[0].UID != "a" {
+		t.Fatalf("expected the overlapping event to be reported instead of booked, got %+v", result)
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/channel"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type ChannelHandler struct {
+	store   *db.Store
+	manager *channel.Manager
+}
+
+func NewChannelHandler(store *db.Store, manager *channel.Manager) *ChannelHandler {
+	return &ChannelHandler{
+		store:   store,
+		manager: manager,
+	}
+}
+
+// admin auth
+func (h *ChannelHandler) HandleCreateMapping(c *fiber.Ctx) error {
+	var params types.CreateChannelMappingParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	mapping := types.NewChannelMappingFromParams(params)
+	if _, err := h.store.Room.GetRoomByID(c.Context(), mapping.RoomID.Hex()); err != nil {
+		return ErrorNotFound()
+	}
+
+	existing, err := h.store.Channel.GetMappings(c.Context(), bson.M{
+		"channel":      mapping.Channel,
+		"roomCode":     mapping.RoomCode,
+		"ratePlanCode": mapping.RatePlanCode,
+	})
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return NewError(http.StatusBadRequest, fmt.Sprintf("%s room code %s with rate plan %s is already mapped", mapping.Channel, mapping.RoomCode, mapping.RatePlanCode))
+	}
+
+	inserted, err := h.store.Channel.CreateMapping(c.Context(), mapping)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(inserted)
+}
+
+// admin auth
+func (h *ChannelHandler) HandleListMappings(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if name := c.Query("channel"); len(name) > 0 {
+		filter["channel"] = name
+	}
+
+	mappings, err := h.store.Channel.GetMappings(c.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(mappings)
+}
+
+// admin auth
+func (h *ChannelHandler) HandleDeleteMapping(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.store.Channel.DeleteMappingByID(c.Context(), id); err != nil {
+		return ErrorInvalidID()
+	}
+
+	return c.JSON(map[string]string{"msg": fmt.Sprintf("channel mapping %s deleted", id)})
+}
+
+// admin auth
+func (h *ChannelHandler) HandleListOutbox(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if status := c.Query("status"); len(status) > 0 {
+		filter["status"] = status
+	}
+
+	messages, err := h.store.Channel.GetMessages(c.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(messages)
+}
+
+// admin auth, queues a full ARI refresh without waiting for the next scheduled one
+func (h *ChannelHandler) HandleSyncARI(c *fiber.Ctx) error {
+	if err := h.manager.RefreshARI(c.Context()); err != nil {
+		return err
+	}
+
+	return c.JSON(map[string]string{"msg": fmt.Sprintf("ARI queued for the next %d days", int(channel.ARIHorizon/(24*time.Hour)))})
+}
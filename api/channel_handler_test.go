@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/channel"
+	"github.com/aboronilov/go-hotel-reservation/channel/mockchannel"
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/events"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestChannelReservationsFollowBookingRules(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user  = fixtures.AddUser(db.store, "james", "foo", false)
+		hotel = fixtures.AddHotel(db.store, "ibis", "paris")
+		mock  = mockchannel.NewServer("secret")
+		srv   = httptest.NewServer(mock)
+		from  = time.Now().AddDate(0, 0, 10)
+		till  = time.Now().AddDate(0, 0, 12)
+	)
+	defer srv.Close()
+	manager := channel.NewManager(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour), channel.NewHTTPAdapter("mock", srv.URL, "secret"))
+
+	for i, code := range []string{"SGL", "DBL"} {
+		_, err := db.store.Channel.CreateMapping(context.TODO(), types.NewChannelMappingFromParams(types.CreateChannelMappingParams{
+			Channel:  "mock",
+			RoomID:   hotel.Rooms[i].Hex(),
+			RoomCode: code,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], from, till)
+
+	mock.AddReservation(channel.Reservation{ID: "taken", RoomCode: "SGL", RatePlanCode: types.DefaultRatePlanCode, Arrival: from, Departure: till, NumPersons: 1})
+	mock.AddReservation(channel.Reservation{ID: "free", RoomCode: "DBL", RatePlanCode: types.DefaultRatePlanCode, Arrival: from, Departure: till, NumPersons: 2, GuestName: "Smith"})
+	mock.AddReservation(channel.Reservation{ID: "unknown", RoomCode: "STE", RatePlanCode: types.DefaultRatePlanCode, Arrival: from, Departure: till, NumPersons: 2})
+	if err := manager.PullReservations(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	if ack, _ := mock.Ack("taken"); ack.Success {
+		t.Fatal("expected a reservation for a booked room to be rejected")
+	}
+	if ack, _ := mock.Ack("unknown"); ack.Success {
+		t.Fatal("expected a reservation for an unmapped room to be rejected")
+	}
+	ack, _ := mock.Ack("free")
+	if !ack.Success || len(ack.BookingRef) == 0 {
+		t.Fatalf("expected the free room to be booked, got %+v", ack)
+	}
+
+	bookings, err := db.store.Booking.GetBookings(context.TODO(), bson.M{"channel": "mock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookings) != 1 || bookings[0].RoomID != hotel.Rooms[1] || bookings[0].GuestName != "Smith" {
+		t.Fatalf("expected one channel booking for the second room, got %+v", bookings)
+	}
+
+	// a cancellation of the same reservation cancels the booking
+	mock.AddReservation(channel.Reservation{ID: "free", Status: channel.ReservationStatusCancelled})
+	if err := manager.PullReservations(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	booking, err := db.store.Booking.GetBookingByID(context.TODO(), bookings[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !booking.Canceled {
+		t.Fatal("expected the channel booking to be canceled")
+	}
+}
+
+func TestChannelOutboxRetriesFailedPushes(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		hotel = fixtures.AddHotel(db.store, "ibis", "paris")
+		mock  = mockchannel.NewServer("secret")
+		srv   = httptest.NewServer(mock)
+		from  = time.Now().AddDate(0, 0, 1)
+	)
+	defer srv.Close()
+	manager := channel.NewManager(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour), channel.NewHTTPAdapter("mock", srv.URL, "secret"))
+
+	_, err := db.store.Channel.CreateMapping(context.TODO(), types.NewChannelMappingFromParams(types.CreateChannelMappingParams{
+		Channel:  "mock",
+		RoomID:   hotel.Rooms[0].Hex(),
+		RoomCode: "SGL",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.QueueARI(context.TODO(), bson.M{}, from, from.AddDate(0, 0, 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.FailARIPushes(1)
+	if err := manager.DispatchOutbox(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := db.store.Channel.GetMessages(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].Status != types.OutboxStatusPending || messages[0].Attempts != 1 || !messages[0].NextAttemptAt.After(time.Now()) {
+		t.Fatalf("expected the failed push to be rescheduled, got %+v", messages)
+	}
+	if len(mock.ARI()) != 0 {
+		t.Fatal("expected no ARI to reach the channel yet")
+	}
+
+	// make the message due again
+	if err := db.store.Channel.UpdateMessage(context.TODO(), messages[0].ID, bson.M{"nextAttemptAt": time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.DispatchOutbox(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if ari := mock.ARI(); len(ari) != 3 || ari[0].Available != 1 || ari[0].Rate != 100 {
+		t.Fatalf("expected three nights of ARI, got %+v", ari)
+	}
+}
+
+func TestBookingChangesQueueARI(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user   = fixtures.AddUser(db.store, "james", "foo", false)
+		hotel  = fixtures.AddHotel(db.store, "ibis", "paris")
+		from   = types.Night(time.Now().AddDate(0, 0, 10))
+		till   = from.AddDate(0, 0, 2)
+		broker = events.NewLocalBroker()
+	)
+	manager := channel.NewManager(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour), channel.NewHTTPAdapter("mock", "http://localhost", "secret"))
+	broker.Subscribe("channels", manager.HandleEvent, channel.BookingEvents...)
+
+	_, err := db.store.Channel.CreateMapping(context.TODO(), types.NewChannelMappingFromParams(types.CreateChannelMappingParams{
+		Channel:  "mock",
+		RoomID:   hotel.Rooms[0].Hex(),
+		RoomCode: "SGL",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	booking := fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], from, till)
+	if err := events.NewRelay(db.store, broker).Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := db.store.Channel.GetMessages(context.TODO(), bson.M{"channel": "mock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || len(messages[0].Updates) != 2 || messages[0].Updates[0].Available != 0 {
+		t.Fatalf("expected the booked nights to be pushed as sold out, got %+v", messages)
+	}
+
+	if _, err := db.store.Booking.CancelBooking(context.TODO(), booking.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.NewRelay(db.store, broker).Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	messages, err = db.store.Channel.GetMessages(context.TODO(), bson.M{"channel": "mock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	available := 0
+	for _, msg := range messages {
+		if len(msg.Updates) == 2 && msg.Updates[0].Available == 1 {
+			available++
+		}
+	}
+	if len(messages) != 2 || available != 1 {
+		t.Fatalf("expected the canceled nights to be pushed as available, got %+v", messages)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"log/slog"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,6 +16,7 @@ func ErrorHandler(c *fiber.Ctx, err error) error {
 	if apiError, ok := err.(*Error); ok {
 		return c.Status(apiError.Code).JSON(map[string]string{"error": apiError.Message})
 	}
+	slog.ErrorContext(c.Context(), "request failed", "err", err)
 	newError := NewError(http.StatusInternalServerError, err.Error())
 	return c.Status(newError.Code).JSON(map[string]string{"error": newError.Message})
 }
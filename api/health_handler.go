@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/scheduler"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+const pingTimeout = 2 * time.Second
+
+// Pinger checks the database connection. *mongo.Client implements it.
+type Pinger interface {
+	Ping(context.Context, *readpref.ReadPref) error
+}
+
+type HealthHandler struct {
+	db   Pinger
+	jobs *scheduler.Scheduler
+	// requireJobs is set when the background jobs are enabled, so a stopped
+	// scheduler makes the instance unready.
+	requireJobs bool
+}
+
+func NewHealthHandler(db Pinger, jobs *scheduler.Scheduler, requireJobs bool) *HealthHandler {
+	return &HealthHandler{
+		db:          db,
+		jobs:        jobs,
+		requireJobs: requireJobs,
+	}
+}
+
+type ReadinessResponse struct {
+	Status    string                `json:"status"`
+	Database  string                `json:"database"`
+	Scheduler string                `json:"scheduler"`
+	Jobs      []scheduler.JobStatus `json:"jobs"`
+}
+
+// liveness only tells the process is serving requests
+func (h *HealthHandler) HandleLiveness(c *fiber.Ctx) error {
+	return c.JSON(map[string]string{"status": "ok"})
+}
+
+// readiness fails when the database is unreachable or the background jobs
+// stopped or hang; job failures are reported but do not fail it. The probe is
+// public, so error messages are logged rather than returned.
+func (h *HealthHandler) HandleReadiness(c *fiber.Ctx) error {
+	resp := ReadinessResponse{
+		Status:    "ok",
+		Database:  "ok",
+		Scheduler: "ok",
+		Jobs:      h.jobs.Status(),
+	}
+	for i := range resp.Jobs {
+		resp.Jobs[i].LastError = ""
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), pingTimeout)
+	defer cancel()
+	if err := h.db.Ping(ctx, readpref.Primary()); err != nil {
+		slog.ErrorContext(c.Context(), "readiness database ping failed", "err", err)
+		resp.Status = "unavailable"
+		resp.Database = "unavailable"
+	}
+
+	switch {
+	case !h.requireJobs:
+		resp.Scheduler = "disabled"
+	case !h.jobs.Running():
+		resp.Status = "unavailable"
+		resp.Scheduler = "stopped"
+	default:
+		for _, job := range resp.Jobs {
+			if job.Stalled {
+				resp.Status = "unavailable"
+				resp.Scheduler = "job " + job.Name + " stalled"
+				break
+			}
+		}
+	}
+
+	if resp.Status != "ok" {
+		return c.Status(http.StatusServiceUnavailable).JSON(resp)
+	}
+	return c.JSON(resp)
+}
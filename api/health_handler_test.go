@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/scheduler"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+type fakePinger struct {
+	err error
+}
+
+func (p *fakePinger) Ping(context.Context, *readpref.ReadPref) error {
+	return p.err
+}
+
+func TestHealthProbes(t *testing.T) {
+	var (
+		pinger  = &fakePinger{}
+		jobs    = scheduler.New(nil)
+		app     = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		handler = NewHealthHandler(pinger, jobs, true)
+	)
+	jobs.Every("noop", time.Hour, func(context.Context) error { return nil })
+	app.Get("/healthz", handler.HandleLiveness)
+	app.Get("/readyz", handler.HandleReadiness)
+
+	probe := func(path string) (int, ReadinessResponse) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body ReadinessResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, body
+	}
+
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Fatalf("expected liveness 200 but got %d", code)
+	}
+
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable || body.Scheduler != "stopped" {
+		t.Fatalf("expected not ready before the jobs start but got %d %+v", code, body)
+	}
+
+	jobs.Start(context.Background())
+	defer jobs.Stop()
+	code, body := probe("/readyz")
+	if code != http.StatusOK || len(body.Jobs) != 1 || body.Jobs[0].Name != "noop" {
+		t.Fatalf("expected ready with the job status but got %d %+v", code, body)
+	}
+
+	pinger.err = errors.New("no reachable servers at mongo-0.internal:27017")
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable || body.Database != "unavailable" {
+		t.Fatalf("expected not ready without the database but got %d %+v", code, body)
+	}
+}
@@ -1,22 +1,46 @@
 package api
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/aboronilov/go-hotel-reservation/availability"
 	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/storage"
+	"github.com/aboronilov/go-hotel-reservation/types"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const maxImageSize = 4 << 20
+
+// imageTypes maps the image formats accepted for upload to their extension.
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
 type HotelHandler struct {
-	store *db.Store
+	store   *db.Store
+	checker *availability.Checker
+	media   storage.Storage
 }
 
-func NewHotelHandler(store *db.Store) *HotelHandler {
+func NewHotelHandler(store *db.Store, media storage.Storage) *HotelHandler {
 	return &HotelHandler{
-		store: store,
+		store:   store,
+		checker: availability.NewChecker(store),
+		media:   media,
 	}
 }
 
@@ -33,16 +57,131 @@ func (h *HotelHandler) HandleGetRooms(c *fiber.Ctx) error {
 		return err
 	}
 
+	rooms, err = filterAvailableRooms(c, h.checker, rooms)
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(rooms)
 }
 
+const (
+	defaultSearchRadiusKm = 10
+	maxSearchRadiusKm     = 500
+)
+
+// ?amenities=wifi,pool only returns hotels offering all of them
+// ?minRating=4 only returns hotels rated at least 4
+// ?fromDate=&tillDate= only returns hotels with a room available for the stay
+// ?near=lat,lng&radius=km returns hotels within radius, nearest first
 func (h *HotelHandler) HandleListHotels(c *fiber.Ctx) error {
-	hotels, err := h.store.Hotel.GetHotels(c.Context(), nil)
+	filter := bson.M{}
+	if amenities := c.Query("amenities"); len(amenities) > 0 {
+		list := strings.Split(amenities, ",")
+		for _, amenity := range list {
+			if !slices.Contains(types.Amenities, amenity) {
+				return NewError(http.StatusBadRequest, fmt.Sprintf("Unknown amenity %s", amenity))
+			}
+		}
+		filter["amenities"] = bson.M{"$all": list}
+	}
+	if minRating := c.Query("minRating"); len(minRating) > 0 {
+		rating, err := strconv.ParseFloat(minRating, 64)
+		if err != nil || rating < 0 || rating > 5 {
+			return NewError(http.StatusBadRequest, "Invalid minRating, expected a number between 0 and 5")
+		}
+		filter["rating"] = bson.M{"$gte": rating}
+	}
+
+	if len(c.Query("near")) == 0 {
+		hotels, err := h.store.Hotel.GetHotels(c.Context(), filter)
+		if err != nil {
+			return err
+		}
+		available, err := h.availableHotels(c, hotels)
+		if err != nil {
+			return err
+		}
+		return c.JSON(slices.DeleteFunc(hotels, func(hotel *types.Hotel) bool {
+			return available != nil && !available[hotel.ID]
+		}))
+	}
+
+	point, radius, err := parseNear(c)
+	if err != nil {
+		return err
+	}
+	results, err := h.store.Hotel.GetHotelsNear(c.Context(), filter, point, radius)
+	if err != nil {
+		return err
+	}
+	hotels := make([]*types.Hotel, len(results))
+	for i, result := range results {
+		hotels[i] = &result.Hotel
+	}
+	available, err := h.availableHotels(c, hotels)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(hotels)
+	return c.JSON(slices.DeleteFunc(results, func(result *types.HotelDistance) bool {
+		return available != nil && !available[result.ID]
+	}))
+}
+
+// availableHotels returns the set of hotels with at least one room bookable
+// for the stay in the fromDate and tillDate query parameters, or nil when no
+// stay was asked for.
+func (h *HotelHandler) availableHotels(c *fiber.Ctx, hotels []*types.Hotel) (map[primitive.ObjectID]bool, error) {
+	if len(c.Query("fromDate")) == 0 && len(c.Query("tillDate")) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(hotels))
+	for i, hotel := range hotels {
+		ids[i] = hotel.ID
+	}
+	rooms, err := h.store.Room.GetRooms(c.Context(), bson.M{"hotelID": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	rooms, err = filterAvailableRooms(c, h.checker, rooms)
+	if err != nil {
+		return nil, err
+	}
+
+	available := map[primitive.ObjectID]bool{}
+	for _, room := range rooms {
+		available[room.HotelID] = true
+	}
+
+	return available, nil
+}
+
+// parseNear reads the near=lat,lng and radius=km query parameters.
+func parseNear(c *fiber.Ctx) (*types.GeoPoint, float64, error) {
+	parts := strings.Split(c.Query("near"), ",")
+	if len(parts) != 2 {
+		return nil, 0, NewError(http.StatusBadRequest, "Invalid near, expected lat,lng")
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return nil, 0, NewError(http.StatusBadRequest, "Invalid near, latitude should be between -90 and 90")
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return nil, 0, NewError(http.StatusBadRequest, "Invalid near, longitude should be between -180 and 180")
+	}
+
+	radius := float64(defaultSearchRadiusKm)
+	if r := c.Query("radius"); len(r) > 0 {
+		radius, err = strconv.ParseFloat(r, 64)
+		if err != nil || radius <= 0 || radius > maxSearchRadiusKm {
+			return nil, 0, NewError(http.StatusBadRequest, fmt.Sprintf("Invalid radius, expected km between 0 and %d", maxSearchRadiusKm))
+		}
+	}
+
+	return types.NewGeoPoint(lat, lng), radius, nil
 }
 
 func (h *HotelHandler) HandleRetrieveHotel(c *fiber.Ctx) error {
@@ -62,3 +201,164 @@ func (h *HotelHandler) HandleRetrieveHotel(c *fiber.Ctx) error {
 
 	return c.JSON(hotel)
 }
+
+// admin auth
+func (h *HotelHandler) HandleUpdateHotel(c *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+
+	var params types.UpdateHotelParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	if update := params.ToBson(); len(update) > 0 {
+		if err := h.store.Hotel.UpdateHotelByID(c.Context(), bson.M{"_id": oid}, bson.M{"$set": update}); err != nil {
+			return err
+		}
+	}
+
+	return h.respondHotel(c, oid)
+}
+
+// admin auth, multipart form with an image file and an optional caption
+func (h *HotelHandler) HandleUploadImage(c *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+	if _, err := h.store.Hotel.GetHotelByID(c.Context(), oid); err != nil {
+		return ErrorNotFound()
+	}
+
+	header, err := c.FormFile("image")
+	if err != nil {
+		return NewError(http.StatusBadRequest, "Missing image file")
+	}
+	if header.Size > maxImageSize {
+		return NewError(http.StatusBadRequest, "Image should be at most 4MB")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorBadRequest()
+	}
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := imageTypes[contentType]
+	if !ok {
+		return NewError(http.StatusBadRequest, fmt.Sprintf("Unsupported image type %s", contentType))
+	}
+
+	key, err := h.media.Save(c.Context(), ext, io.MultiReader(bytes.NewReader(head[:n]), file))
+	if err != nil {
+		return err
+	}
+	image := types.HotelImage{
+		Key:         key,
+		URL:         h.media.URL(key),
+		Caption:     c.FormValue("caption"),
+		ContentType: contentType,
+	}
+	if err := h.store.Hotel.UpdateHotelByID(c.Context(), bson.M{"_id": oid}, bson.M{"$push": bson.M{"images": image}}); err != nil {
+		h.media.Delete(c.Context(), key)
+		return err
+	}
+
+	return h.respondHotel(c, oid)
+}
+
+// admin auth
+func (h *HotelHandler) HandleDeleteImage(c *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+	hotel, err := h.store.Hotel.GetHotelByID(c.Context(), oid)
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	key := c.Params("key")
+	if !slices.ContainsFunc(hotel.Images, func(image types.HotelImage) bool { return image.Key == key }) {
+		return ErrorNotFound()
+	}
+	if err := h.store.Hotel.UpdateHotelByID(c.Context(), bson.M{"_id": oid}, bson.M{"$pull": bson.M{"images": bson.M{"key": key}}}); err != nil {
+		return err
+	}
+	if err := h.media.Delete(c.Context(), key); err != nil {
+		return err
+	}
+
+	return h.respondHotel(c, oid)
+}
+
+type ReorderImagesParams struct {
+	Keys []string `json:"keys"`
+}
+
+// admin auth, keys lists every image of the gallery in the new order
+func (h *HotelHandler) HandleReorderImages(c *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+	hotel, err := h.store.Hotel.GetHotelByID(c.Context(), oid)
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	var params ReorderImagesParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+
+	byKey := map[string]types.HotelImage{}
+	read := make([]string, 0, len(hotel.Images))
+	for _, image := range hotel.Images {
+		byKey[image.Key] = image
+		read = append(read, image.Key)
+	}
+	images := make([]types.HotelImage, 0, len(params.Keys))
+	for _, key := range params.Keys {
+		image, ok := byKey[key]
+		if !ok {
+			return NewError(http.StatusBadRequest, "keys should list every image of the hotel exactly once")
+		}
+		images = append(images, image)
+		delete(byKey, key)
+	}
+	if len(byKey) > 0 {
+		return NewError(http.StatusBadRequest, "keys should list every image of the hotel exactly once")
+	}
+
+	// images added or deleted since the gallery was read would be lost
+	reordered, err := h.store.Hotel.ReorderImages(c.Context(), oid, read, images)
+	if err != nil {
+		return err
+	}
+	if !reordered {
+		return NewError(http.StatusConflict, "The gallery has changed, reload it and try again")
+	}
+
+	return h.respondHotel(c, oid)
+}
+
+func (h *HotelHandler) respondHotel(c *fiber.Ctx, oid primitive.ObjectID) error {
+	hotel, err := h.store.Hotel.GetHotelByID(c.Context(), oid)
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	return c.JSON(hotel)
+}
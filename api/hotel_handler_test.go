@@ -0,0 +1,268 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/storage"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// a 1x1 transparent png
+var pngImage = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\x00\x00\x00\rIDATx\x9cc\x00\x01\x00\x00\x05\x00\x01\r\n-\xb4\x00\x00\x00\x00IEND\xaeB`\x82")
+
+func uploadImage(t *testing.T, app *fiber.App, path string, user *types.User, caption string) *http.Response {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("image", "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(pngImage)
+	w.WriteField("caption", caption)
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Add("Content-Type", w.FormDataContentType())
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestHotelDetailsAndAmenityFilter(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		adminUser = fixtures.AddUser(db.store, "jack", "bauer", true)
+		ibis      = fixtures.AddHotel(db.store, "ibis", "paris")
+		hilton    = fixtures.AddHotel(db.store, "hilton", "london")
+		app       = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		apiv1     = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		handler   = NewHotelHandler(db.store, storage.NewLocalStorage(t.TempDir(), "/media"))
+		lat, lng  = 48.8566, 2.3522
+	)
+	apiv1.Get("/hotel", handler.HandleListHotels)
+	apiv1.Put("/admin/hotel/:id", AdminAuth, handler.HandleUpdateHotel)
+
+	params := types.UpdateHotelParams{
+		Address:   &types.Address{Street: "1 Rue de Rivoli", City: "Paris", PostalCode: "75001", Country: "FR"},
+		Latitude:  &lat,
+		Longitude: &lng,
+		Amenities: []string{types.AmenityWifi, types.AmenityPool},
+		CheckIn:   "15:00",
+		CheckOut:  "11:00",
+	}
+	b, _ := json.Marshal(params)
+	req := httptest.NewRequest(http.MethodPut, "/admin/hotel/"+ibis.ID.Hex(), bytes.NewReader(b))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updated types.Hotel
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Address == nil || updated.Address.City != "Paris" || updated.Geo == nil || updated.Geo.Lat() != lat || updated.CheckIn != "15:00" || updated.Name != "ibis" {
+		t.Fatalf("unexpected hotel after update %+v", updated)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/hotel?amenities=wifi,pool", nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hotels []*types.Hotel
+	if err := json.NewDecoder(resp.Body).Decode(&hotels); err != nil {
+		t.Fatal(err)
+	}
+	if len(hotels) != 1 || hotels[0].ID != ibis.ID {
+		t.Fatalf("expected only %s to have wifi and a pool, got %+v", ibis.ID, hotels)
+	}
+	if hotels[0].ID == hilton.ID {
+		t.Fatal("expected hilton to be filtered out")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/hotel?amenities=helipad", nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+	if resp, _ = app.Test(req); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected an unknown amenity to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestHotelImageGallery(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		adminUser = fixtures.AddUser(db.store, "jack", "bauer", true)
+		hotel     = fixtures.AddHotel(db.store, "ibis", "paris")
+		dir       = t.TempDir()
+		app       = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		admin     = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth), AdminAuth)
+		handler   = NewHotelHandler(db.store, storage.NewLocalStorage(dir, "/media"))
+		path      = "/hotel/" + hotel.ID.Hex() + "/images"
+	)
+	admin.Post("/hotel/:id/images", handler.HandleUploadImage)
+	admin.Put("/hotel/:id/images", handler.HandleReorderImages)
+	admin.Delete("/hotel/:id/images/:key", handler.HandleDeleteImage)
+
+	uploadImage(t, app, path, adminUser, "lobby")
+	resp := uploadImage(t, app, path, adminUser, "pool")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+	var updated types.Hotel
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Images) != 2 || updated.Images[0].Caption != "lobby" || updated.Images[1].ContentType != "image/png" {
+		t.Fatalf("unexpected gallery %+v", updated.Images)
+	}
+	first, second := updated.Images[0].Key, updated.Images[1].Key
+	if _, err := os.Stat(filepath.Join(dir, first)); err != nil {
+		t.Fatalf("expected the image to be stored on disk: %v", err)
+	}
+
+	b, _ := json.Marshal(ReorderImagesParams{Keys: []string{second, first}})
+	req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader(b))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated = types.Hotel{}
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Images) != 2 || updated.Images[0].Key != second {
+		t.Fatalf("expected the gallery to be reordered, got %+v", updated.Images)
+	}
+
+	// an image uploaded after the gallery was read must not be dropped
+	stale := updated.Images
+	uploadImage(t, app, path, adminUser, "spa")
+	reordered, err := db.store.Hotel.ReorderImages(context.TODO(), hotel.ID, []string{stale[0].Key, stale[1].Key}, []types.HotelImage{stale[1], stale[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reordered {
+		t.Fatal("expected the reorder of a stale gallery to be refused")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, path+"/"+first, nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, first)); !os.IsNotExist(err) {
+		t.Fatal("expected the deleted image to be removed from disk")
+	}
+}
+
+func TestGeoHotelSearch(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user       = fixtures.AddUser(db.store, "james", "foo", false)
+		louvre     = fixtures.AddHotel(db.store, "louvre", "paris")
+		eiffel     = fixtures.AddHotel(db.store, "eiffel", "paris")
+		versailles = fixtures.AddHotel(db.store, "versailles", "versailles")
+		_          = fixtures.AddHotel(db.store, "hilton", "london")
+		louvreRoom = fixtures.AddRoom(db.store, "small", false, 99.9, louvre.ID)
+		_          = fixtures.AddRoom(db.store, "small", false, 99.9, eiffel.ID)
+		from       = time.Now().AddDate(0, 0, 7).Truncate(24 * time.Hour)
+		till       = from.AddDate(0, 0, 2)
+		app        = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		apiv1      = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		handler    = NewHotelHandler(db.store, storage.NewLocalStorage(t.TempDir(), "/media"))
+	)
+	apiv1.Get("/hotel", handler.HandleListHotels)
+	fixtures.AddBooking(db.store, user.ID, louvreRoom.ID, from, till)
+
+	locate := func(hotel *types.Hotel, lat, lng float64, rating float64) {
+		update := bson.M{"$set": bson.M{"geo": types.NewGeoPoint(lat, lng), "rating": rating}}
+		if err := db.store.Hotel.UpdateHotelByID(context.TODO(), bson.M{"_id": hotel.ID}, update); err != nil {
+			t.Fatal(err)
+		}
+	}
+	locate(louvre, 48.8606, 2.3376, 3.5)
+	locate(eiffel, 48.8584, 2.2945, 4.5)
+	locate(versailles, 48.8049, 2.1204, 4.8)
+
+	search := func(query string) []types.HotelDistance {
+		req := httptest.NewRequest(http.MethodGet, "/hotel?"+query, nil)
+		req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200 for %s but got %d", query, resp.StatusCode)
+		}
+		var hotels []types.HotelDistance
+		if err := json.NewDecoder(resp.Body).Decode(&hotels); err != nil {
+			t.Fatal(err)
+		}
+		return hotels
+	}
+	names := func(hotels []types.HotelDistance) []string {
+		var names []string
+		for _, hotel := range hotels {
+			names = append(names, hotel.Name)
+		}
+		return names
+	}
+
+	hotels := search("near=48.8566,2.3522&radius=5")
+	if !slices.Equal(names(hotels), []string{"louvre", "eiffel"}) {
+		t.Fatalf("expected louvre and eiffel nearest first but got %v", names(hotels))
+	}
+	if hotels[0].DistanceKm <= 0 || hotels[0].DistanceKm > hotels[1].DistanceKm || hotels[1].DistanceKm > 5 {
+		t.Fatalf("unexpected distances %v and %v", hotels[0].DistanceKm, hotels[1].DistanceKm)
+	}
+
+	if got := names(search("near=48.8566,2.3522&radius=30")); len(got) != 3 || got[2] != "versailles" {
+		t.Fatalf("expected three hotels within 30km but got %v", got)
+	}
+	if got := names(search("near=48.8566,2.3522&radius=30&minRating=4")); !slices.Equal(got, []string{"eiffel", "versailles"}) {
+		t.Fatalf("expected hotels rated 4 or more but got %v", got)
+	}
+
+	stay := fmt.Sprintf("&fromDate=%s&tillDate=%s", url.QueryEscape(from.Format(time.RFC3339)), url.QueryEscape(till.Format(time.RFC3339)))
+	if got := names(search("near=48.8566,2.3522&radius=5" + stay)); !slices.Equal(got, []string{"eiffel"}) {
+		t.Fatalf("expected only hotels with an available room but got %v", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/hotel?near=91,2", nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an invalid point but got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/availability"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/metrics"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ItineraryHandler struct {
+	store    *db.Store
+	checker  *availability.Checker
+	waitlist *waitlist.Manager
+}
+
+type ItineraryResponse struct {
+	Itinerary *types.Itinerary `json:"itinerary"`
+	Bookings  []*types.Booking `json:"bookings"`
+}
+
+func NewItineraryHandler(store *db.Store, waitlist *waitlist.Manager) *ItineraryHandler {
+	return &ItineraryHandler{
+		store:    store,
+		checker:  availability.NewChecker(store),
+		waitlist: waitlist,
+	}
+}
+
+func (h *ItineraryHandler) HandleCreateItinerary(c *fiber.Ctx) error {
+	var params types.CreateItineraryParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	user, err := getAuthUser(c)
+	if err != nil {
+		return ErrorUnauthorized()
+	}
+
+	itinerary := &types.Itinerary{
+		UserID:        user.ID,
+		PaymentStatus: types.PaymentStatusPending,
+		CreatedAt:     time.Now(),
+	}
+	bookings := make([]*types.Booking, len(params.Legs))
+	for i, leg := range params.Legs {
+		room, err := h.store.Room.GetRoomByID(c.Context(), leg.RoomID)
+		if err != nil {
+			return NewError(http.StatusBadRequest, fmt.Sprintf("Room %s not found", leg.RoomID))
+		}
+		if err := checkRestrictions(c.Context(), h.checker, room, leg.FromDate, leg.TillDate); err != nil {
+			return err
+		}
+		ok, err := h.checker.IsRoomAvailable(c.Context(), availability.Request{
+			RoomID:     room.ID,
+			UserID:     user.ID,
+			FromDate:   leg.FromDate,
+			TillDate:   leg.TillDate,
+			NumPersons: leg.NumPersons,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			metrics.AvailabilityConflicts.WithLabelValues(metrics.ConflictUnavailable).Inc()
+			message := fmt.Sprintf("Room %s is already booked between %s and %s", room.ID.Hex(), leg.FromDate.Format(time.RFC3339), leg.TillDate.Format(time.RFC3339))
+			return NewError(http.StatusBadRequest, message)
+		}
+
+		bookings[i] = &types.Booking{
+			UserID:     user.ID,
+			RoomID:     room.ID,
+			FromDate:   leg.FromDate,
+			TillDate:   leg.TillDate,
+			NumPersons: leg.NumPersons,
+			Price:      room.PriceFor(leg.FromDate, leg.TillDate),
+		}
+		itinerary.TotalPrice += bookings[i].Price
+	}
+
+	inserted, err := h.store.Itinerary.CreateItinerary(c.Context(), itinerary, bookings)
+	if err != nil {
+		return err
+	}
+	for _, booking := range bookings {
+		recordAudit(c, h.store.Audit, types.AuditBookingCreate, "booking", booking.ID.Hex(), nil, booking)
+		if err := h.waitlist.Claim(c.Context(), user.ID, booking.RoomID); err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(ItineraryResponse{
+		Itinerary: inserted,
+		Bookings:  bookings,
+	})
+}
+
+// only owner
+func (h *ItineraryHandler) HandleRetrieveItinerary(c *fiber.Ctx) error {
+	itinerary, err := h.getOwnItinerary(c)
+	if err != nil {
+		return err
+	}
+
+	bookings, err := h.store.Booking.GetBookings(c.Context(), bson.M{"itineraryID": itinerary.ID})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(ItineraryResponse{
+		Itinerary: itinerary,
+		Bookings:  bookings,
+	})
+}
+
+// only owner
+func (h *ItineraryHandler) HandlePayItinerary(c *fiber.Ctx) error {
+	itinerary, err := h.getOwnItinerary(c)
+	if err != nil {
+		return err
+	}
+	if itinerary.Canceled {
+		return NewError(http.StatusBadRequest, "Itinerary is canceled")
+	}
+	if itinerary.PaymentStatus == types.PaymentStatusPaid {
+		return NewError(http.StatusBadRequest, "Itinerary is already paid")
+	}
+
+	update := bson.M{
+		"paymentStatus": types.PaymentStatusPaid,
+		"paidAt":        time.Now(),
+	}
+	if err := h.store.Itinerary.UpdateItinerary(c.Context(), itinerary.ID, update); err != nil {
+		return err
+	}
+
+	return c.JSON(map[string]string{"message": fmt.Sprintf("Itinerary paid, total %.2f", itinerary.TotalPrice)})
+}
+
+// only owner
+func (h *ItineraryHandler) HandleCancelItinerary(c *fiber.Ctx) error {
+	itinerary, err := h.getOwnItinerary(c)
+	if err != nil {
+		return err
+	}
+
+	for _, bookingID := range itinerary.BookingIDs {
+		if err := h.cancelLeg(c, bookingID); err != nil {
+			return err
+		}
+	}
+	if err := refreshItinerary(c.Context(), h.store, itinerary.ID); err != nil {
+		return err
+	}
+
+	return c.JSON(map[string]string{"message": "Itinerary canceled"})
+}
+
+// only owner
+func (h *ItineraryHandler) HandleCancelItineraryBooking(c *fiber.Ctx) error {
+	itinerary, err := h.getOwnItinerary(c)
+	if err != nil {
+		return err
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Params("bookingID"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+	if !containsID(itinerary.BookingIDs, bookingID) {
+		return ErrorNotFound()
+	}
+
+	if err := h.cancelLeg(c, bookingID); err != nil {
+		return err
+	}
+	if err := refreshItinerary(c.Context(), h.store, itinerary.ID); err != nil {
+		return err
+	}
+
+	return c.JSON(map[string]string{"message": "Booking canceled"})
+}
+
+func (h *ItineraryHandler) cancelLeg(c *fiber.Ctx, bookingID primitive.ObjectID) error {
+	ctx := c.Context()
+	booking, err := h.store.Booking.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	if booking.Canceled {
+		return nil
+	}
+	canceled, err := h.store.Booking.CancelBooking(ctx, bookingID)
+	if err != nil || !canceled {
+		return err
+	}
+	after := *booking
+	after.Canceled = true
+	recordAudit(c, h.store.Audit, types.AuditBookingCancel, "booking", bookingID.Hex(), booking, &after)
+
+	return h.waitlist.Release(ctx, booking.RoomID)
+}
+
+func (h *ItineraryHandler) getOwnItinerary(c *fiber.Ctx) (*types.Itinerary, error) {
+	oid, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, ErrorInvalidID()
+	}
+
+	itinerary, err := h.store.Itinerary.GetItineraryByID(c.Context(), oid)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrorNotFound()
+		}
+		return nil, err
+	}
+
+	user, err := getAuthUser(c)
+	if err != nil || itinerary.UserID != user.ID {
+		return nil, ErrorUnauthorized()
+	}
+
+	return itinerary, nil
+}
+
+// refreshItinerary recomputes the itinerary total from its bookings that are
+// still active and marks it canceled once every leg is.
+func refreshItinerary(ctx context.Context, store *db.Store, id primitive.ObjectID) error {
+	bookings, err := store.Booking.GetBookings(ctx, bson.M{"itineraryID": id})
+	if err != nil {
+		return err
+	}
+
+	var (
+		total    float64
+		canceled = true
+	)
+	for _, booking := range bookings {
+		if booking.Canceled {
+			continue
+		}
+		total += booking.Price
+		canceled = false
+	}
+
+	return store.Itinerary.UpdateItinerary(ctx, id, bson.M{
+		"totalPrice": total,
+		"canceled":   canceled,
+	})
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
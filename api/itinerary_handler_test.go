@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestItineraryIsCreatedAllOrNothing(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user             = fixtures.AddUser(db.store, "john", "smith", false)
+		other            = fixtures.AddUser(db.store, "jack", "bauer", false)
+		hotel            = fixtures.AddHotel(db.store, "ibis", "paris")
+		from             = time.Now().AddDate(0, 0, 1)
+		till             = time.Now().AddDate(0, 0, 3)
+		_                = fixtures.AddBooking(db.store, other.ID, hotel.Rooms[1], from, till)
+		app              = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		route            = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		itineraryHandler = NewItineraryHandler(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour))
+	)
+	route.Post("/itinerary", itineraryHandler.HandleCreateItinerary)
+	route.Get("/itinerary/:id/cancel", itineraryHandler.HandleCancelItinerary)
+
+	params := types.CreateItineraryParams{
+		Legs: []types.ItineraryLegParams{
+			{RoomID: hotel.Rooms[0].Hex(), FromDate: from, TillDate: till, NumPersons: 2},
+			{RoomID: hotel.Rooms[1].Hex(), FromDate: from, TillDate: till, NumPersons: 2},
+		},
+	}
+	b, _ := json.Marshal(params)
+	req := httptest.NewRequest(http.MethodPost, "/itinerary", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status code 400, got %d", resp.StatusCode)
+	}
+
+	bookings, err := db.store.Booking.GetBookings(context.TODO(), bson.M{"userID": user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookings) != 0 {
+		t.Fatalf("expected no bookings to be created, got %d", len(bookings))
+	}
+
+	params.Legs[1].RoomID = hotel.Rooms[2].Hex()
+	b, _ = json.Marshal(params)
+	req = httptest.NewRequest(http.MethodPost, "/itinerary", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+
+	var itinerary ItineraryResponse
+	if err = json.NewDecoder(resp.Body).Decode(&itinerary); err != nil {
+		t.Fatal(err)
+	}
+	if len(itinerary.Bookings) != 2 {
+		t.Fatalf("expected 2 bookings, got %d", len(itinerary.Bookings))
+	}
+	if itinerary.Itinerary.TotalPrice != itinerary.Bookings[0].Price+itinerary.Bookings[1].Price {
+		t.Fatalf("expected total price to be the sum of the bookings, got %.2f", itinerary.Itinerary.TotalPrice)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/itinerary/"+itinerary.Itinerary.ID.Hex()+"/cancel", nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the itinerary to be canceled, got %d", resp.StatusCode)
+	}
+
+	// guests create and cancel bookings outside the admin routes, the
+	// handlers audit them
+	for action, expected := range map[string]int{types.AuditBookingCreate: 2, types.AuditBookingCancel: 2} {
+		records, err := db.store.Audit.GetRecords(context.TODO(), bson.M{"action": action, "actorID": user.ID}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != expected {
+			t.Fatalf("expected %d %s audit records, got %d", expected, action, len(records))
+		}
+	}
+}
@@ -2,16 +2,21 @@ package api
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
 	"time"
 
+	"github.com/aboronilov/go-hotel-reservation/config"
 	"github.com/aboronilov/go-hotel-reservation/db"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt"
 )
 
-func JWTAuthentication(userStore db.UserStore) fiber.Handler {
+// twoFactorKey is set on requests whose token was issued after a two-factor
+// login.
+type twoFactorKey struct{}
+
+func JWTAuthentication(userStore db.UserStore, auth config.Auth) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		headers := c.GetReqHeaders()
 		token, ok := headers["Authorization"]
@@ -20,8 +25,13 @@ func JWTAuthentication(userStore db.UserStore) fiber.Handler {
 			return ErrorUnauthorized()
 		}
 
-		claims, err := validateToken(token[0])
+		claims, err := validateToken(token[0], auth.JWTSecret)
 		if err != nil {
+			slog.InfoContext(c.Context(), "invalid auth token", "err", err)
+			return ErrorUnauthorized()
+		}
+		// challenge tokens only prove the password, not the second factor
+		if _, ok := claims["purpose"]; ok {
 			return ErrorUnauthorized()
 		}
 
@@ -37,24 +47,22 @@ func JWTAuthentication(userStore db.UserStore) fiber.Handler {
 			return ErrorUnauthorized()
 		}
 		c.Context().SetUserValue("user", user)
+		c.Context().SetUserValue(twoFactorKey{}, claims["mfa"] == true)
 
 		return c.Next()
 	}
 }
 
-func validateToken(tokenStr string) (jwt.MapClaims, error) {
+func validateToken(tokenStr, secret string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			fmt.Println("Invalid signing method: ", token.Header["alg"])
-			return nil, ErrorUnauthorized()
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
 		}
 
-		secret := os.Getenv("JWT_SECRET")
 		return []byte(secret), nil
 	})
 	if err != nil {
-		fmt.Println("Failed to parse JWT: ", err)
-		return nil, ErrorUnauthorized()
+		return nil, err
 	}
 
 	if !token.Valid {
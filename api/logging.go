@@ -0,0 +1,86 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/logging"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// request IDs from clients or proxies are kept when they look sane
+var requestIDReg = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// probes are polled constantly, so their successes are only logged at debug
+var probeRoutes = map[string]bool{"/healthz": true, "/readyz": true}
+
+// RequestID tags the request with the X-Request-ID header, or a new ID, and
+// echoes it in the response. Logs written with c.Context() include it.
+func RequestID(c *fiber.Ctx) error {
+	id := c.Get(requestIDHeader)
+	if !requestIDReg.MatchString(id) {
+		id = newRequestID()
+	}
+	c.Context().SetUserValue(logging.RequestIDKey, id)
+	c.SetUserContext(logging.WithRequestID(c.UserContext(), id))
+	c.Set(requestIDHeader, id)
+
+	return c.Next()
+}
+
+// sendError answers the request with the error handler right away, so
+// middlewares that run after the handler see the final response status.
+func sendError(c *fiber.Ctx, err error) {
+	if err := c.App().ErrorHandler(c, err); err != nil {
+		c.SendStatus(http.StatusInternalServerError)
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog logs every request once it is answered. It logs the route
+// pattern rather than the path, as paths may carry secret tokens.
+func AccessLog(c *fiber.Ctx) error {
+	start := time.Now()
+	if err := c.Next(); err != nil {
+		sendError(c, err)
+	}
+
+	status := c.Response().StatusCode()
+	route := c.Route().Path
+	attrs := []any{
+		"method", c.Method(),
+		"route", route,
+		"status", status,
+		"latency", time.Since(start),
+		"ip", c.IP(),
+		"bytes", c.Response().Header.ContentLength(),
+	}
+	if user, ok := c.Context().UserValue("user").(*types.User); ok {
+		attrs = append(attrs, "userID", user.ID.Hex())
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	case probeRoutes[route]:
+		level = slog.LevelDebug
+	}
+	slog.Log(c.Context(), level, "request", attrs...)
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/logging"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "json", "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	var (
+		app  = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		user = &types.User{ID: primitive.NewObjectID()}
+		seen string
+	)
+	app.Use(RequestID, AccessLog)
+	app.Get("/calendar/:token", func(c *fiber.Ctx) error {
+		c.Context().SetUserValue("user", user)
+		seen = logging.RequestID(c.Context())
+		return ErrorNotFound()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/secret-token", nil)
+	req.Header.Set(requestIDHeader, "abc-123")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get(requestIDHeader) != "abc-123" || seen != "abc-123" {
+		t.Fatalf("expected the request id to be kept but got %q and %q", resp.Header.Get(requestIDHeader), seen)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404 but got %d", resp.StatusCode)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected one access log record but got %q", buf.String())
+	}
+	if record["requestID"] != "abc-123" || record["status"] != float64(http.StatusNotFound) || record["userID"] != user.ID.Hex() || record["level"] != "WARN" {
+		t.Fatalf("unexpected access log %v", record)
+	}
+	if record["route"] != "/calendar/:token" || strings.Contains(buf.String(), "secret-token") {
+		t.Fatalf("expected the route pattern instead of the path but got %v", record)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/calendar/x", nil)
+	req.Header.Set(requestIDHeader, "not a valid id!")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := resp.Header.Get(requestIDHeader); len(id) != 16 || id != seen {
+		t.Fatalf("expected a generated request id but got %q", id)
+	}
+}
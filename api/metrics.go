@@ -0,0 +1,24 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics counts requests and records their latency by route pattern, which
+// keeps the number of label values bounded.
+func Metrics(c *fiber.Ctx) error {
+	start := time.Now()
+	if err := c.Next(); err != nil {
+		sendError(c, err)
+	}
+
+	route := c.Route().Path
+	metrics.HTTPRequests.WithLabelValues(c.Method(), route, strconv.Itoa(c.Response().StatusCode())).Inc()
+	metrics.HTTPDuration.WithLabelValues(c.Method(), route).Observe(time.Since(start).Seconds())
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/events"
+	"github.com/aboronilov/go-hotel-reservation/metrics"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMetricsCountsByRoute(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(Metrics)
+	app.Get("/hotel/:id", func(c *fiber.Ctx) error {
+		return ErrorNotFound()
+	})
+
+	counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/hotel/:id", "404")
+	before := testutil.ToFloat64(counter)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/hotel/123", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404 but got %d", resp.StatusCode)
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Fatalf("expected the request to be counted under its route pattern but got %v", got)
+	}
+}
+
+func TestBookingMetricsCountEachEventOnce(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		broker = events.NewLocalBroker()
+		relay  = events.NewRelay(db.store, broker)
+		user   = fixtures.AddUser(db.store, "james", "foo", false)
+		hotel  = fixtures.AddHotel(db.store, "ibis", "paris")
+	)
+	broker.Subscribe("metrics", metrics.HandleEvent, types.EventBookingCreated, types.EventBookingCanceled)
+	broker.Subscribe("webhooks", func(ctx context.Context, event *types.Event) error {
+		return errors.New("receiver down")
+	})
+
+	counter := metrics.BookingsCreated.WithLabelValues("direct")
+	before := testutil.ToFloat64(counter)
+	fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], time.Now().AddDate(0, 0, 1), time.Now().AddDate(0, 0, 3))
+	for range 3 {
+		if err := relay.Run(context.TODO()); err != nil {
+			t.Fatal(err)
+		}
+		// make the retry due right away
+		pending, err := db.store.Event.GetPendingEvents(context.TODO(), time.Now().Add(time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, event := range pending {
+			if err := db.store.Event.UpdateEvent(context.TODO(), event.ID, bson.M{"nextAttemptAt": time.Now()}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Fatalf("expected the booking to be counted once despite retries, got %v", got)
+	}
+}
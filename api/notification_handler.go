@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/random"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationHandler struct {
+	store *db.Store
+}
+
+func NewNotificationHandler(store *db.Store) *NotificationHandler {
+	return &NotificationHandler{
+		store: store,
+	}
+}
+
+func (h *NotificationHandler) preferences(c *fiber.Ctx, user *types.User) (*types.NotificationPreferences, error) {
+	token, err := random.Token()
+	if err != nil {
+		return nil, err
+	}
+	return h.store.Notification.EnsurePreferences(c.Context(), &types.NotificationPreferences{
+		UserID:   user.ID,
+		Locale:   types.DefaultLocale,
+		OptedOut: []string{},
+		Token:    token,
+	})
+}
+
+// only owner
+func (h *NotificationHandler) HandleGetPreferences(c *fiber.Ctx) error {
+	user, err := getAuthUser(c)
+	if err != nil {
+		return ErrorUnauthorized()
+	}
+
+	prefs, err := h.preferences(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(prefs)
+}
+
+// only owner
+func (h *NotificationHandler) HandleUpdatePreferences(c *fiber.Ctx) error {
+	user, err := getAuthUser(c)
+	if err != nil {
+		return ErrorUnauthorized()
+	}
+
+	var params types.UpdateNotificationPreferencesParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	prefs, err := h.preferences(c, user)
+	if err != nil {
+		return err
+	}
+	if err := h.store.Notification.UpdatePreferences(c.Context(), prefs.ID, params.ToBson()); err != nil {
+		return err
+	}
+
+	prefs, err = h.preferences(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(prefs)
+}
+
+// public, the token from an unsubscribe link identifies the user. With a
+// ?template= only that kind of message is turned off.
+func (h *NotificationHandler) HandleUnsubscribe(c *fiber.Ctx) error {
+	prefs, err := h.store.Notification.GetPreferencesByToken(c.Context(), c.Params("token"))
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	template := c.Query("template")
+	update := bson.M{"unsubscribed": true}
+	if len(template) > 0 {
+		if !slices.Contains(types.NotificationTemplates, template) {
+			return NewError(http.StatusBadRequest, fmt.Sprintf("Unknown notification %s", template))
+		}
+		if !slices.Contains(prefs.OptedOut, template) {
+			prefs.OptedOut = append(prefs.OptedOut, template)
+		}
+		update = bson.M{"optedOut": prefs.OptedOut}
+	}
+	if err := h.store.Notification.UpdatePreferences(c.Context(), prefs.ID, update); err != nil {
+		return err
+	}
+
+	return c.JSON(map[string]string{"msg": "You have been unsubscribed"})
+}
+
+// only owner
+func (h *NotificationHandler) HandleListOwnNotifications(c *fiber.Ctx) error {
+	user, err := getAuthUser(c)
+	if err != nil {
+		return ErrorUnauthorized()
+	}
+
+	notifications, err := h.store.Notification.GetNotifications(c.Context(), bson.M{"userID": user.ID})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(notifications)
+}
+
+// admin auth
+func (h *NotificationHandler) HandleListNotifications(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if userID := c.Query("userID"); len(userID) > 0 {
+		oid, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return ErrorInvalidID()
+		}
+		filter["userID"] = oid
+	}
+	if status := c.Query("status"); len(status) > 0 {
+		filter["status"] = status
+	}
+	if template := c.Query("template"); len(template) > 0 {
+		filter["template"] = template
+	}
+
+	notifications, err := h.store.Notification.GetNotifications(c.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(notifications)
+}
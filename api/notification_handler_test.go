@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/events"
+	"github.com/aboronilov/go-hotel-reservation/notifications"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type recordingChannel struct {
+	sent []notifications.Message
+	fail bool
+}
+
+func (c *recordingChannel) Name() string { return "recording" }
+
+func (c *recordingChannel) Send(_ context.Context, msg notifications.Message) error {
+	if c.fail {
+		return errors.New("smtp unavailable")
+	}
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func TestBookingConfirmationAndUnsubscribe(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		channel = &recordingChannel{}
+		service = notifications.NewService(db.store, channel, "http://hotel.test")
+		broker  = events.NewLocalBroker()
+		relay   = events.NewRelay(db.store, broker)
+		user    = fixtures.AddUser(db.store, "james", "foo", false)
+		hotel   = fixtures.AddHotel(db.store, "ibis", "paris")
+		from    = time.Now().AddDate(0, 0, 10)
+	)
+	broker.Subscribe("notifications", service.HandleEvent, notifications.BookingEvents...)
+
+	booking := fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], from, from.AddDate(0, 0, 2))
+	if err := relay.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeliverDue(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(channel.sent) != 1 || channel.sent[0].To != user.Email || !strings.Contains(channel.sent[0].Body, booking.ID.Hex()) {
+		t.Fatalf("expected a confirmation for the booking, got %+v", channel.sent)
+	}
+
+	// follow the unsubscribe link from the email
+	app := fiber.New()
+	app.Get("/api/notifications/unsubscribe/:token", NewNotificationHandler(db.store).HandleUnsubscribe)
+	path := strings.TrimPrefix(channel.sent[0].UnsubscribeURL, "http://hotel.test")
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 but got %d", resp.StatusCode)
+	}
+
+	if _, err := db.store.Booking.CancelBooking(context.TODO(), booking.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := relay.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeliverDue(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(channel.sent) != 1 {
+		t.Fatalf("expected no email after unsubscribing, got %d", len(channel.sent))
+	}
+
+	logged, err := db.store.Notification.GetNotifications(context.TODO(), bson.M{"userID": user.ID, "template": types.TemplateBookingCancellation})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 1 || logged[0].Status != types.NotificationStatusSkipped {
+		t.Fatalf("expected the cancellation to be logged as skipped, got %+v", logged)
+	}
+}
+
+func TestNotificationRetriesAndScheduledMessages(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		channel = &recordingChannel{fail: true}
+		service = notifications.NewService(db.store, channel, "http://hotel.test")
+		user    = fixtures.AddUser(db.store, "james", "foo", false)
+		hotel   = fixtures.AddHotel(db.store, "ibis", "paris")
+	)
+	fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], time.Now().Add(24*time.Hour), time.Now().Add(72*time.Hour))
+	fixtures.AddBooking(db.store, user.ID, hotel.Rooms[1], time.Now().Add(-72*time.Hour), time.Now().Add(-24*time.Hour))
+	fixtures.AddBooking(db.store, user.ID, hotel.Rooms[2], time.Now().AddDate(0, 0, 10), time.Now().AddDate(0, 0, 12))
+
+	// running twice must not queue the same message twice
+	for i := 0; i < 2; i++ {
+		if err := service.QueueScheduled(context.TODO()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queued, err := db.store.Notification.GetNotifications(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := map[string]int{}
+	for _, n := range queued {
+		templates[n.Template]++
+	}
+	if len(queued) != 2 || templates[types.TemplatePreArrivalReminder] != 1 || templates[types.TemplateReceipt] != 1 {
+		t.Fatalf("expected one reminder and one receipt, got %v", templates)
+	}
+
+	if err := service.DeliverDue(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := db.store.Notification.GetNotifications(context.TODO(), bson.M{"status": types.NotificationStatusPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range pending {
+		if n.Attempts != 1 || !n.NextAttemptAt.After(time.Now()) || len(n.LastError) == 0 {
+			t.Fatalf("expected the failed send to be rescheduled, got %+v", n)
+		}
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending notifications, got %d", len(pending))
+	}
+
+	channel.fail = false
+	for _, n := range pending {
+		if err := db.store.Notification.UpdateNotification(context.TODO(), n.ID, bson.M{"nextAttemptAt": time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := service.DeliverDue(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(channel.sent) != 2 {
+		t.Fatalf("expected both messages to be sent on retry, got %d", len(channel.sent))
+	}
+}
+
+func TestLockoutNotificationKeepsTheTokenOutOfTheLog(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		channel = &recordingChannel{}
+		service = notifications.NewService(db.store, channel, "http://hotel.test")
+		user    = fixtures.AddUser(db.store, "james", "foo", false)
+		token   = "unlock-token"
+	)
+	user.LockedUntil = time.Now().Add(time.Hour)
+
+	if err := service.NotifyLockout(context.TODO(), user, token); err != nil {
+		t.Fatal(err)
+	}
+	if len(channel.sent) != 1 || !strings.Contains(channel.sent[0].Body, token) {
+		t.Fatalf("expected the unlock link to be sent, got %+v", channel.sent)
+	}
+
+	logged, err := db.store.Notification.GetNotifications(context.TODO(), bson.M{"userID": user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 1 || logged[0].Status != types.NotificationStatusSent || strings.Contains(logged[0].Body, token) {
+		t.Fatalf("expected a sent notification without the token, got %+v", logged)
+	}
+	// sent already, so the delivery job has nothing to do
+	if err := service.DeliverDue(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(channel.sent) != 1 {
+		t.Fatalf("expected the lockout message to be sent once, got %d", len(channel.sent))
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/availability"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OverbookingHandler struct {
+	store    *db.Store
+	checker  *availability.Checker
+	waitlist *waitlist.Manager
+}
+
+func NewOverbookingHandler(store *db.Store, waitlist *waitlist.Manager) *OverbookingHandler {
+	return &OverbookingHandler{
+		store:    store,
+		checker:  availability.NewChecker(store),
+		waitlist: waitlist,
+	}
+}
+
+// admin auth
+func (h *OverbookingHandler) HandleCreateAllowance(c *fiber.Ctx) error {
+	var params types.CreateOverbookingAllowanceParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	allowance := types.NewOverbookingAllowanceFromParams(params)
+	if _, err := h.store.Hotel.GetHotelByID(c.Context(), allowance.HotelID); err != nil {
+		return ErrorNotFound()
+	}
+
+	inserted, err := h.store.Overbooking.CreateAllowance(c.Context(), allowance)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(inserted)
+}
+
+// admin auth
+func (h *OverbookingHandler) HandleListAllowances(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if hotelID := c.Query("hotelID"); len(hotelID) > 0 {
+		oid, err := primitive.ObjectIDFromHex(hotelID)
+		if err != nil {
+			return ErrorInvalidID()
+		}
+		filter["hotelID"] = oid
+	}
+
+	allowances, err := h.store.Overbooking.GetAllowances(c.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(allowances)
+}
+
+// admin auth
+func (h *OverbookingHandler) HandleDeleteAllowance(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.store.Overbooking.DeleteAllowanceByID(c.Context(), id); err != nil {
+		return ErrorInvalidID()
+	}
+
+	return c.JSON(map[string]string{"msg": fmt.Sprintf("allowance %s deleted", id)})
+}
+
+// admin auth
+func (h *OverbookingHandler) HandleOversoldReport(c *fiber.Ctx) error {
+	hotelID, err := primitive.ObjectIDFromHex(c.Query("hotelID"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+
+	from := time.Now()
+	if len(c.Query("fromDate")) > 0 {
+		if from, err = time.Parse(time.RFC3339, c.Query("fromDate")); err != nil {
+			return NewError(http.StatusBadRequest, "Invalid fromDate, expected RFC3339")
+		}
+	}
+	till := from.AddDate(0, 0, 30)
+	if len(c.Query("tillDate")) > 0 {
+		if till, err = time.Parse(time.RFC3339, c.Query("tillDate")); err != nil {
+			return NewError(http.StatusBadRequest, "Invalid tillDate, expected RFC3339")
+		}
+	}
+
+	report, err := h.checker.OversoldNights(c.Context(), hotelID, from, till)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(report)
+}
+
+// admin auth
+func (h *OverbookingHandler) HandleWalkBooking(c *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+
+	var params types.WalkParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	booking, err := h.store.Booking.GetBookingByID(c.Context(), oid)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrorNotFound()
+		}
+		return err
+	}
+	if booking.Canceled || booking.Walk != nil {
+		return NewError(http.StatusBadRequest, "Booking is no longer active")
+	}
+
+	walk := types.Walk{
+		PartnerHotel: params.PartnerHotel,
+		Reason:       params.Reason,
+		Compensation: params.Compensation,
+		WalkedAt:     time.Now(),
+	}
+	if err := h.store.Booking.UpdateBooking(c.Context(), oid, bson.M{"walk": walk}); err != nil {
+		return err
+	}
+
+	if err := h.waitlist.Release(c.Context(), booking.RoomID); err != nil {
+		return err
+	}
+
+	return c.JSON(map[string]string{"message": fmt.Sprintf("Booking relocated to %s", walk.PartnerHotel)})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOverbookingAllowanceReportAndWalk(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		adminUser = fixtures.AddUser(db.store, "jack", "bauer", true)
+		guests    = []*types.User{
+			fixtures.AddUser(db.store, "james", "bond", false),
+			fixtures.AddUser(db.store, "john", "smith", false),
+			fixtures.AddUser(db.store, "jane", "doe", false),
+			fixtures.AddUser(db.store, "ethan", "hunt", false),
+		}
+		hotel              = fixtures.AddHotel(db.store, "ibis", "paris")
+		small              = hotel.Rooms[0]
+		otherSmall         = fixtures.AddRoom(db.store, "small", false, 100, hotel.ID).ID
+		from               = types.Night(time.Now().AddDate(0, 0, 10))
+		till               = from.AddDate(0, 0, 2)
+		manager            = waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour)
+		roomHandler        = NewRoomHandler(db.store, manager)
+		overbookingHandler = NewOverbookingHandler(db.store, manager)
+		app                = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		apiv1              = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		admin              = apiv1.Group("/admin", AdminAuth)
+	)
+	apiv1.Post("/room/:id/book", roomHandler.HandleBookRoom)
+	admin.Post("/overbooking", overbookingHandler.HandleCreateAllowance)
+	admin.Get("/overbooking/report", overbookingHandler.HandleOversoldReport)
+	admin.Post("/booking/:id/walk", overbookingHandler.HandleWalkBooking)
+
+	resp := postJSON(t, app, "/admin/overbooking", adminUser, types.CreateOverbookingAllowanceParams{
+		HotelID:  hotel.ID.Hex(),
+		RoomType: "small",
+		FromDate: from,
+		TillDate: till,
+		Limit:    1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the allowance to be created, got %d", resp.StatusCode)
+	}
+
+	book := func(guest *types.User, roomID primitive.ObjectID) (*types.Booking, int) {
+		resp := postJSON(t, app, "/room/"+roomID.Hex()+"/book", guest, BookRoomParams{FromDate: from, TillDate: till, NumPersons: 1})
+		if resp.StatusCode != http.StatusOK {
+			return nil, resp.StatusCode
+		}
+		var booking types.Booking
+		if err := json.NewDecoder(resp.Body).Decode(&booking); err != nil {
+			t.Fatal(err)
+		}
+		return &booking, resp.StatusCode
+	}
+
+	if _, status := book(guests[0], small); status != http.StatusOK {
+		t.Fatalf("expected the first booking to succeed, got %d", status)
+	}
+	// another room of the type is free, so the taken one is not overbooked
+	if _, status := book(guests[1], small); status != http.StatusBadRequest {
+		t.Fatalf("expected the taken room to be refused while another is free, got %d", status)
+	}
+	if _, status := book(guests[1], otherSmall); status != http.StatusOK {
+		t.Fatalf("expected the free room to be booked, got %d", status)
+	}
+	overbooked, status := book(guests[2], small)
+	if status != http.StatusOK || !overbooked.Overbooked {
+		t.Fatalf("expected an overbooking within the allowance, got %d %+v", status, overbooked)
+	}
+	if _, status := book(guests[3], otherSmall); status != http.StatusBadRequest {
+		t.Fatalf("expected the used up allowance to refuse the booking, got %d", status)
+	}
+
+	report := func() []types.OversoldNight {
+		path := fmt.Sprintf("/admin/overbooking/report?hotelID=%s&fromDate=%s&tillDate=%s", hotel.ID.Hex(), from.Format(time.RFC3339), till.Format(time.RFC3339))
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var nights []types.OversoldNight
+		if err := json.NewDecoder(resp.Body).Decode(&nights); err != nil {
+			t.Fatal(err)
+		}
+		return nights
+	}
+	nights := report()
+	if len(nights) != 2 {
+		t.Fatalf("expected both nights to be oversold, got %+v", nights)
+	}
+	for _, night := range nights {
+		if night.RoomType != "small" || night.Capacity != 2 || night.Sold != 3 || night.Oversold != 1 {
+			t.Fatalf("unexpected oversold night %+v", night)
+		}
+	}
+
+	walk := types.WalkParams{PartnerHotel: "Novotel", Reason: "oversold", Compensation: 50}
+	resp = postJSON(t, app, "/admin/booking/"+overbooked.ID.Hex()+"/walk", adminUser, walk)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the booking to be walked, got %d", resp.StatusCode)
+	}
+	walked, err := db.store.Booking.GetBookingByID(context.TODO(), overbooked.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walked.Walk == nil || walked.Walk.PartnerHotel != "Novotel" {
+		t.Fatalf("expected the walk to be recorded, got %+v", walked.Walk)
+	}
+	if nights := report(); len(nights) != 0 {
+		t.Fatalf("expected the walk to resolve the oversold nights, got %+v", nights)
+	}
+
+	resp = postJSON(t, app, "/admin/booking/"+overbooked.ID.Hex()+"/walk", adminUser, walk)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a walked booking not to be walked again, got %d", resp.StatusCode)
+	}
+}
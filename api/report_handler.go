@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxReportDays = 366
+
+type ReportHandler struct {
+	store *db.Store
+}
+
+func NewReportHandler(store *db.Store) *ReportHandler {
+	return &ReportHandler{
+		store: store,
+	}
+}
+
+// admin auth
+func (h *ReportHandler) HandleKPIReport(c *fiber.Ctx) error {
+	hotelID, err := primitive.ObjectIDFromHex(c.Query("hotelID"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+	if _, err := h.store.Hotel.GetHotelByID(c.Context(), hotelID); err != nil {
+		return ErrorNotFound()
+	}
+
+	granularity := types.Granularity(c.Query("granularity", string(types.GranularityDay)))
+	if !granularity.IsValid() {
+		return NewError(http.StatusBadRequest, "Invalid granularity, expected day, week or month")
+	}
+	from, err := parseDateQuery(c, "fromDate")
+	if err != nil {
+		return err
+	}
+	till, err := parseDateQuery(c, "tillDate")
+	if err != nil {
+		return err
+	}
+	from, till = types.Night(from), types.Night(till)
+	if !from.Before(till) || till.Sub(from) > maxReportDays*24*time.Hour {
+		return NewError(http.StatusBadRequest, "Invalid dates: fromDate should be before tillDate and span at most a year")
+	}
+
+	rooms, err := h.store.Report.CountRooms(c.Context(), hotelID)
+	if err != nil {
+		return err
+	}
+	current, err := h.store.Report.GetSales(c.Context(), hotelID, from, till, granularity)
+	if err != nil {
+		return err
+	}
+	previous, err := h.store.Report.GetSales(c.Context(), hotelID, from.Add(-till.Sub(from)), from, granularity)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(types.BuildKPIReport(hotelID, granularity, rooms, from, till, current, previous))
+}
+
+// parseDateQuery reads a required date query parameter given either as a
+// plain date or as an RFC3339 timestamp.
+func parseDateQuery(c *fiber.Ctx, key string) (time.Time, error) {
+	value := c.Query(key)
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, NewError(http.StatusBadRequest, "Invalid "+key+", expected YYYY-MM-DD or RFC3339")
+	}
+	return t, nil
+}
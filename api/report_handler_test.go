@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestKPIReportSplitsStaysAcrossPeriods(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		adminUser     = fixtures.AddUser(db.store, "james", "bond", true)
+		hotel         = fixtures.AddHotel(db.store, "ibis", "paris")
+		app           = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		admin         = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth), AdminAuth)
+		reportHandler = NewReportHandler(db.store)
+	)
+	admin.Get("/kpi", reportHandler.HandleKPIReport)
+
+	_, err := db.store.Booking.BookRoom(context.TODO(), &types.Booking{
+		UserID:   adminUser.ID,
+		RoomID:   hotel.Rooms[0],
+		FromDate: time.Date(2030, time.January, 30, 14, 0, 0, 0, time.UTC),
+		TillDate: time.Date(2030, time.February, 2, 11, 0, 0, 0, time.UTC),
+		Price:    300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := fmt.Sprintf("/kpi?hotelID=%s&fromDate=2030-01-01&tillDate=2030-03-01&granularity=month", hotel.ID.Hex())
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+
+	var report types.KPIReport
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Periods) != 2 {
+		t.Fatalf("expected 2 periods, got %d", len(report.Periods))
+	}
+
+	january, february := report.Periods[0], report.Periods[1]
+	if january.SoldNights != 2 || january.Revenue != 200 || january.AvailableNights != 3*31 {
+		t.Fatalf("unexpected january figures %+v", january)
+	}
+	if february.SoldNights != 1 || february.Revenue != 100 || february.AvailableNights != 3*28 {
+		t.Fatalf("unexpected february figures %+v", february)
+	}
+	if report.Total.ADR != 100 {
+		t.Fatalf("expected ADR 100, got %.2f", report.Total.ADR)
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RestrictionHandler struct {
+	store *db.Store
+}
+
+func NewRestrictionHandler(store *db.Store) *RestrictionHandler {
+	return &RestrictionHandler{
+		store: store,
+	}
+}
+
+// admin auth
+func (h *RestrictionHandler) HandleCreateRestriction(c *fiber.Ctx) error {
+	var params types.CreateStayRestrictionParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	restriction := types.NewStayRestrictionFromParams(params)
+	if _, err := h.store.Hotel.GetHotelByID(c.Context(), restriction.HotelID); err != nil {
+		return ErrorNotFound()
+	}
+
+	inserted, err := h.store.Restriction.CreateRestriction(c.Context(), restriction)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(inserted)
+}
+
+// admin auth
+func (h *RestrictionHandler) HandleListRestrictions(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if hotelID := c.Query("hotelID"); len(hotelID) > 0 {
+		oid, err := primitive.ObjectIDFromHex(hotelID)
+		if err != nil {
+			return ErrorInvalidID()
+		}
+		filter["hotelID"] = oid
+	}
+
+	restrictions, err := h.store.Restriction.GetRestrictions(c.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(restrictions)
+}
+
+// admin auth
+func (h *RestrictionHandler) HandleDeleteRestriction(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.store.Restriction.DeleteRestrictionByID(c.Context(), id); err != nil {
+		return ErrorInvalidID()
+	}
+
+	return c.JSON(map[string]string{"msg": fmt.Sprintf("restriction %s deleted", id)})
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReviewHandler struct {
+	store *db.Store
+}
+
+func NewReviewHandler(store *db.Store) *ReviewHandler {
+	return &ReviewHandler{
+		store: store,
+	}
+}
+
+// only the guest of a completed stay, once per booking
+func (h *ReviewHandler) HandleCreateReview(c *fiber.Ctx) error {
+	var params types.CreateReviewParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	user, err := getAuthUser(c)
+	if err != nil {
+		return ErrorUnauthorized()
+	}
+
+	bookingID, _ := primitive.ObjectIDFromHex(params.BookingID)
+	booking, err := h.store.Booking.GetBookingByID(c.Context(), bookingID)
+	if err != nil {
+		return ErrorNotFound()
+	}
+	if booking.UserID != user.ID {
+		return ErrorUnauthorized()
+	}
+	if !booking.CanBeReviewed(time.Now()) {
+		return NewError(http.StatusBadRequest, "Only completed stays can be reviewed")
+	}
+
+	room, err := h.store.Room.GetRoomByID(c.Context(), booking.RoomID.Hex())
+	if err != nil {
+		return err
+	}
+
+	// the unique bookingID index rejects a second review, also when both
+	// are sent at once
+	inserted, err := h.store.Review.CreateReview(c.Context(), types.NewReviewFromParams(params, booking, room.HotelID))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return NewError(http.StatusConflict, fmt.Sprintf("Booking %s has already been reviewed", booking.ID.Hex()))
+		}
+		return err
+	}
+
+	return c.JSON(inserted)
+}
+
+// approved reviews only
+func (h *ReviewHandler) HandleListHotelReviews(c *fiber.Ctx) error {
+	hotelID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+
+	reviews, err := h.store.Review.GetReviews(c.Context(), bson.M{"hotelID": hotelID, "status": types.ReviewStatusApproved})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(reviews)
+}
+
+// admin auth
+func (h *ReviewHandler) HandleListReviews(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if status := c.Query("status"); len(status) > 0 {
+		filter["status"] = status
+	}
+	if hotelID := c.Query("hotelID"); len(hotelID) > 0 {
+		oid, err := primitive.ObjectIDFromHex(hotelID)
+		if err != nil {
+			return ErrorInvalidID()
+		}
+		filter["hotelID"] = oid
+	}
+
+	reviews, err := h.store.Review.GetReviews(c.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(reviews)
+}
+
+// admin auth
+func (h *ReviewHandler) HandleModerateReview(c *fiber.Ctx) error {
+	var params types.ModerateReviewParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	review, err := h.store.Review.GetReviewByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	update := bson.M{"status": params.Status, "moderationNote": params.Note}
+	if err := h.store.Review.UpdateReview(c.Context(), review.ID, update); err != nil {
+		return err
+	}
+	if err := h.store.Review.UpdateHotelRating(c.Context(), review.HotelID); err != nil {
+		return err
+	}
+
+	review.Status = params.Status
+	review.ModerationNote = params.Note
+	return c.JSON(review)
+}
+
+// admin auth, replies on behalf of the hotel
+func (h *ReviewHandler) HandleReplyReview(c *fiber.Ctx) error {
+	var params types.ReplyReviewParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	review, err := h.store.Review.GetReviewByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	review.Reply = &types.ReviewReply{
+		Text:      params.Text,
+		RepliedAt: time.Now().UTC(),
+	}
+	if err := h.store.Review.UpdateReview(c.Context(), review.ID, bson.M{"reply": review.Reply}); err != nil {
+		return err
+	}
+
+	return c.JSON(review)
+}
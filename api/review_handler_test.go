@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+func postJSON(t *testing.T, app *fiber.App, path string, user *types.User, body any) *http.Response {
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestReviewsOfCompletedStaysUpdateHotelRating(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user      = fixtures.AddUser(db.store, "james", "foo", false)
+		adminUser = fixtures.AddUser(db.store, "jack", "bauer", true)
+		hotel     = fixtures.AddHotel(db.store, "ibis", "paris")
+		past      = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], time.Now().AddDate(0, 0, -5), time.Now().AddDate(0, 0, -2))
+		upcoming  = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[1], time.Now().AddDate(0, 0, 2), time.Now().AddDate(0, 0, 5))
+		app       = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		apiv1     = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		admin     = apiv1.Group("/admin", AdminAuth)
+		handler   = NewReviewHandler(db.store)
+	)
+	apiv1.Post("/review", handler.HandleCreateReview)
+	admin.Post("/review/:id/moderate", handler.HandleModerateReview)
+
+	params := types.CreateReviewParams{BookingID: upcoming.ID.Hex(), Cleanliness: 5, Location: 4, Service: 3, Text: "great"}
+	if resp := postJSON(t, app, "/review", user, params); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected an upcoming stay not to be reviewable, got %d", resp.StatusCode)
+	}
+
+	params.BookingID = past.ID.Hex()
+	resp := postJSON(t, app, "/review", user, params)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+	var review types.Review
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		t.Fatal(err)
+	}
+	if review.Status != types.ReviewStatusPending || review.Overall != 4 || review.HotelID != hotel.ID {
+		t.Fatalf("unexpected review %+v", review)
+	}
+
+	if resp := postJSON(t, app, "/review", user, params); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected a second review of the same booking to be rejected, got %d", resp.StatusCode)
+	}
+
+	// pending reviews do not count
+	updated, err := db.store.Hotel.GetHotelByID(context.TODO(), hotel.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ReviewCount != 0 {
+		t.Fatalf("expected no reviews to count yet, got %d", updated.ReviewCount)
+	}
+
+	resp = postJSON(t, app, "/admin/review/"+review.ID.Hex()+"/moderate", adminUser, types.ModerateReviewParams{Status: types.ReviewStatusApproved})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+	updated, err = db.store.Hotel.GetHotelByID(context.TODO(), hotel.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ReviewCount != 1 || updated.Rating != 4 {
+		t.Fatalf("expected a rating of 4 from 1 review, got %.1f from %d", updated.Rating, updated.ReviewCount)
+	}
+
+	resp = postJSON(t, app, "/admin/review/"+review.ID.Hex()+"/moderate", adminUser, types.ModerateReviewParams{Status: types.ReviewStatusRejected, Note: "spam"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+	updated, err = db.store.Hotel.GetHotelByID(context.TODO(), hotel.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ReviewCount != 0 || updated.Rating != 0 {
+		t.Fatalf("expected the rejected review to be removed from the rating, got %.1f from %d", updated.Rating, updated.ReviewCount)
+	}
+}
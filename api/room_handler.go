@@ -2,19 +2,25 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/aboronilov/go-hotel-reservation/availability"
 	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/metrics"
 	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type RoomHandler struct {
-	store *db.Store
+	store    *db.Store
+	checker  *availability.Checker
+	waitlist *waitlist.Manager
 }
 
 type BookRoomParams struct {
@@ -40,9 +46,11 @@ func (p *BookRoomParams) validate() error {
 	return nil
 }
 
-func NewRoomHandler(store *db.Store) *RoomHandler {
+func NewRoomHandler(store *db.Store, waitlist *waitlist.Manager) *RoomHandler {
 	return &RoomHandler{
-		store: store,
+		store:    store,
+		checker:  availability.NewChecker(store),
+		waitlist: waitlist,
 	}
 }
 
@@ -52,6 +60,11 @@ func (h *RoomHandler) HandleListRooms(c *fiber.Ctx) error {
 		return err
 	}
 
+	rooms, err = filterAvailableRooms(c, h.checker, rooms)
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(rooms)
 }
 
@@ -75,14 +88,26 @@ func (h *RoomHandler) HandleBookRoom(c *fiber.Ctx) error {
 		return ErrorUnauthorized()
 	}
 
-	ok, err = h.isRoomAvailiable(c.Context(), roomID, params)
+	room, err := h.store.Room.GetRoomByID(c.Context(), roomID.Hex())
 	if err != nil {
-		return err
+		return ErrorNotFound()
 	}
-	if !ok {
-		message := fmt.Sprintf("Room %s is already booked between %s and %s", roomID.Hex(), params.FromDate.Format(time.RFC3339), params.TillDate.Format(time.RFC3339))
+
+	overbooked, err := h.checker.CheckBooking(c.Context(), room, availability.Request{
+		RoomID:     roomID,
+		UserID:     user.ID,
+		FromDate:   params.FromDate,
+		TillDate:   params.TillDate,
+		NumPersons: params.NumPersons,
+	})
+	if errors.Is(err, availability.ErrUnavailable) {
+		metrics.AvailabilityConflicts.WithLabelValues(metrics.ConflictUnavailable).Inc()
+		message := fmt.Sprintf("Room %s is already booked between %s and %s, join the waitlist to be notified when it frees up", roomID.Hex(), params.FromDate.Format(time.RFC3339), params.TillDate.Format(time.RFC3339))
 		return NewError(http.StatusBadRequest, message)
 	}
+	if err != nil {
+		return restrictionError(room, err)
+	}
 
 	booking := types.Booking{
 		UserID:     user.ID,
@@ -90,28 +115,56 @@ func (h *RoomHandler) HandleBookRoom(c *fiber.Ctx) error {
 		FromDate:   params.FromDate,
 		TillDate:   params.TillDate,
 		NumPersons: params.NumPersons,
+		Price:      room.PriceFor(params.FromDate, params.TillDate),
+		Overbooked: overbooked,
 	}
 
 	inserted, err := h.store.Booking.BookRoom(c.Context(), &booking)
 	if err != nil {
 		return ErrorBadRequest()
 	}
+	recordAudit(c, h.store.Audit, types.AuditBookingCreate, "booking", inserted.ID.Hex(), nil, inserted)
+
+	if err := h.waitlist.Claim(c.Context(), user.ID, roomID); err != nil {
+		return err
+	}
 
 	return c.JSON(inserted)
 }
 
-func (h *RoomHandler) isRoomAvailiable(ctx context.Context, roomID primitive.ObjectID, params BookRoomParams) (bool, error) {
-	filter := bson.M{
-		"roomID":   roomID,
-		"fromDate": bson.M{"$lte": params.TillDate},
-		"tillDate": bson.M{"$gte": params.FromDate},
+// checkRestrictions turns a stay restriction violation into a bad request.
+func checkRestrictions(ctx context.Context, checker *availability.Checker, room *types.Room, from, till time.Time) error {
+	return restrictionError(room, checker.CheckRestrictions(ctx, room, from, till))
+}
+
+func restrictionError(room *types.Room, err error) error {
+	var restricted *availability.RestrictionError
+	if errors.As(err, &restricted) {
+		metrics.AvailabilityConflicts.WithLabelValues(metrics.ConflictRestricted).Inc()
+		return NewError(http.StatusBadRequest, fmt.Sprintf("Room %s cannot be booked: %s", room.ID.Hex(), restricted.Reason))
+	}
+	return err
+}
+
+// filterAvailableRooms narrows rooms down to those bookable for the stay given
+// by the fromDate and tillDate query parameters. Without them rooms are
+// returned unchanged.
+func filterAvailableRooms(c *fiber.Ctx, checker *availability.Checker, rooms []*types.Room) ([]*types.Room, error) {
+	if len(c.Query("fromDate")) == 0 && len(c.Query("tillDate")) == 0 {
+		return rooms, nil
 	}
-	bookings, err := h.store.Booking.GetBookings(ctx, filter)
 
+	from, err := time.Parse(time.RFC3339, c.Query("fromDate"))
 	if err != nil {
-		return false, err
+		return nil, NewError(http.StatusBadRequest, "Invalid fromDate, expected RFC3339")
+	}
+	till, err := time.Parse(time.RFC3339, c.Query("tillDate"))
+	if err != nil {
+		return nil, NewError(http.StatusBadRequest, "Invalid tillDate, expected RFC3339")
+	}
+	if !from.Before(till) {
+		return nil, NewError(http.StatusBadRequest, "Invalid dates: fromDate should be before tillDate")
 	}
 
-	ok := len(bookings) == 0
-	return ok, nil
+	return checker.AvailableRooms(c.Context(), rooms, from, till)
 }
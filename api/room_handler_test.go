@@ -0,0 +1,61 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBookingRespectsMinimumStay(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user        = fixtures.AddUser(db.store, "john", "smith", false)
+		hotel       = fixtures.AddHotel(db.store, "ibis", "paris")
+		from        = time.Now().AddDate(0, 0, 7)
+		app         = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		route       = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		roomHandler = NewRoomHandler(db.store, waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour))
+	)
+	route.Post("/:id/book", roomHandler.HandleBookRoom)
+
+	_, err := db.store.Restriction.CreateRestriction(context.TODO(), &types.StayRestriction{
+		HotelID:  hotel.ID,
+		FromDate: types.Night(from),
+		TillDate: types.Night(from.AddDate(0, 0, 2)),
+		MinStay:  3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for nights, expected := range map[int]int{2: http.StatusBadRequest, 3: http.StatusOK} {
+		params := BookRoomParams{
+			FromDate:   from,
+			TillDate:   from.AddDate(0, 0, nights),
+			NumPersons: 2,
+		}
+		b, _ := json.Marshal(params)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/%s/book", hotel.Rooms[nights-2].Hex()), bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Add("Authorization", CreateTokenFromUser(user, testConfig.Auth))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != expected {
+			t.Fatalf("expected status code %d for a %d night stay, got %d", expected, nights, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aboronilov/go-hotel-reservation/search"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type SearchHandler struct {
+	searcher search.Searcher
+}
+
+func NewSearchHandler(searcher search.Searcher) *SearchHandler {
+	return &SearchHandler{
+		searcher: searcher,
+	}
+}
+
+// ?q=spa paris&limit=20
+func (h *SearchHandler) HandleSearchHotels(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) == 0 {
+		return NewError(http.StatusBadRequest, "Missing search query q")
+	}
+
+	limit := defaultSearchLimit
+	if l := c.Query("limit"); len(l) > 0 {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return NewError(http.StatusBadRequest, "Invalid limit, expected 1 to 100")
+		}
+		limit = n
+	}
+
+	matches, err := h.searcher.Search(c.Context(), query, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(matches)
+}
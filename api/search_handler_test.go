@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/search"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSearchHotels(t *testing.T) {
+	var (
+		idx     = search.NewMemoryIndex()
+		app     = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		handler = NewSearchHandler(idx)
+	)
+	app.Get("/hotel/search", handler.HandleSearchHotels)
+	idx.Add(
+		&types.Hotel{ID: primitive.NewObjectID(), Name: "Savoy", Address: &types.Address{City: "London"}, Amenities: []string{types.AmenitySpa}},
+		&types.Hotel{ID: primitive.NewObjectID(), Name: "Ritz", Address: &types.Address{City: "Paris"}, Amenities: []string{types.AmenitySpa}},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/hotel/search?q=spa+londn", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 but got %d", resp.StatusCode)
+	}
+	var matches []types.HotelMatch
+	if err := json.NewDecoder(resp.Body).Decode(&matches); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].Name != "Savoy" || matches[0].Score <= matches[1].Score {
+		t.Fatalf("expected Savoy to rank first but got %+v", matches)
+	}
+
+	for _, path := range []string{"/hotel/search", "/hotel/search?q=spa&limit=0"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected status 400 but got %d", path, resp.StatusCode)
+		}
+	}
+}
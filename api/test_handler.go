@@ -5,37 +5,66 @@ import (
 	"log"
 	"testing"
 
+	"github.com/aboronilov/go-hotel-reservation/config"
 	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/db/migrations"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// testConfig points the tests at a database of their own.
+var testConfig = func() *config.Config {
+	cfg := config.Default()
+	cfg.DB.Name = "test-hotel-reservation"
+	cfg.Auth.JWTSecret = "test-secret"
+	return cfg
+}()
+
 type testdb struct {
 	client *mongo.Client
 	store  *db.Store
 }
 
 func (tdb *testdb) teardown(t *testing.T) {
-	if err := tdb.client.Database(db.TestDBNAME).Drop(context.TODO()); err != nil {
+	if err := tdb.client.Database(testConfig.DB.Name).Drop(context.TODO()); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func setup(t *testing.T) *testdb {
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(db.DBURI))
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(testConfig.DB.URI))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	hotelStore := db.NewMongoHotelStore(client, true)
+	if _, err := migrations.NewMigrator(client.Database(testConfig.DB.Name), migrations.All).Up(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	hotelStore := db.NewMongoHotelStore(client, testConfig.DB.Name)
 
 	return &testdb{
 		client: client,
 		store: &db.Store{
-			User:    db.NewMongoUserStore(client, true),
-			Room:    db.NewMongoRoomStore(client, hotelStore, true),
-			Hotel:   hotelStore,
-			Booking: db.NewMongoBookingStore(client, true),
+			User:         db.NewMongoUserStore(client, testConfig.DB.Name),
+			Room:         db.NewMongoRoomStore(client, hotelStore, testConfig.DB.Name),
+			Hotel:        hotelStore,
+			Booking:      db.NewMongoBookingStore(client, testConfig.DB.Name),
+			Waitlist:     db.NewMongoWaitlistStore(client, testConfig.DB.Name),
+			Block:        db.NewMongoBlockStore(client, testConfig.DB.Name),
+			Itinerary:    db.NewMongoItineraryStore(client, testConfig.DB.Name),
+			Restriction:  db.NewMongoRestrictionStore(client, testConfig.DB.Name),
+			Overbooking:  db.NewMongoOverbookingStore(client, testConfig.DB.Name),
+			Report:       db.NewMongoReportStore(client, testConfig.DB.Name),
+			Calendar:     db.NewMongoCalendarStore(client, testConfig.DB.Name),
+			Channel:      db.NewMongoChannelStore(client, testConfig.DB.Name),
+			Webhook:      db.NewMongoWebhookStore(client, testConfig.DB.Name),
+			Event:        db.NewMongoEventStore(client, testConfig.DB.Name),
+			Notification: db.NewMongoNotificationStore(client, testConfig.DB.Name),
+			Review:       db.NewMongoReviewStore(client, testConfig.DB.Name),
+			Attempt:      db.NewMongoAttemptStore(client, testConfig.DB.Name),
+			Audit:        db.NewMongoAuditStore(client, testConfig.DB.Name),
+			Settings:     db.NewMongoSettingsStore(client, testConfig.DB.Name),
 		},
 	}
 }
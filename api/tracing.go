@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/aboronilov/go-hotel-reservation/logging"
+	"github.com/aboronilov/go-hotel-reservation/tracing"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace of
+// the caller when the request carries a traceparent header. The span is
+// named after the route pattern once the request has been routed.
+func Tracing(c *fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), propagation.HeaderCarrier(c.GetReqHeaders()))
+	ctx, span := tracing.Tracer().Start(ctx, c.Method(), trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	c.Context().SetUserValue(tracing.SpanKey, span)
+	c.SetUserContext(ctx)
+	if err := c.Next(); err != nil {
+		sendError(c, err)
+	}
+
+	route := c.Route().Path
+	status := c.Response().StatusCode()
+	span.SetName(c.Method() + " " + route)
+	span.SetAttributes(
+		semconv.HTTPRequestMethodKey.String(c.Method()),
+		semconv.HTTPRoute(route),
+		semconv.HTTPResponseStatusCode(status),
+		attribute.String("request.id", logging.RequestID(c.Context())),
+	)
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+
+	return nil
+}
+
+// Traced runs a middleware in a span of its own. Everything the middleware
+// passes the request on to runs inside that span as well.
+func Traced(name string, handler fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parent := c.Context().UserValue(tracing.SpanKey)
+		_, span := tracing.Tracer().Start(tracing.Context(c.Context()), name)
+		defer span.End()
+
+		c.Context().SetUserValue(tracing.SpanKey, span)
+		defer c.Context().SetUserValue(tracing.SpanKey, parent)
+		userCtx := c.UserContext()
+		c.SetUserContext(trace.ContextWithSpan(userCtx, span))
+		defer c.SetUserContext(userCtx)
+
+		err := handler(c)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
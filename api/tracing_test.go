@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/tracing"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type tracedHotelStore struct {
+	db.HotelStore
+}
+
+func (tracedHotelStore) GetHotelByID(ctx context.Context, id primitive.ObjectID) (*types.Hotel, error) {
+	return &types.Hotel{ID: id}, nil
+}
+
+func TestTracingNestsMiddlewareAndStoreSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var (
+		app   = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		store = db.Instrument(&db.Store{Hotel: tracedHotelStore{}}, tracing.StoreObserver{})
+	)
+	app.Use(Tracing)
+	app.Get("/hotel/:id", Traced("auth", func(c *fiber.Ctx) error {
+		return c.Next()
+	}), func(c *fiber.Ctx) error {
+		hotel, err := store.Hotel.GetHotelByID(c.Context(), primitive.NewObjectID())
+		if err != nil {
+			return err
+		}
+		return c.JSON(hotel)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/hotel/123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 but got %d", resp.StatusCode)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	var (
+		request = spans["GET /hotel/:id"]
+		auth    = spans["auth"]
+		call    = spans["Hotel.GetHotelByID"]
+	)
+	if request == nil || auth == nil || call == nil {
+		t.Fatalf("expected request, middleware and store spans but got %v", spans)
+	}
+	if request.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected the trace of the caller to be continued but got %s", request.SpanContext().TraceID())
+	}
+	if auth.Parent().SpanID() != request.SpanContext().SpanID() || call.Parent().SpanID() != auth.SpanContext().SpanID() {
+		t.Fatal("expected the store span in the middleware span in the request span")
+	}
+}
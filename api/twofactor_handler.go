@@ -0,0 +1,341 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/authguard"
+	"github.com/aboronilov/go-hotel-reservation/config"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/twofactor"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TwoFactorHandler struct {
+	store *db.Store
+	guard *authguard.Guard
+	auth  config.Auth
+}
+
+func NewTwoFactorHandler(store *db.Store, guard *authguard.Guard, auth config.Auth) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		store: store,
+		guard: guard,
+		auth:  auth,
+	}
+}
+
+type EnrollResponse struct {
+	Secret string `json:"secret"`
+	// ProvisioningURI is the otpauth URI to show as a QR code.
+	ProvisioningURI string `json:"provisioningURI"`
+}
+
+// RecoveryCodesResponse carries the recovery codes, which are only ever
+// shown once. Token replaces the auth token after enrollment, since the user
+// has just proved the second factor.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+	Token         string   `json:"token,omitempty"`
+}
+
+type CodeParams struct {
+	Code string `json:"code"`
+}
+
+// HandleEnroll starts enrollment with a new secret. Two-factor
+// authentication is only turned on once HandleConfirm gets a code for it.
+func (h *TwoFactorHandler) HandleEnroll(c *fiber.Ctx) error {
+	user, ok := c.Context().Value("user").(*types.User)
+	if !ok {
+		return ErrorUnauthorized()
+	}
+	if user.TwoFactorEnabled {
+		return NewError(http.StatusConflict, "Two-factor authentication is enabled already")
+	}
+
+	secret, err := twofactor.NewSecret()
+	if err != nil {
+		return err
+	}
+	if err := h.store.User.SetTwoFactor(c.Context(), user.ID, secret, false, nil); err != nil {
+		return err
+	}
+
+	return c.JSON(EnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: twofactor.ProvisioningURI(h.auth.TwoFactorIssuer, user.Email, secret),
+	})
+}
+
+// HandleConfirm turns two-factor authentication on with the first code from
+// the authenticator app and returns the recovery codes.
+func (h *TwoFactorHandler) HandleConfirm(c *fiber.Ctx) error {
+	user, ok := c.Context().Value("user").(*types.User)
+	if !ok {
+		return ErrorUnauthorized()
+	}
+	var params CodeParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if user.TwoFactorEnabled {
+		return NewError(http.StatusConflict, "Two-factor authentication is enabled already")
+	}
+	if len(user.TOTPSecret) == 0 {
+		return NewError(http.StatusBadRequest, "Start the enrollment first")
+	}
+
+	ok, err := useTOTPCode(c.Context(), h.store.User, user, params.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewError(http.StatusBadRequest, "Invalid code")
+	}
+
+	codes, hashes, err := twofactor.NewRecoveryCodes()
+	if err != nil {
+		return err
+	}
+	if err := h.store.User.SetTwoFactor(c.Context(), user.ID, user.TOTPSecret, true, hashes); err != nil {
+		return err
+	}
+	enabled := *user
+	enabled.TwoFactorEnabled = true
+	recordAudit(c, h.store.Audit, types.AuditTwoFactorEnable, "user", user.ID.Hex(), user, &enabled)
+
+	return c.JSON(RecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Token:         CreateTokenFromUser(&enabled, h.auth),
+	})
+}
+
+// HandleDisable turns two-factor authentication off after checking a code,
+// unless the policy requires it for the user.
+func (h *TwoFactorHandler) HandleDisable(c *fiber.Ctx) error {
+	user, ok := c.Context().Value("user").(*types.User)
+	if !ok {
+		return ErrorUnauthorized()
+	}
+	var params CodeParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if !user.TwoFactorEnabled {
+		return NewError(http.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+
+	policy, err := h.store.Settings.GetSecurityPolicy(c.Context())
+	if err != nil {
+		return err
+	}
+	if policy.RequireAdminTwoFactor && user.IsAdmin {
+		return NewError(http.StatusForbidden, "Two-factor authentication is required for admins")
+	}
+
+	if err := h.checkCodeAllowed(c, user); err != nil {
+		return err
+	}
+	recovery, ok, err := verifySecondFactor(c.Context(), h.store.User, user, params.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return wrongCode(c, h.guard, user)
+	}
+	if err := h.codeAccepted(c, user, recovery); err != nil {
+		return err
+	}
+
+	if err := h.store.User.SetTwoFactor(c.Context(), user.ID, "", false, nil); err != nil {
+		return err
+	}
+	disabled := *user
+	disabled.TwoFactorEnabled = false
+	recordAudit(c, h.store.Audit, types.AuditTwoFactorDisable, "user", user.ID.Hex(), user, &disabled)
+
+	return c.JSON(genericResponse{
+		Message: "Two-factor authentication disabled",
+		Type:    "success",
+	})
+}
+
+// HandleRegenerateRecoveryCodes replaces the recovery codes, for when they
+// were used up or may have leaked.
+func (h *TwoFactorHandler) HandleRegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user, ok := c.Context().Value("user").(*types.User)
+	if !ok {
+		return ErrorUnauthorized()
+	}
+	var params CodeParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if !user.TwoFactorEnabled {
+		return NewError(http.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+
+	if err := h.checkCodeAllowed(c, user); err != nil {
+		return err
+	}
+	ok, err := useTOTPCode(c.Context(), h.store.User, user, params.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return wrongCode(c, h.guard, user)
+	}
+	if err := h.codeAccepted(c, user, false); err != nil {
+		return err
+	}
+
+	codes, hashes, err := twofactor.NewRecoveryCodes()
+	if err != nil {
+		return err
+	}
+	if err := h.store.User.SetTwoFactor(c.Context(), user.ID, user.TOTPSecret, true, hashes); err != nil {
+		return err
+	}
+	recordAudit(c, h.store.Audit, types.AuditRecoveryCodes, "user", user.ID.Hex(), nil, nil)
+
+	return c.JSON(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// HandleResetUser turns two-factor authentication off for a user who lost
+// both the authenticator and the recovery codes.
+func (h *TwoFactorHandler) HandleResetUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrorInvalidID()
+	}
+	user, err := h.store.User.GetUserByID(c.Context(), id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrorNotFound()
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.User.SetTwoFactor(c.Context(), oid, "", false, nil); err != nil {
+		return err
+	}
+	reset := *user
+	reset.TwoFactorEnabled = false
+	recordAudit(c, h.store.Audit, types.AuditTwoFactorReset, "user", id, user, &reset)
+
+	return c.JSON(map[string]string{"reset": id})
+}
+
+func (h *TwoFactorHandler) HandleGetPolicy(c *fiber.Ctx) error {
+	policy, err := h.store.Settings.GetSecurityPolicy(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(policy)
+}
+
+func (h *TwoFactorHandler) HandleUpdatePolicy(c *fiber.Ctx) error {
+	user, ok := c.Context().Value("user").(*types.User)
+	if !ok {
+		return ErrorUnauthorized()
+	}
+	var params types.UpdateSecurityPolicyParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if params.RequireAdminTwoFactor == nil {
+		return NewError(http.StatusBadRequest, "requireAdminTwoFactor is required")
+	}
+	// the admin would shut themselves out of the admin endpoints otherwise
+	if *params.RequireAdminTwoFactor && !hasTwoFactorLogin(c) {
+		return NewError(http.StatusBadRequest, "Log in with two-factor authentication before requiring it")
+	}
+
+	before, err := h.store.Settings.GetSecurityPolicy(c.Context())
+	if err != nil {
+		return err
+	}
+	policy := &types.SecurityPolicy{
+		RequireAdminTwoFactor: *params.RequireAdminTwoFactor,
+		UpdatedBy:             user.ID,
+		UpdatedAt:             time.Now().UTC(),
+	}
+	if err := h.store.Settings.UpdateSecurityPolicy(c.Context(), policy); err != nil {
+		return err
+	}
+	recordAudit(c, h.store.Audit, types.AuditSecurityPolicy, "settings", "security", before, policy)
+
+	return c.JSON(policy)
+}
+
+// RequireTwoFactor refuses admins who did not log in with two-factor
+// authentication while the security policy requires it. It goes after
+// AdminAuth.
+func RequireTwoFactor(settingsStore db.SettingsStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		policy, err := settingsStore.GetSecurityPolicy(c.Context())
+		if err != nil {
+			return err
+		}
+		if policy.RequireAdminTwoFactor && !hasTwoFactorLogin(c) {
+			return NewError(http.StatusForbidden, "Two-factor authentication is required for admins, enable it and log in again")
+		}
+
+		return c.Next()
+	}
+}
+
+// checkCodeAllowed applies the login budgets to endpoints that check a code
+// of a signed in user, so a token alone is not enough to guess codes.
+func (h *TwoFactorHandler) checkCodeAllowed(c *fiber.Ctx, user *types.User) error {
+	if err := throttle(c, h.guard, user.Email); err != nil {
+		return err
+	}
+	if user.IsLocked(time.Now()) {
+		return locked(c, h.guard, user)
+	}
+	return nil
+}
+
+func (h *TwoFactorHandler) codeAccepted(c *fiber.Ctx, user *types.User, recovery bool) error {
+	if recovery {
+		if err := h.guard.RecoveryCodeUsed(c.Context(), c.IP(), user); err != nil {
+			return err
+		}
+	}
+	return h.guard.Succeeded(c.Context(), user.Email)
+}
+
+func hasTwoFactorLogin(c *fiber.Ctx) bool {
+	mfa, _ := c.Context().UserValue(twoFactorKey{}).(bool)
+	return mfa
+}
+
+// verifySecondFactor checks a code from the authenticator app or, failing
+// that, a recovery code, and uses it up. recovery reports which one it was.
+func verifySecondFactor(ctx context.Context, userStore db.UserStore, user *types.User, code string) (recovery, ok bool, err error) {
+	ok, err = useTOTPCode(ctx, userStore, user, code)
+	if err != nil || ok {
+		return false, ok, err
+	}
+
+	ok, err = userStore.UseRecoveryCode(ctx, user.ID, twofactor.HashRecoveryCode(code))
+	return ok, ok, err
+}
+
+func useTOTPCode(ctx context.Context, userStore db.UserStore, user *types.User, code string) (bool, error) {
+	step, ok := twofactor.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return userStore.UseTOTPStep(ctx, user.ID, step)
+}
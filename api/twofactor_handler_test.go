@@ -0,0 +1,186 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/twofactor"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTwoFactorEnrollmentAndLogin(t *testing.T) {
+	tdb := setup(t)
+	defer tdb.teardown(t)
+
+	var (
+		app              = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		adminUser        = fixtures.AddUser(tdb.store, "jack", "bauer", true)
+		guard            = newTestGuard(tdb.store, testConfig.Auth, &lockoutRecorder{})
+		authHandler      = NewAuthHandler(tdb.store.User, guard, testConfig.Auth)
+		twoFactorHandler = NewTwoFactorHandler(tdb.store, guard, testConfig.Auth)
+		apiv1            = app.Group("/api/v1", JWTAuthentication(tdb.store.User, testConfig.Auth))
+		admin            = apiv1.Group("/admin", AdminAuth, RequireTwoFactor(tdb.store.Settings), AuditAdmin(tdb.store.Audit))
+	)
+	app.Post("/api/auth", authHandler.HandleAuthenticate)
+	app.Post("/api/auth/2fa", authHandler.HandleVerifyTwoFactor)
+	apiv1.Post("/user/2fa/enroll", twoFactorHandler.HandleEnroll)
+	apiv1.Post("/user/2fa/confirm", twoFactorHandler.HandleConfirm)
+	admin.Put("/security", twoFactorHandler.HandleUpdatePolicy)
+	admin.Get("/security", twoFactorHandler.HandleGetPolicy)
+
+	send := func(method, path, token string, body any) *http.Response {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Add("Content-Type", "application/json")
+		if len(token) > 0 {
+			req.Header.Add("Authorization", token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	challenge := func() string {
+		resp := send(http.MethodPost, "/api/auth", "", AuthParams{Email: "jack_bauer@ctu.com", Password: "jack_bauer"})
+		var challenge ChallengeResponse
+		if err := json.NewDecoder(resp.Body).Decode(&challenge); err != nil {
+			t.Fatal(err)
+		}
+		if !challenge.TwoFactorRequired || len(challenge.ChallengeToken) == 0 {
+			t.Fatalf("expected a two-factor challenge, got %+v", challenge)
+		}
+		return challenge.ChallengeToken
+	}
+
+	resp := postJSON(t, app, "/api/v1/user/2fa/enroll", adminUser, nil)
+	var enrollment EnrollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&enrollment); err != nil {
+		t.Fatal(err)
+	}
+	if len(enrollment.Secret) == 0 || len(enrollment.ProvisioningURI) == 0 {
+		t.Fatalf("expected a secret and provisioning URI, got %+v", enrollment)
+	}
+
+	step := twofactor.Step(time.Now())
+	code, _ := twofactor.Code(enrollment.Secret, step)
+	resp = postJSON(t, app, "/api/v1/user/2fa/confirm", adminUser, CodeParams{Code: code})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected enrollment to be confirmed, got %d", resp.StatusCode)
+	}
+	var confirmed RecoveryCodesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&confirmed); err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmed.RecoveryCodes) == 0 || len(confirmed.Token) == 0 {
+		t.Fatalf("expected recovery codes and a token, got %+v", confirmed)
+	}
+
+	// a challenge token is no auth token
+	token := challenge()
+	if resp := send(http.MethodGet, "/api/v1/admin/security", token, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected the challenge token to be refused, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/auth/2fa", "", TwoFactorParams{ChallengeToken: token, Code: code}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the used code to be refused, got %d", resp.StatusCode)
+	}
+	next, _ := twofactor.Code(enrollment.Secret, step+1)
+	if resp := send(http.MethodPost, "/api/auth/2fa", "", TwoFactorParams{ChallengeToken: token, Code: next}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected to log in with a fresh code, got %d", resp.StatusCode)
+	}
+
+	recovery := TwoFactorParams{ChallengeToken: challenge(), Code: confirmed.RecoveryCodes[0]}
+	if resp := send(http.MethodPost, "/api/auth/2fa", "", recovery); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected to log in with a recovery code, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/auth/2fa", "", recovery); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the recovery code to work only once, got %d", resp.StatusCode)
+	}
+
+	// tokens issued before enrollment no longer open admin endpoints once
+	// the policy requires two-factor authentication
+	password := CreateTokenFromUser(adminUser, testConfig.Auth)
+	require := types.UpdateSecurityPolicyParams{RequireAdminTwoFactor: new(bool)}
+	*require.RequireAdminTwoFactor = true
+	if resp := send(http.MethodPut, "/api/v1/admin/security", password, require); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the policy to need a two-factor login, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodPut, "/api/v1/admin/security", confirmed.Token, require); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the policy to be updated, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodGet, "/api/v1/admin/security", password, nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a password only login to be refused, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodGet, "/api/v1/admin/security", confirmed.Token, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected a two-factor login to be let in, got %d", resp.StatusCode)
+	}
+}
+
+func TestWrongTwoFactorCodesLockTheAccount(t *testing.T) {
+	tdb := setup(t)
+	defer tdb.teardown(t)
+
+	var (
+		app      = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		user     = fixtures.AddUser(tdb.store, "james", "bond", false)
+		auth     = testConfig.Auth
+		notifier = &lockoutRecorder{}
+	)
+	auth.MaxFailures = 3
+	authHandler := NewAuthHandler(tdb.store.User, newTestGuard(tdb.store, auth, notifier), auth)
+	app.Post("/auth", authHandler.HandleAuthenticate)
+	app.Post("/auth/2fa", authHandler.HandleVerifyTwoFactor)
+
+	secret, _ := twofactor.NewSecret()
+	if err := tdb.store.User.SetTwoFactor(context.Background(), user.ID, secret, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	// a code from minutes ago is always refused
+	wrong, _ := twofactor.Code(secret, twofactor.Step(time.Now())-10)
+
+	login := func() (int, ChallengeResponse) {
+		b, _ := json.Marshal(AuthParams{Email: "james_bond@ctu.com", Password: "james_bond"})
+		req := httptest.NewRequest(http.MethodPost, "/auth", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var challenge ChallengeResponse
+		json.NewDecoder(resp.Body).Decode(&challenge)
+		return resp.StatusCode, challenge
+	}
+
+	// logging in with the password again between guesses does not reset
+	// the count of wrong codes
+	for range auth.MaxFailures {
+		status, challenge := login()
+		if status != http.StatusOK || !challenge.TwoFactorRequired {
+			t.Fatalf("expected a two-factor challenge, got %d", status)
+		}
+		b, _ := json.Marshal(TwoFactorParams{ChallengeToken: challenge.ChallengeToken, Code: wrong})
+		req := httptest.NewRequest(http.MethodPost, "/auth/2fa", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected the wrong code to be refused, got %d", resp.StatusCode)
+		}
+	}
+
+	if status, _ := login(); status != http.StatusLocked {
+		t.Fatalf("expected the account to be locked, got %d", status)
+	}
+	if len(notifier.tokens) != 1 {
+		t.Fatalf("expected one unlock email, got %d", len(notifier.tokens))
+	}
+}
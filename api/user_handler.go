@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/aboronilov/go-hotel-reservation/db"
 	"github.com/aboronilov/go-hotel-reservation/types"
@@ -13,12 +14,14 @@ import (
 )
 
 type UserHandler struct {
-	userStore db.UserStore
+	userStore  db.UserStore
+	auditStore db.AuditStore
 }
 
-func NewUserHandler(userStore db.UserStore) *UserHandler {
+func NewUserHandler(userStore db.UserStore, auditStore db.AuditStore) *UserHandler {
 	return &UserHandler{
-		userStore: userStore,
+		userStore:  userStore,
+		auditStore: auditStore,
 	}
 }
 
@@ -62,8 +65,12 @@ func (h *UserHandler) HandleCreateUser(c *fiber.Ctx) error {
 
 	createdUser, err := h.userStore.CreateUser(c.Context(), user)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return NewError(http.StatusConflict, "Email is already registered")
+		}
 		return err
 	}
+	recordAudit(c, h.auditStore, types.AuditUserCreate, "user", createdUser.ID.Hex(), nil, createdUser)
 
 	return c.JSON(createdUser)
 }
@@ -84,6 +91,14 @@ func (h *UserHandler) HandleUpdateUser(c *fiber.Ctx) error {
 		return ErrorBadRequest()
 	}
 
+	before, err := h.userStore.GetUserByID(c.Context(), userId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrorNotFound()
+		}
+		return err
+	}
+
 	filter := bson.M{"_id": id}
 
 	err = h.userStore.UpdateUserByID(c.Context(), filter, params)
@@ -91,14 +106,28 @@ func (h *UserHandler) HandleUpdateUser(c *fiber.Ctx) error {
 		return ErrorBadRequest()
 	}
 
+	after, err := h.userStore.GetUserByID(c.Context(), userId)
+	if err != nil {
+		return err
+	}
+	recordAudit(c, h.auditStore, types.AuditUserUpdate, "user", userId, before, after)
+
 	return c.JSON(map[string]string{"msg": fmt.Sprintf("user %s updated", userId)})
 }
 
 func (h *UserHandler) HandleDeleteUser(c *fiber.Ctx) error {
 	userId := c.Params("id")
+	before, err := h.userStore.GetUserByID(c.Context(), userId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrorNotFound()
+		}
+		return err
+	}
 	if err := h.userStore.DeleteUserByID(c.Context(), userId); err != nil {
 		return err
 	}
+	recordAudit(c, h.auditStore, types.AuditUserDelete, "user", userId, before, nil)
 
 	return c.JSON(map[string]string{"msg": fmt.Sprintf("user %s deleted", userId)})
 }
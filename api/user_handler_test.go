@@ -15,7 +15,7 @@ func TestCreateUser(t *testing.T) {
 	defer tdb.teardown(t)
 
 	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
-	userHandler := NewUserHandler(tdb.store.User)
+	userHandler := NewUserHandler(tdb.store.User, tdb.store.Audit)
 	app.Post("/", userHandler.HandleCreateUser)
 
 	params := types.CreateUserParams{
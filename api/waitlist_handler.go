@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type WaitlistHandler struct {
+	store    *db.Store
+	waitlist *waitlist.Manager
+}
+
+func NewWaitlistHandler(store *db.Store, waitlist *waitlist.Manager) *WaitlistHandler {
+	return &WaitlistHandler{
+		store:    store,
+		waitlist: waitlist,
+	}
+}
+
+func (h *WaitlistHandler) HandleCreateEntry(c *fiber.Ctx) error {
+	var params types.CreateWaitlistEntryParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	user, err := getAuthUser(c)
+	if err != nil {
+		return ErrorUnauthorized()
+	}
+
+	entry := types.NewWaitlistEntryFromParams(user.ID, params)
+	if !entry.RoomID.IsZero() {
+		room, err := h.store.Room.GetRoomByID(c.Context(), entry.RoomID.Hex())
+		if err != nil {
+			return ErrorNotFound()
+		}
+		entry.HotelID = room.HotelID
+		entry.RoomType = room.Size
+	}
+
+	inserted, err := h.store.Waitlist.CreateEntry(c.Context(), entry)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(inserted)
+}
+
+// only owner
+func (h *WaitlistHandler) HandleListEntries(c *fiber.Ctx) error {
+	user, err := getAuthUser(c)
+	if err != nil {
+		return ErrorUnauthorized()
+	}
+
+	entries, err := h.store.Waitlist.GetEntries(c.Context(), bson.M{"userID": user.ID})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(entries)
+}
+
+// only owner
+func (h *WaitlistHandler) HandleWithdrawEntry(c *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return ErrorInvalidID()
+	}
+
+	entry, err := h.store.Waitlist.GetEntryByID(c.Context(), oid)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrorNotFound()
+		}
+		return err
+	}
+
+	user, err := getAuthUser(c)
+	if err != nil || entry.UserID != user.ID {
+		return ErrorUnauthorized()
+	}
+	if !entry.IsActive() {
+		return NewError(fiber.StatusBadRequest, "waitlist entry is no longer active")
+	}
+
+	if err := h.waitlist.Withdraw(c.Context(), entry); err != nil {
+		return err
+	}
+
+	return c.JSON(map[string]string{"message": "Waitlist entry withdrawn"})
+}
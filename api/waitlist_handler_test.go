@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCancellationOffersHoldToWaitlistedUser(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		owner           = fixtures.AddUser(db.store, "john", "smith", false)
+		waiting         = fixtures.AddUser(db.store, "jack", "bauer", false)
+		other           = fixtures.AddUser(db.store, "tony", "almeida", false)
+		hotel           = fixtures.AddHotel(db.store, "ibis", "paris")
+		from            = time.Now().AddDate(0, 0, 1)
+		till            = time.Now().AddDate(0, 0, 6)
+		booking         = fixtures.AddBooking(db.store, owner.ID, hotel.Rooms[0], from, till)
+		manager         = waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour)
+		app             = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		route           = app.Group("/", JWTAuthentication(db.store.User, testConfig.Auth))
+		waitlistHandler = NewWaitlistHandler(db.store, manager)
+		bookingHandler  = NewBookingHandler(db.store, manager)
+		roomHandler     = NewRoomHandler(db.store, manager)
+	)
+	route.Post("/waitlist", waitlistHandler.HandleCreateEntry)
+	route.Get("/waitlist", waitlistHandler.HandleListEntries)
+	route.Get("/booking/:id/cancel", bookingHandler.HandleCancelBooking)
+	route.Post("/room/:id/book", roomHandler.HandleBookRoom)
+
+	params := types.CreateWaitlistEntryParams{
+		RoomID:     hotel.Rooms[0].Hex(),
+		FromDate:   from,
+		TillDate:   till,
+		NumPersons: 2,
+	}
+	b, _ := json.Marshal(params)
+	req := httptest.NewRequest(http.MethodPost, "/waitlist", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(waiting, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/booking/%s/cancel", booking.ID.Hex()), nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(owner, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+
+	// canceling again must not release the room a second time
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/booking/%s/cancel", booking.ID.Hex()), nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(owner, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a repeat cancellation to be refused, got %d", resp.StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/waitlist", nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(waiting, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []*types.WaitlistEntry
+	if err = json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Status != types.WaitlistStatusOffered {
+		t.Fatalf("expected one offered entry, got %+v", entries)
+	}
+	if entries[0].HoldRoomID != hotel.Rooms[0] {
+		t.Fatalf("expected hold on room %s, got %s", hotel.Rooms[0], entries[0].HoldRoomID)
+	}
+
+	bookParams := BookRoomParams{
+		FromDate:   from,
+		TillDate:   till,
+		NumPersons: 2,
+	}
+	b, _ = json.Marshal(bookParams)
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/room/%s/book", hotel.Rooms[0].Hex()), bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(other, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected held room to be unavailable to other users, got %d", resp.StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/room/%s/book", hotel.Rooms[0].Hex()), bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", CreateTokenFromUser(waiting, testConfig.Auth))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected hold owner to book the room, got %d", resp.StatusCode)
+	}
+}
+
+func TestReleaseOffersRoomTypeOnlyToEntriesWithoutRoom(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user    = fixtures.AddUser(db.store, "jack", "bauer", false)
+		hotel   = fixtures.AddHotel(db.store, "ibis", "paris")
+		other   = fixtures.AddRoom(db.store, "small", false, 100, hotel.ID)
+		from    = time.Now().AddDate(0, 0, 1)
+		till    = time.Now().AddDate(0, 0, 6)
+		manager = waitlist.NewManager(db.store, waitlist.LogNotifier{}, time.Hour)
+	)
+	// the guest asked for the other small room, naming its type as well
+	entry, err := db.store.Waitlist.CreateEntry(context.TODO(), &types.WaitlistEntry{
+		UserID:     user.ID,
+		RoomID:     other.ID,
+		HotelID:    hotel.ID,
+		RoomType:   "small",
+		FromDate:   from,
+		TillDate:   till,
+		NumPersons: 1,
+		Status:     types.WaitlistStatusWaiting,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Release(context.TODO(), hotel.Rooms[0]); err != nil {
+		t.Fatal(err)
+	}
+	entry, err = db.store.Waitlist.GetEntryByID(context.TODO(), entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Status != types.WaitlistStatusWaiting {
+		t.Fatalf("expected the entry for another room to keep waiting, got %+v", entry)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/random"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/webhook"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookHandler struct {
+	store      *db.Store
+	dispatcher *webhook.Dispatcher
+}
+
+// WebhookSubscriptionResponse is the created subscription along with its
+// secret, which no other response carries.
+type WebhookSubscriptionResponse struct {
+	*types.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+func NewWebhookHandler(store *db.Store, dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		store:      store,
+		dispatcher: dispatcher,
+	}
+}
+
+// admin auth, the response carries the secret deliveries are signed with,
+// which is not shown again
+func (h *WebhookHandler) HandleCreateSubscription(c *fiber.Ctx) error {
+	var params types.CreateWebhookSubscriptionParams
+	if err := c.BodyParser(&params); err != nil {
+		return ErrorBadRequest()
+	}
+	if errors := params.Validate(); len(errors) != 0 {
+		return c.JSON(errors)
+	}
+
+	secret, err := random.Token()
+	if err != nil {
+		return err
+	}
+
+	inserted, err := h.store.Webhook.CreateSubscription(c.Context(), types.NewWebhookSubscriptionFromParams(params, secret))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(WebhookSubscriptionResponse{
+		WebhookSubscription: inserted,
+		Secret:              inserted.Secret,
+	})
+}
+
+// admin auth
+func (h *WebhookHandler) HandleListSubscriptions(c *fiber.Ctx) error {
+	subs, err := h.store.Webhook.GetSubscriptions(c.Context(), bson.M{})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(subs)
+}
+
+// admin auth
+func (h *WebhookHandler) HandleDeleteSubscription(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.store.Webhook.DeleteSubscriptionByID(c.Context(), id); err != nil {
+		return ErrorInvalidID()
+	}
+
+	return c.JSON(map[string]string{"msg": fmt.Sprintf("webhook %s deleted", id)})
+}
+
+// admin auth, ?status=dead lists the dead-letter queue
+func (h *WebhookHandler) HandleListDeliveries(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if status := c.Query("status"); len(status) > 0 {
+		filter["status"] = status
+	}
+	if id := c.Query("webhookID"); len(id) > 0 {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return ErrorInvalidID()
+		}
+		filter["subscriptionID"] = oid
+	}
+
+	deliveries, err := h.store.Webhook.GetDeliveries(c.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(deliveries)
+}
+
+// admin auth
+func (h *WebhookHandler) HandleRedeliver(c *fiber.Ctx) error {
+	delivery, err := h.store.Webhook.GetDeliveryByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return ErrorNotFound()
+	}
+
+	delivery, err = h.dispatcher.Redeliver(c.Context(), delivery)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(delivery)
+}
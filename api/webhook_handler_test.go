@@ -0,0 +1,348 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/events"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/webhook"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	payloads []webhook.Payload
+	valid    []bool
+	secret   string
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var payload webhook.Payload
+	json.Unmarshal(body, &payload)
+	r.payloads = append(r.payloads, payload)
+	r.valid = append(r.valid, webhook.Verify(r.secret, req.Header.Get(webhook.HeaderSignature), req.Header.Get(webhook.HeaderTimestamp), body, time.Minute))
+	w.WriteHeader(r.status)
+}
+
+func TestWebhookDeliversSignedBookingEvents(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	receiver := &webhookReceiver{status: http.StatusOK}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	sub, err := db.store.Webhook.CreateSubscription(context.TODO(), types.NewWebhookSubscriptionFromParams(types.CreateWebhookSubscriptionParams{
+		URL:    srv.URL,
+		Events: []string{types.EventBookingCreated, types.EventBookingCanceled},
+	}, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver.secret = sub.Secret
+
+	var (
+		user    = fixtures.AddUser(db.store, "james", "foo", false)
+		hotel   = fixtures.AddHotel(db.store, "ibis", "paris")
+		booking = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], time.Now().AddDate(0, 0, 1), time.Now().AddDate(0, 0, 3))
+	)
+	if _, err := db.store.Booking.CancelBooking(context.TODO(), booking.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	broker := events.NewLocalBroker()
+	dispatcher := webhook.NewDispatcher(db.store)
+	broker.Subscribe("webhooks", dispatcher.HandleEvent, types.WebhookEvents...)
+	relay := events.NewRelay(db.store, broker)
+	if err := relay.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.DeliverDue(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	// user.created is not subscribed to
+	if len(receiver.payloads) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(receiver.payloads))
+	}
+	for i, want := range []string{types.EventBookingCreated, types.EventBookingCanceled} {
+		if receiver.payloads[i].Type != want || !receiver.valid[i] {
+			t.Fatalf("expected a signed %s delivery, got %+v", want, receiver.payloads[i])
+		}
+	}
+	var canceled types.Booking
+	if err := json.Unmarshal(receiver.payloads[1].Data, &canceled); err != nil {
+		t.Fatal(err)
+	}
+	if canceled.ID != booking.ID || !canceled.Canceled {
+		t.Fatalf("expected the canceled booking as payload, got %+v", canceled)
+	}
+
+	// events are fanned out only once
+	if err := relay.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.DeliverDue(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(receiver.payloads) != 2 {
+		t.Fatalf("expected no further deliveries, got %d", len(receiver.payloads))
+	}
+}
+
+func TestWebhookDeadLetterAndRedeliver(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	_, err := db.store.Webhook.CreateSubscription(context.TODO(), types.NewWebhookSubscriptionFromParams(types.CreateWebhookSubscriptionParams{
+		URL:    srv.URL,
+		Events: []string{types.EventUserCreated},
+	}, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures.AddUser(db.store, "james", "foo", false)
+
+	broker := events.NewLocalBroker()
+	dispatcher := webhook.NewDispatcher(db.store)
+	broker.Subscribe("webhooks", dispatcher.HandleEvent, types.WebhookEvents...)
+	if err := events.NewRelay(db.store, broker).Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.DeliverDue(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := db.store.Webhook.GetDeliveries(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(deliveries))
+	}
+	delivery := deliveries[0]
+	if delivery.Status != types.DeliveryStatusPending || delivery.Attempts != 1 || !delivery.NextAttemptAt.After(time.Now()) || delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("expected the failed delivery to be retried later, got %+v", delivery)
+	}
+
+	for delivery.Status == types.DeliveryStatusPending {
+		if delivery, err = dispatcher.Deliver(context.TODO(), delivery); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if delivery.Status != types.DeliveryStatusDead || delivery.Attempts != webhook.MaxAttempts {
+		t.Fatalf("expected the delivery to be dead lettered after %d attempts, got %+v", webhook.MaxAttempts, delivery)
+	}
+
+	receiver.status = http.StatusNoContent
+	delivery, err = dispatcher.Redeliver(context.TODO(), delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != types.DeliveryStatusDelivered || delivery.Attempts != 1 {
+		t.Fatalf("expected the redelivery to succeed, got %+v", delivery)
+	}
+}
+
+func TestEventRelayRetriesFailedSubscribers(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		broker = events.NewLocalBroker()
+		relay  = events.NewRelay(db.store, broker)
+		seen   []string
+		fail   = true
+	)
+	broker.Subscribe("flaky", func(ctx context.Context, event *types.Event) error {
+		seen = append(seen, event.Type)
+		if fail {
+			return errors.New("unavailable")
+		}
+		return nil
+	}, types.EventUserCreated)
+	user := fixtures.AddUser(db.store, "james", "foo", false)
+
+	if err := relay.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := db.store.Event.GetPendingEvents(context.TODO(), time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].SubjectID != user.ID || pending[0].Attempts != 1 || len(pending[0].LastError) == 0 {
+		t.Fatalf("expected the user.created event to stay pending, got %+v", pending)
+	}
+
+	// not due yet
+	if err := relay.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 {
+		t.Fatalf("expected the event to wait for its backoff, got %d attempts", len(seen))
+	}
+
+	fail = false
+	if err := db.store.Event.UpdateEvent(context.TODO(), pending[0].ID, bson.M{"nextAttemptAt": time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := relay.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expected the event to be published again, got %d attempts", len(seen))
+	}
+	pending, err = db.store.Event.GetPendingEvents(context.TODO(), time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected no pending events, got %+v", pending)
+	}
+}
+
+func TestEventRelayParksEventsAfterMaxAttempts(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		broker = events.NewLocalBroker()
+		relay  = events.NewRelay(db.store, broker)
+	)
+	broker.Subscribe("broken", func(ctx context.Context, event *types.Event) error {
+		return errors.New("unavailable")
+	}, types.EventUserCreated)
+	fixtures.AddUser(db.store, "james", "foo", false)
+
+	pending, err := db.store.Event.GetPendingEvents(context.TODO(), time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected one pending event, got %+v", pending)
+	}
+	if err := db.store.Event.UpdateEvent(context.TODO(), pending[0].ID, bson.M{"attempts": events.MaxAttempts - 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := relay.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := db.store.Event.GetEventByID(context.TODO(), pending[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !event.Dead || event.Dispatched || event.Attempts != events.MaxAttempts || len(event.LastError) == 0 {
+		t.Fatalf("expected the event to be dead after %d attempts, got %+v", events.MaxAttempts, event)
+	}
+	pending, err = db.store.Event.GetPendingEvents(context.TODO(), time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected the dead event not to be retried, got %+v", pending)
+	}
+}
+
+func TestBookingEventsOnlyForGuestVisibleChanges(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		user    = fixtures.AddUser(db.store, "james", "foo", false)
+		hotel   = fixtures.AddHotel(db.store, "ibis", "paris")
+		booking = fixtures.AddBooking(db.store, user.ID, hotel.Rooms[0], time.Now().AddDate(0, 0, 1), time.Now().AddDate(0, 0, 3))
+	)
+	if err := db.store.Booking.UpdateBooking(context.TODO(), booking.ID, bson.M{"walk": types.Walk{PartnerHotel: "Novotel"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.store.Booking.UpdateBooking(context.TODO(), booking.ID, bson.M{"numPersons": booking.NumPersons + 1}); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []bool{true, false} {
+		canceled, err := db.store.Booking.CancelBooking(context.TODO(), booking.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canceled != expected {
+			t.Fatalf("expected cancellation %d to report %t, got %t", i+1, expected, canceled)
+		}
+	}
+
+	pending, err := db.store.Event.GetPendingEvents(context.TODO(), time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var eventTypes []string
+	for _, event := range pending {
+		if event.SubjectID == booking.ID {
+			eventTypes = append(eventTypes, event.Type)
+		}
+	}
+	want := []string{types.EventBookingCreated, types.EventBookingModified, types.EventBookingCanceled}
+	if !slices.Equal(eventTypes, want) {
+		t.Fatalf("expected events %v, got %v", want, eventTypes)
+	}
+}
+
+func TestWebhookSecretIsOnlyShownOnCreate(t *testing.T) {
+	db := setup(t)
+	defer db.teardown(t)
+
+	var (
+		adminUser = fixtures.AddUser(db.store, "jack", "bauer", true)
+		app       = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		admin     = app.Group("/admin", JWTAuthentication(db.store.User, testConfig.Auth), AdminAuth)
+		handler   = NewWebhookHandler(db.store, webhook.NewDispatcher(db.store))
+	)
+	admin.Post("/webhooks", handler.HandleCreateSubscription)
+	admin.Get("/webhooks", handler.HandleListSubscriptions)
+
+	resp := postJSON(t, app, "/admin/webhooks", adminUser, types.CreateWebhookSubscriptionParams{
+		URL:    "https://example.com/hook",
+		Events: []string{types.EventBookingCreated},
+	})
+	var created map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if secret, _ := created["secret"].(string); len(secret) == 0 || created["url"] != "https://example.com/hook" {
+		t.Fatalf("expected the subscription with its secret, got %+v", created)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+	req.Header.Add("Authorization", CreateTokenFromUser(adminUser, testConfig.Auth))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 {
+		t.Fatalf("expected one subscription, got %+v", listed)
+	}
+	if _, ok := listed[0]["secret"]; ok {
+		t.Fatalf("expected the secret not to be listed, got %+v", listed[0])
+	}
+}
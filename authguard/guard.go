@@ -0,0 +1,220 @@
+// Package authguard protects logins against password guessing. Attempts are
+// budgeted per IP and per email, failures in a row slow the answers down, and
+// too many of them lock the account until the lockout ends or the owner
+// unlocks it from the link emailed to them.
+package authguard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/config"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/logging"
+	"github.com/aboronilov/go-hotel-reservation/random"
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+const (
+	// failureWindow is how long failures in a row are remembered without a
+	// successful login.
+	failureWindow = 24 * time.Hour
+	// answers are slowed down from the delayAfter-th failure in a row on,
+	// doubling from baseDelay up to maxDelay
+	delayAfter = 2
+	baseDelay  = 250 * time.Millisecond
+	maxDelay   = 8 * time.Second
+)
+
+// Counter counts attempts per key in fixed windows. db.AttemptStore shares
+// the counts between instances; MemoryCounter keeps them in the process.
+type Counter interface {
+	Incr(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	Count(ctx context.Context, key string) (int, time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// Notifier emails the owner of a locked account the link to unlock it.
+type Notifier interface {
+	NotifyLockout(ctx context.Context, user *types.User, unlockToken string) error
+}
+
+// LimitError is returned by Attempt once a budget is used up.
+type LimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return "too many login attempts"
+}
+
+type Guard struct {
+	store    *db.Store
+	counter  Counter
+	notifier Notifier
+	auth     config.Auth
+}
+
+func NewGuard(store *db.Store, counter Counter, notifier Notifier, auth config.Auth) *Guard {
+	return &Guard{
+		store:    store,
+		counter:  counter,
+		notifier: notifier,
+		auth:     auth,
+	}
+}
+
+// Attempt counts a login attempt from ip for email. It returns a *LimitError
+// when either budget is used up, and otherwise how long to wait before
+// answering.
+func (g *Guard) Attempt(ctx context.Context, ip, email string) (time.Duration, error) {
+	budgets := []struct {
+		key   string
+		limit int
+	}{
+		{"ip:" + ip, g.auth.IPLimit},
+		{"email:" + normalize(email), g.auth.EmailLimit},
+	}
+	for _, budget := range budgets {
+		count, resetAt, err := g.counter.Incr(ctx, budget.key, g.auth.LimitWindow)
+		if err != nil {
+			return 0, err
+		}
+		if count <= budget.limit {
+			continue
+		}
+		// only the first refused attempt of a window is worth a record
+		if count == budget.limit+1 {
+			slog.WarnContext(ctx, "login rate limited", "key", budget.key, "ip", ip)
+			details := map[string]string{"key": budget.key, "limit": strconv.Itoa(budget.limit)}
+			if err := g.audit(ctx, types.AuditLoginRateLimited, ip, nil, details); err != nil {
+				return 0, err
+			}
+		}
+		return 0, &LimitError{RetryAfter: time.Until(resetAt)}
+	}
+
+	failures, _, err := g.counter.Count(ctx, failureKey(email))
+	if err != nil {
+		return 0, err
+	}
+
+	return delay(failures), nil
+}
+
+// Failed records a failed login for email. user is nil when no account has
+// that email. When the failures in a row reach MaxFailures the account is
+// locked and its owner is sent an unlock link.
+func (g *Guard) Failed(ctx context.Context, ip, email string, user *types.User) error {
+	failures, _, err := g.counter.Incr(ctx, failureKey(email), failureWindow)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if user == nil || failures < g.auth.MaxFailures || user.IsLocked(now) {
+		return nil
+	}
+
+	token, err := random.Token()
+	if err != nil {
+		return err
+	}
+	until := now.Add(g.auth.LockoutDuration)
+	if err := g.store.User.LockUser(ctx, user.ID, until, hashToken(token)); err != nil {
+		return err
+	}
+	// the account gets MaxFailures tries again once the lockout ends
+	if err := g.counter.Reset(ctx, failureKey(email)); err != nil {
+		return err
+	}
+
+	slog.WarnContext(ctx, "account locked", "userID", user.ID.Hex(), "failures", failures, "ip", ip)
+	details := map[string]string{"failures": strconv.Itoa(failures), "lockedUntil": until.UTC().Format(time.RFC3339)}
+	if err := g.audit(ctx, types.AuditLoginLockout, ip, user, details); err != nil {
+		return err
+	}
+
+	user.LockedUntil = until
+	return g.notifier.NotifyLockout(ctx, user, token)
+}
+
+// Locked records a login attempt on a locked account.
+func (g *Guard) Locked(ctx context.Context, ip string, user *types.User) error {
+	return g.audit(ctx, types.AuditLoginWhileLocked, ip, user, nil)
+}
+
+// RecoveryCodeUsed records a login with a recovery code, which usually means
+// the authenticator was lost.
+func (g *Guard) RecoveryCodeUsed(ctx context.Context, ip string, user *types.User) error {
+	return g.audit(ctx, types.AuditRecoveryCodeUsed, ip, user, map[string]string{"remaining": strconv.Itoa(len(user.RecoveryCodes) - 1)})
+}
+
+// Succeeded forgets the failures in a row of email.
+func (g *Guard) Succeeded(ctx context.Context, email string) error {
+	return g.counter.Reset(ctx, failureKey(email))
+}
+
+// Unlock unlocks the account an unlock token was emailed for. It returns
+// mongo.ErrNoDocuments when the token is unknown or was used already.
+func (g *Guard) Unlock(ctx context.Context, ip, token string) (*types.User, error) {
+	user, err := g.store.User.UnlockUser(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if err := g.counter.Reset(ctx, failureKey(user.Email)); err != nil {
+		return nil, err
+	}
+	if err := g.audit(ctx, types.AuditLoginUnlock, ip, user, nil); err != nil {
+		return nil, err
+	}
+
+	user.LockedUntil = time.Time{}
+	return user, nil
+}
+
+func (g *Guard) audit(ctx context.Context, action, ip string, user *types.User, details map[string]string) error {
+	record := &types.AuditRecord{
+		Action:    action,
+		IP:        ip,
+		RequestID: logging.RequestID(ctx),
+		Details:   details,
+		CreatedAt: time.Now().UTC(),
+	}
+	if user != nil {
+		record.TargetType = "user"
+		record.TargetID = user.ID.Hex()
+	}
+	_, err := g.store.Audit.AddRecord(ctx, record)
+	return err
+}
+
+func delay(failures int) time.Duration {
+	if failures < delayAfter {
+		return 0
+	}
+	d := baseDelay << (failures - delayAfter)
+	if d <= 0 || d > maxDelay {
+		return maxDelay
+	}
+	return d
+}
+
+func failureKey(email string) string {
+	return "failures:" + normalize(email)
+}
+
+func normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// hashToken keeps unlock tokens out of the database, so reading it is not
+// enough to unlock accounts.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,129 @@
+package authguard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/config"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeUserStore struct {
+	db.UserStore
+	lockedUntil time.Time
+	unlockToken string
+}
+
+func (s *fakeUserStore) LockUser(ctx context.Context, id primitive.ObjectID, until time.Time, unlockToken string) error {
+	s.lockedUntil, s.unlockToken = until, unlockToken
+	return nil
+}
+
+type fakeAuditStore struct {
+	db.AuditStore
+	actions []string
+}
+
+func (s *fakeAuditStore) AddRecord(ctx context.Context, record *types.AuditRecord) (*types.AuditRecord, error) {
+	s.actions = append(s.actions, record.Action)
+	return record, nil
+}
+
+type fakeNotifier struct {
+	tokens []string
+}
+
+func (n *fakeNotifier) NotifyLockout(ctx context.Context, user *types.User, unlockToken string) error {
+	n.tokens = append(n.tokens, unlockToken)
+	return nil
+}
+
+func newTestGuard(auth config.Auth) (*Guard, *fakeUserStore, *fakeAuditStore, *fakeNotifier) {
+	var (
+		users    = &fakeUserStore{}
+		audit    = &fakeAuditStore{}
+		notifier = &fakeNotifier{}
+		store    = &db.Store{User: users, Audit: audit}
+	)
+	return NewGuard(store, NewMemoryCounter(), notifier, auth), users, audit, notifier
+}
+
+func TestAttemptBudgets(t *testing.T) {
+	auth := config.Default().Auth
+	auth.IPLimit, auth.EmailLimit = 3, 2
+	guard, _, audit, _ := newTestGuard(auth)
+	ctx := context.Background()
+
+	for i := range 2 {
+		if _, err := guard.Attempt(ctx, "10.0.0.1", "James@ctu.com"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	// the email budget is spent, whatever the case of the address
+	var limited *LimitError
+	if _, err := guard.Attempt(ctx, "10.0.0.2", "james@ctu.com "); !errors.As(err, &limited) || limited.RetryAfter <= 0 {
+		t.Fatalf("expected the email budget to be used up but got %v", err)
+	}
+	if _, err := guard.Attempt(ctx, "10.0.0.1", "bond@ctu.com"); err != nil {
+		t.Fatalf("expected the last attempt of the ip budget to pass but got %v", err)
+	}
+	for range 2 {
+		if _, err := guard.Attempt(ctx, "10.0.0.1", "bond@ctu.com"); !errors.As(err, &limited) {
+			t.Fatalf("expected the ip budget to be used up but got %v", err)
+		}
+	}
+	if len(audit.actions) != 2 {
+		t.Fatalf("expected each budget to be audited once but got %v", audit.actions)
+	}
+}
+
+func TestFailuresDelayAndLock(t *testing.T) {
+	auth := config.Default().Auth
+	auth.MaxFailures = 4
+	guard, users, audit, notifier := newTestGuard(auth)
+	var (
+		ctx  = context.Background()
+		user = &types.User{ID: primitive.NewObjectID(), Email: "james@ctu.com"}
+	)
+
+	var delays []time.Duration
+	for range auth.MaxFailures {
+		delay, err := guard.Attempt(ctx, "10.0.0.1", user.Email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		delays = append(delays, delay)
+		if err := guard.Failed(ctx, "10.0.0.1", user.Email, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []time.Duration{0, 0, baseDelay, 2 * baseDelay}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Fatalf("expected delays %v but got %v", expected, delays)
+		}
+	}
+	if !users.lockedUntil.After(time.Now()) || len(notifier.tokens) != 1 {
+		t.Fatalf("expected the account to be locked and its owner notified")
+	}
+	if users.unlockToken != hashToken(notifier.tokens[0]) {
+		t.Fatal("expected only the hash of the unlock token to be stored")
+	}
+	if len(audit.actions) != 1 || audit.actions[0] != types.AuditLoginLockout {
+		t.Fatalf("expected the lockout to be audited but got %v", audit.actions)
+	}
+	if delay, _ := guard.Attempt(ctx, "10.0.0.1", user.Email); delay != 0 {
+		t.Fatalf("expected the failures to start over after the lockout but got a delay of %s", delay)
+	}
+}
+
+func TestDelayIsCapped(t *testing.T) {
+	if d := delay(100); d != maxDelay {
+		t.Fatalf("expected %s but got %s", maxDelay, d)
+	}
+}
@@ -0,0 +1,77 @@
+package authguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const pruneInterval = time.Minute
+
+type window struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryCounter is a Counter for a single instance. Counts are lost on
+// restart and every instance has budgets of its own.
+type MemoryCounter struct {
+	mu       sync.Mutex
+	windows  map[string]*window
+	prunedAt time.Time
+}
+
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{
+		windows: map[string]*window{},
+	}
+}
+
+func (c *MemoryCounter) Incr(ctx context.Context, key string, length time.Duration) (int, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.prune(now)
+	w, ok := c.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &window{resetAt: now.Add(length)}
+		c.windows[key] = w
+	}
+	w.count++
+
+	return w.count, w.resetAt, nil
+}
+
+func (c *MemoryCounter) Count(ctx context.Context, key string) (int, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.windows[key]
+	if !ok || !time.Now().Before(w.resetAt) {
+		return 0, time.Time{}, nil
+	}
+
+	return w.count, w.resetAt, nil
+}
+
+func (c *MemoryCounter) Reset(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.windows, key)
+	return nil
+}
+
+// prune drops the windows that ended, at most once per pruneInterval.
+func (c *MemoryCounter) prune(now time.Time) {
+	if now.Sub(c.prunedAt) < pruneInterval {
+		return
+	}
+	c.prunedAt = now
+	for key, w := range c.windows {
+		if !now.Before(w.resetAt) {
+			delete(c.windows, key)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Request describes a stay a user wants to book in a specific room. BlockID
+// is set when the stay is booked against a room block, and BookingID when an
+// existing booking is moved, so it does not conflict with itself.
+type Request struct {
+	RoomID     primitive.ObjectID
+	UserID     primitive.ObjectID
+	BlockID    primitive.ObjectID
+	BookingID  primitive.ObjectID
+	FromDate   time.Time
+	TillDate   time.Time
+	NumPersons int
+}
+
+// Checker decides whether a room can be booked. Handlers and background jobs
+// share it so every path into the bookings collection applies the same rules.
+type Checker struct {
+	store *db.Store
+}
+
+func NewChecker(store *db.Store) *Checker {
+	return &Checker{
+		store: store,
+	}
+}
+
+// IsRoomAvailable reports whether the room is free for the requested dates.
+// A room is taken when it has an overlapping booking that was neither
+// canceled nor walked, when it is allocated to an unreleased room block other
+// than the one being booked against, or when a waitlisted guest other than
+// the requester holds it.
+func (c *Checker) IsRoomAvailable(ctx context.Context, req Request) (bool, error) {
+	filter := bson.M{
+		"_id":      bson.M{"$ne": req.BookingID},
+		"roomID":   req.RoomID,
+		"canceled": bson.M{"$ne": true},
+		"walk":     bson.M{"$exists": false},
+		"fromDate": bson.M{"$lte": req.TillDate},
+		"tillDate": bson.M{"$gte": req.FromDate},
+	}
+	bookings, err := c.store.Booking.GetBookings(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	if len(bookings) > 0 {
+		return false, nil
+	}
+
+	blocks, err := c.store.Block.GetBlocks(ctx, bson.M{
+		"_id":         bson.M{"$ne": req.BlockID},
+		"roomIDs":     req.RoomID,
+		"released":    false,
+		"releaseDate": bson.M{"$gt": time.Now()},
+		"fromDate":    bson.M{"$lte": req.TillDate},
+		"tillDate":    bson.M{"$gte": req.FromDate},
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(blocks) > 0 {
+		return false, nil
+	}
+
+	holds, err := c.store.Waitlist.GetEntries(ctx, bson.M{
+		"status":        types.WaitlistStatusOffered,
+		"holdRoomID":    req.RoomID,
+		"holdExpiresAt": bson.M{"$gt": time.Now()},
+		"userID":        bson.M{"$ne": req.UserID},
+		"fromDate":      bson.M{"$lte": req.TillDate},
+		"tillDate":      bson.M{"$gte": req.FromDate},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(holds) == 0, nil
+}
+
+// RestrictionError is returned when a stay breaks the hotel's stay
+// restrictions, such as a minimum length of stay or a closed arrival day.
+type RestrictionError struct {
+	Reason string
+}
+
+func (e *RestrictionError) Error() string {
+	return "stay restricted: " + e.Reason
+}
+
+// CheckRestrictions returns a *RestrictionError when the restrictions that
+// apply to the room forbid the stay.
+func (c *Checker) CheckRestrictions(ctx context.Context, room *types.Room, from, till time.Time) error {
+	filter := bson.M{
+		"hotelID":  room.HotelID,
+		"fromDate": bson.M{"$lte": types.Night(till)},
+		"tillDate": bson.M{"$gte": types.Night(from)},
+		"$or": bson.A{
+			bson.M{"roomID": room.ID},
+			bson.M{"roomID": bson.M{"$exists": false}, "roomType": room.Size},
+			bson.M{"roomID": bson.M{"$exists": false}, "roomType": bson.M{"$exists": false}},
+		},
+	}
+	restrictions, err := c.store.Restriction.GetRestrictions(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if reason := types.CheckStay(restrictions, from, till); len(reason) > 0 {
+		return &RestrictionError{Reason: reason}
+	}
+
+	return nil
+}
+
+// AvailableRooms returns the rooms that are free and open for sale for the
+// whole stay.
+func (c *Checker) AvailableRooms(ctx context.Context, rooms []*types.Room, from, till time.Time) ([]*types.Room, error) {
+	available := []*types.Room{}
+	for _, room := range rooms {
+		err := c.CheckRestrictions(ctx, room, from, till)
+		var restricted *RestrictionError
+		if errors.As(err, &restricted) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := c.IsRoomAvailable(ctx, Request{
+			RoomID:   room.ID,
+			FromDate: from,
+			TillDate: till,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			available = append(available, room)
+		}
+	}
+
+	return available, nil
+}
+
+// ErrUnavailable is returned by CheckBooking when the room is taken and the
+// hotel's overbooking allowance is used up.
+var ErrUnavailable = errors.New("room is not available")
+
+// CheckBooking applies every rule a new booking must pass: stay restrictions,
+// room availability and, when the room and every other room of its type is
+// taken, the overbooking allowance.
+// It reports whether the booking would be sold beyond physical inventory.
+func (c *Checker) CheckBooking(ctx context.Context, room *types.Room, req Request) (bool, error) {
+	if err := c.CheckRestrictions(ctx, room, req.FromDate, req.TillDate); err != nil {
+		return false, err
+	}
+
+	ok, err := c.IsRoomAvailable(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return false, nil
+	}
+
+	overbooked, err := c.CanOverbook(ctx, room, req)
+	if err != nil {
+		return false, err
+	}
+	if !overbooked {
+		return false, ErrUnavailable
+	}
+
+	return true, nil
+}
@@ -0,0 +1,188 @@
+package availability
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CanOverbook reports whether the stay can be sold beyond physical inventory:
+// every room of the room's type must be taken on every night of the stay, so
+// a guest is never put into a taken room while another one is free, and one
+// more booking must fit within the hotel's overbooking allowance.
+func (c *Checker) CanOverbook(ctx context.Context, room *types.Room, req Request) (bool, error) {
+	allowances, err := c.store.Overbooking.GetAllowances(ctx, bson.M{
+		"hotelID":  room.HotelID,
+		"roomType": room.Size,
+		"fromDate": bson.M{"$lte": types.Night(req.TillDate)},
+		"tillDate": bson.M{"$gte": types.Night(req.FromDate)},
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(allowances) == 0 {
+		return false, nil
+	}
+
+	nights, err := c.occupancy(ctx, room.HotelID, room.Size, req)
+	if err != nil {
+		return false, err
+	}
+	for night := types.Night(req.FromDate); night.Before(types.Night(req.TillDate)); night = night.AddDate(0, 0, 1) {
+		o := nights.byNight[night]
+		if o.taken < nights.capacity {
+			return false, nil
+		}
+		if o.sold+1-nights.capacity > types.LimitFor(allowances, night) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// OversoldNights lists, per room type, the nights in the range on which the
+// hotel sold more bookings than it has rooms.
+func (c *Checker) OversoldNights(ctx context.Context, hotelID primitive.ObjectID, from, till time.Time) ([]types.OversoldNight, error) {
+	rooms, err := c.store.Room.GetRooms(ctx, bson.M{"hotelID": hotelID})
+	if err != nil {
+		return nil, err
+	}
+	roomTypes := map[string]bool{}
+	for _, room := range rooms {
+		roomTypes[room.Size] = true
+	}
+
+	report := []types.OversoldNight{}
+	for roomType := range roomTypes {
+		nights, err := c.occupancy(ctx, hotelID, roomType, Request{FromDate: from, TillDate: till})
+		if err != nil {
+			return nil, err
+		}
+		for night, o := range nights.byNight {
+			if o.sold <= nights.capacity {
+				continue
+			}
+			report = append(report, types.OversoldNight{
+				Date:     night,
+				RoomType: roomType,
+				Capacity: nights.capacity,
+				Sold:     o.sold,
+				Oversold: o.sold - nights.capacity,
+			})
+		}
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Date.Equal(report[j].Date) {
+			return report[i].RoomType < report[j].RoomType
+		}
+		return report[i].Date.Before(report[j].Date)
+	})
+
+	return report, nil
+}
+
+type nightOccupancy struct {
+	// taken counts the rooms that are booked, blocked or held, sold the
+	// bookings plus the blocked or held rooms nobody booked yet.
+	taken int
+	sold  int
+}
+
+type occupancyByNight struct {
+	capacity int
+	byNight  map[time.Time]nightOccupancy
+}
+
+// occupancy counts, for each night of the request, the rooms of a type at a
+// hotel that are taken and how many of them are sold. Rooms are taken by the
+// same rules as in IsRoomAvailable, so the block being booked against and the
+// requester's own waitlist hold leave a room free.
+func (c *Checker) occupancy(ctx context.Context, hotelID primitive.ObjectID, roomType string, req Request) (occupancyByNight, error) {
+	rooms, err := c.store.Room.GetRooms(ctx, bson.M{"hotelID": hotelID, "size": roomType})
+	if err != nil {
+		return occupancyByNight{}, err
+	}
+	roomIDs := make([]primitive.ObjectID, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = room.ID
+	}
+
+	bookings, err := c.store.Booking.GetBookings(ctx, bson.M{
+		"roomID":   bson.M{"$in": roomIDs},
+		"canceled": bson.M{"$ne": true},
+		"walk":     bson.M{"$exists": false},
+		"fromDate": bson.M{"$lt": req.TillDate},
+		"tillDate": bson.M{"$gt": req.FromDate},
+	})
+	if err != nil {
+		return occupancyByNight{}, err
+	}
+	blocks, err := c.store.Block.GetBlocks(ctx, bson.M{
+		"_id":         bson.M{"$ne": req.BlockID},
+		"roomIDs":     bson.M{"$in": roomIDs},
+		"released":    false,
+		"releaseDate": bson.M{"$gt": time.Now()},
+		"fromDate":    bson.M{"$lt": req.TillDate},
+		"tillDate":    bson.M{"$gt": req.FromDate},
+	})
+	if err != nil {
+		return occupancyByNight{}, err
+	}
+	holds, err := c.store.Waitlist.GetEntries(ctx, bson.M{
+		"status":        types.WaitlistStatusOffered,
+		"holdRoomID":    bson.M{"$in": roomIDs},
+		"holdExpiresAt": bson.M{"$gt": time.Now()},
+		"userID":        bson.M{"$ne": req.UserID},
+		"fromDate":      bson.M{"$lt": req.TillDate},
+		"tillDate":      bson.M{"$gt": req.FromDate},
+	})
+	if err != nil {
+		return occupancyByNight{}, err
+	}
+
+	inType := map[primitive.ObjectID]bool{}
+	for _, id := range roomIDs {
+		inType[id] = true
+	}
+	covers := func(from, till, night time.Time) bool {
+		return !night.Before(types.Night(from)) && night.Before(types.Night(till))
+	}
+
+	nights := occupancyByNight{capacity: len(rooms), byNight: map[time.Time]nightOccupancy{}}
+	for night := types.Night(req.FromDate); night.Before(types.Night(req.TillDate)); night = night.AddDate(0, 0, 1) {
+		var o nightOccupancy
+		booked := map[primitive.ObjectID]bool{}
+		for _, booking := range bookings {
+			if booking.CoversNight(night) {
+				booked[booking.RoomID] = true
+				o.sold++
+			}
+		}
+		claimed := map[primitive.ObjectID]bool{}
+		for _, block := range blocks {
+			if !covers(block.FromDate, block.TillDate, night) {
+				continue
+			}
+			for _, id := range block.RoomIDs {
+				if inType[id] && !booked[id] {
+					claimed[id] = true
+				}
+			}
+		}
+		for _, hold := range holds {
+			if covers(hold.FromDate, hold.TillDate, night) && !booked[hold.HoldRoomID] {
+				claimed[hold.HoldRoomID] = true
+			}
+		}
+		o.sold += len(claimed)
+		o.taken = len(booked) + len(claimed)
+		nights.byNight[night] = o
+	}
+
+	return nights, nil
+}
@@ -0,0 +1,89 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+var bookingColumns = []string{
+	"id", "userID", "roomID", "fromDate", "tillDate", "numPersons",
+	"price", "canceled", "overbooked", "walked", "blockID", "itineraryID",
+}
+
+// BookingWriter streams bookings to w one at a time.
+type BookingWriter interface {
+	Write(*types.Booking) error
+	Flush() error
+}
+
+func NewBookingWriter(w io.Writer, format Format) BookingWriter {
+	if format == FormatJSONL {
+		return &jsonlBookingWriter{enc: json.NewEncoder(w)}
+	}
+	return &csvBookingWriter{w: csv.NewWriter(w)}
+}
+
+type jsonlBookingWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonlBookingWriter) Write(booking *types.Booking) error {
+	return w.enc.Encode(booking)
+}
+
+func (w *jsonlBookingWriter) Flush() error {
+	return nil
+}
+
+type csvBookingWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvBookingWriter) Write(booking *types.Booking) error {
+	if !w.headerWritten {
+		if err := w.w.Write(bookingColumns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	return w.w.Write([]string{
+		booking.ID.Hex(),
+		booking.UserID.Hex(),
+		booking.RoomID.Hex(),
+		booking.FromDate.Format(time.RFC3339),
+		booking.TillDate.Format(time.RFC3339),
+		strconv.Itoa(booking.NumPersons),
+		strconv.FormatFloat(booking.Price, 'f', 2, 64),
+		strconv.FormatBool(booking.Canceled),
+		strconv.FormatBool(booking.Overbooked),
+		strconv.FormatBool(booking.Walk != nil),
+		hexOrEmpty(booking.BlockID.IsZero(), booking.BlockID.Hex()),
+		hexOrEmpty(booking.ItineraryID.IsZero(), booking.ItineraryID.Hex()),
+	})
+}
+
+// Flush writes the header even when there were no bookings, so an empty
+// export is still a valid CSV file.
+func (w *csvBookingWriter) Flush() error {
+	if !w.headerWritten {
+		if err := w.w.Write(bookingColumns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func hexOrEmpty(zero bool, hex string) string {
+	if zero {
+		return ""
+	}
+	return hex
+}
@@ -0,0 +1,27 @@
+package bulk
+
+import "fmt"
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatCSV, FormatJSONL:
+		return Format(s), nil
+	case "":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, expected csv or jsonl", s)
+}
+
+func (f Format) ContentType() string {
+	if f == FormatJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
@@ -0,0 +1,218 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxLineSize = 1024 * 1024
+
+// RowError reports why a line of an import file was rejected. Line numbers
+// start at 1 and, for CSV, count the header.
+type RowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type HotelRow struct {
+	Line     int    `json:"-"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+}
+
+func (r *HotelRow) validate() []RowError {
+	errors := []RowError{}
+	if len(strings.TrimSpace(r.Name)) == 0 {
+		errors = append(errors, RowError{Line: r.Line, Field: "name", Message: "name is required"})
+	}
+	if len(strings.TrimSpace(r.Location)) == 0 {
+		errors = append(errors, RowError{Line: r.Line, Field: "location", Message: "location is required"})
+	}
+	return errors
+}
+
+// RoomRow references its hotel either by id or, for hotels imported in the
+// same onboarding, by name.
+type RoomRow struct {
+	Line      int     `json:"-"`
+	HotelID   string  `json:"hotelID"`
+	HotelName string  `json:"hotelName"`
+	Size      string  `json:"size"`
+	Seaside   bool    `json:"seaside"`
+	Price     float64 `json:"price"`
+}
+
+func (r *RoomRow) validate() []RowError {
+	errors := []RowError{}
+	if len(r.HotelID) == 0 && len(r.HotelName) == 0 {
+		errors = append(errors, RowError{Line: r.Line, Field: "hotelID", Message: "hotelID or hotelName is required"})
+	}
+	if len(r.HotelID) > 0 && !primitive.IsValidObjectID(r.HotelID) {
+		errors = append(errors, RowError{Line: r.Line, Field: "hotelID", Message: "invalid hotelID"})
+	}
+	if len(strings.TrimSpace(r.Size)) == 0 {
+		errors = append(errors, RowError{Line: r.Line, Field: "size", Message: "size is required"})
+	}
+	if r.Price <= 0 {
+		errors = append(errors, RowError{Line: r.Line, Field: "price", Message: "price should be positive"})
+	}
+	return errors
+}
+
+// DecodeHotels reads and validates hotel rows. Rows with errors are left out
+// of the result and reported instead.
+func DecodeHotels(r io.Reader, format Format) ([]*HotelRow, []RowError, error) {
+	var (
+		rows   []*HotelRow
+		errors []RowError
+	)
+	err := decode(r, format, []string{"name", "location"}, func(line int, record map[string]string, raw []byte) []RowError {
+		row := &HotelRow{Line: line}
+		var errs []RowError
+		if raw != nil {
+			errs = unmarshalLine(line, raw, row)
+		} else {
+			row.Name = record["name"]
+			row.Location = record["location"]
+		}
+		if len(errs) == 0 {
+			errs = row.validate()
+		}
+		if len(errs) == 0 {
+			rows = append(rows, row)
+		}
+		return errs
+	}, &errors)
+
+	return rows, errors, err
+}
+
+// DecodeRooms reads and validates room rows. Rows with errors are left out of
+// the result and reported instead.
+func DecodeRooms(r io.Reader, format Format) ([]*RoomRow, []RowError, error) {
+	var (
+		rows   []*RoomRow
+		errors []RowError
+	)
+	err := decode(r, format, []string{"size", "price"}, func(line int, record map[string]string, raw []byte) []RowError {
+		row := &RoomRow{Line: line}
+		var errs []RowError
+		if raw != nil {
+			errs = unmarshalLine(line, raw, row)
+		} else {
+			row.HotelID = record["hotelID"]
+			row.HotelName = record["hotelName"]
+			row.Size = record["size"]
+			errs = append(errs, parseBool(line, "seaside", record["seaside"], &row.Seaside)...)
+			errs = append(errs, parseFloat(line, "price", record["price"], &row.Price)...)
+		}
+		if len(errs) == 0 {
+			errs = row.validate()
+		}
+		if len(errs) == 0 {
+			rows = append(rows, row)
+		}
+		return errs
+	}, &errors)
+
+	return rows, errors, err
+}
+
+// decode calls fn for every data line of the input. CSV lines are passed as
+// a record keyed by header, JSON lines as raw bytes.
+func decode(r io.Reader, format Format, required []string, fn func(int, map[string]string, []byte) []RowError, errs *[]RowError) error {
+	if format == FormatJSONL {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		line := 0
+		for scanner.Scan() {
+			line++
+			raw := bytes.TrimSpace(scanner.Bytes())
+			if len(raw) == 0 {
+				continue
+			}
+			*errs = append(*errs, fn(line, nil, raw)...)
+		}
+		return scanner.Err()
+	}
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("empty CSV file")
+		}
+		return err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+
+	line := 1
+	for {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		line++
+		if err != nil {
+			*errs = append(*errs, RowError{Line: line, Message: err.Error()})
+			continue
+		}
+		record := map[string]string{}
+		for name, i := range columns {
+			if i < len(values) {
+				record[name] = strings.TrimSpace(values[i])
+			}
+		}
+		*errs = append(*errs, fn(line, record, nil)...)
+	}
+}
+
+func unmarshalLine(line int, raw []byte, v any) []RowError {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return []RowError{{Line: line, Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	return nil
+}
+
+func parseFloat(line int, field, value string, dst *float64) []RowError {
+	if len(value) == 0 {
+		return nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return []RowError{{Line: line, Field: field, Message: field + " should be a number"}}
+	}
+	*dst = n
+	return nil
+}
+
+func parseBool(line int, field, value string, dst *bool) []RowError {
+	if len(value) == 0 {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return []RowError{{Line: line, Field: field, Message: field + " should be true or false"}}
+	}
+	*dst = b
+	return nil
+}
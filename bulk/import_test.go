@@ -0,0 +1,52 @@
+package bulk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeHotelsCSVReportsRowErrors(t *testing.T) {
+	input := "name,location\nIbis,Paris\n,Berlin\nHilton,\n"
+
+	rows, errors, err := DecodeHotels(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Name != "Ibis" || rows[0].Location != "Paris" {
+		t.Fatalf("expected one valid row, got %+v", rows)
+	}
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 row errors, got %+v", errors)
+	}
+	if errors[0].Line != 3 || errors[0].Field != "name" {
+		t.Fatalf("expected missing name on line 3, got %+v", errors[0])
+	}
+	if errors[1].Line != 4 || errors[1].Field != "location" {
+		t.Fatalf("expected missing location on line 4, got %+v", errors[1])
+	}
+}
+
+func TestDecodeHotelsCSVRequiresColumns(t *testing.T) {
+	if _, _, err := DecodeHotels(strings.NewReader("name\nIbis\n"), FormatCSV); err == nil {
+		t.Fatal("expected missing location column to fail")
+	}
+}
+
+func TestDecodeRoomsJSONL(t *testing.T) {
+	input := `{"hotelName":"Ibis","size":"small","seaside":true,"price":100}
+
+{"hotelID":"nope","size":"large","price":120}
+{"size":
+`
+
+	rows, errors, err := DecodeRooms(strings.NewReader(input), FormatJSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].HotelName != "Ibis" || !rows[0].Seaside || rows[0].Line != 1 {
+		t.Fatalf("expected one valid row, got %+v", rows)
+	}
+	if len(errors) != 2 || errors[0].Line != 3 || errors[1].Line != 4 {
+		t.Fatalf("expected errors on lines 3 and 4, got %+v", errors)
+	}
+}
@@ -0,0 +1,45 @@
+package channel
+
+import (
+	"context"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+const ReservationStatusCancelled = "cancelled"
+
+// Reservation is a booking made on a channel, waiting to be pulled into our
+// inventory.
+type Reservation struct {
+	ID           string    `json:"id"`
+	RoomCode     string    `json:"roomCode"`
+	RatePlanCode string    `json:"ratePlanCode"`
+	Arrival      time.Time `json:"arrival"`
+	Departure    time.Time `json:"departure"`
+	NumPersons   int       `json:"numPersons"`
+	GuestName    string    `json:"guestName"`
+	Total        float64   `json:"total"`
+	Status       string    `json:"status"`
+}
+
+func (r Reservation) IsCancelled() bool {
+	return r.Status == ReservationStatusCancelled
+}
+
+// Ack tells the channel whether a pulled reservation was accepted.
+type Ack struct {
+	Success    bool   `json:"success"`
+	BookingRef string `json:"bookingRef,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ChannelAdapter is implemented once per online travel agency. It pushes
+// availability, rates and inventory (ARI) and pulls reservations, which are
+// acknowledged once they have been processed.
+type ChannelAdapter interface {
+	Name() string
+	PushARI(context.Context, []types.ARIUpdate) error
+	PullReservations(context.Context) ([]Reservation, error)
+	AckReservation(context.Context, string, Ack) error
+}
@@ -0,0 +1,91 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+const requestTimeout = 30 * time.Second
+
+// HTTPAdapter talks to a channel exposing the JSON protocol implemented by
+// mockchannel.Server: POST /ari, GET /reservations and
+// POST /reservations/:id/ack, authenticated with an X-Api-Key header.
+type HTTPAdapter struct {
+	name    string
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewHTTPAdapter(name, baseURL, apiKey string) *HTTPAdapter {
+	return &HTTPAdapter{
+		name:    name,
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (a *HTTPAdapter) Name() string {
+	return a.name
+}
+
+func (a *HTTPAdapter) PushARI(ctx context.Context, updates []types.ARIUpdate) error {
+	return a.do(ctx, http.MethodPost, "/ari", map[string]any{"updates": updates}, nil)
+}
+
+func (a *HTTPAdapter) PullReservations(ctx context.Context) ([]Reservation, error) {
+	var reservations []Reservation
+	if err := a.do(ctx, http.MethodGet, "/reservations", nil, &reservations); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (a *HTTPAdapter) AckReservation(ctx context.Context, id string, ack Ack) error {
+	return a.do(ctx, http.MethodPost, "/reservations/"+url.PathEscape(id)+"/ack", ack, nil)
+}
+
+func (a *HTTPAdapter) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Api-Key", a.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s %s: status %d: %s", a.name, method, path, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,67 @@
+package channel_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/channel"
+	"github.com/aboronilov/go-hotel-reservation/channel/mockchannel"
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+func TestHTTPAdapterAgainstMockChannel(t *testing.T) {
+	mock := mockchannel.NewServer("secret")
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	adapter := channel.NewHTTPAdapter("mock", srv.URL, "secret")
+	updates := []types.ARIUpdate{{
+		RoomCode:     "DBL",
+		RatePlanCode: types.DefaultRatePlanCode,
+		Date:         time.Date(2030, time.January, 5, 0, 0, 0, 0, time.UTC),
+		Available:    1,
+		Rate:         120,
+	}}
+
+	mock.FailARIPushes(1)
+	if err := adapter.PushARI(context.TODO(), updates); err == nil {
+		t.Fatal("expected the first push to fail")
+	}
+	if err := adapter.PushARI(context.TODO(), updates); err != nil {
+		t.Fatal(err)
+	}
+	if ari := mock.ARI(); len(ari) != 1 || ari[0].RoomCode != "DBL" || ari[0].Rate != 120 {
+		t.Fatalf("unexpected ARI received by the channel %+v", ari)
+	}
+
+	mock.AddReservation(channel.Reservation{ID: "r-1", RoomCode: "DBL", NumPersons: 2})
+	reservations, err := adapter.PullReservations(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 1 || reservations[0].ID != "r-1" {
+		t.Fatalf("expected reservation r-1 to be pulled, got %+v", reservations)
+	}
+
+	if err := adapter.AckReservation(context.TODO(), "r-1", channel.Ack{Success: true, BookingRef: "b-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if ack, ok := mock.Ack("r-1"); !ok || !ack.Success || ack.BookingRef != "b-1" {
+		t.Fatalf("unexpected ack %+v", ack)
+	}
+	if reservations, _ := adapter.PullReservations(context.TODO()); len(reservations) != 0 {
+		t.Fatalf("expected acked reservations to be gone, got %+v", reservations)
+	}
+}
+
+func TestHTTPAdapterRejectsWrongAPIKey(t *testing.T) {
+	srv := httptest.NewServer(mockchannel.NewServer("secret"))
+	defer srv.Close()
+
+	adapter := channel.NewHTTPAdapter("mock", srv.URL, "wrong")
+	if _, err := adapter.PullReservations(context.TODO()); err == nil {
+		t.Fatal("expected an error with a wrong api key")
+	}
+}
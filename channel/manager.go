@@ -0,0 +1,327 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/availability"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/metrics"
+	"github.com/aboronilov/go-hotel-reservation/retry"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	ARIHorizon    = 90 * 24 * time.Hour
+	maxAttempts   = 8
+	baseBackoff   = 30 * time.Second
+	maxBackoff    = time.Hour
+	dispatchBatch = 50
+)
+
+// Manager keeps channels in sync with our inventory. ARI pushes are queued in
+// an outbox and delivered by DispatchOutbox, reservations are pulled by
+// PullReservations and booked under the same rules as direct bookings.
+type Manager struct {
+	store    *db.Store
+	checker  *availability.Checker
+	waitlist *waitlist.Manager
+	adapters map[string]ChannelAdapter
+}
+
+func NewManager(store *db.Store, waitlist *waitlist.Manager, adapters ...ChannelAdapter) *Manager {
+	m := &Manager{
+		store:    store,
+		checker:  availability.NewChecker(store),
+		waitlist: waitlist,
+		adapters: map[string]ChannelAdapter{},
+	}
+	for _, adapter := range adapters {
+		m.adapters[adapter.Name()] = adapter
+	}
+	return m
+}
+
+// QueueARI queues, per channel, the nightly ARI of the mapped rooms matching
+// filter for the nights in [from, till).
+func (m *Manager) QueueARI(ctx context.Context, filter bson.M, from, till time.Time) error {
+	mappings, err := m.store.Channel.GetMappings(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string][]types.ARIUpdate{}
+	for _, mapping := range mappings {
+		if _, ok := m.adapters[mapping.Channel]; !ok {
+			continue
+		}
+		room, err := m.store.Room.GetRoomByID(ctx, mapping.RoomID.Hex())
+		if err != nil {
+			return err
+		}
+		for night := types.Night(from); night.Before(types.Night(till)); night = night.AddDate(0, 0, 1) {
+			update, err := m.nightlyARI(ctx, mapping, room, night)
+			if err != nil {
+				return err
+			}
+			updates[mapping.Channel] = append(updates[mapping.Channel], update)
+		}
+	}
+
+	now := time.Now()
+	for name, channelUpdates := range updates {
+		_, err := m.store.Channel.EnqueueMessage(ctx, &types.ChannelMessage{
+			Channel:       name,
+			Updates:       channelUpdates,
+			Status:        types.OutboxStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RefreshARI queues the full ARI horizon of every mapped room. It is meant to
+// be run by the scheduler.
+func (m *Manager) RefreshARI(ctx context.Context) error {
+	now := time.Now()
+	return m.QueueARI(ctx, bson.M{}, now, now.Add(ARIHorizon))
+}
+
+// BookingEvents are the events that change what a room has left to sell.
+var BookingEvents = []string{types.EventBookingCreated, types.EventBookingCanceled, types.EventBookingModified}
+
+// HandleEvent queues the ARI of the booked room for the nights of a booking
+// that was created, canceled or changed, so channels stop selling a room as
+// soon as it is taken rather than at the next refresh. A booking moved to
+// other dates is queued for its new nights only; the nights it left are
+// caught up by RefreshARI.
+func (m *Manager) HandleEvent(ctx context.Context, event *types.Event) error {
+	var booking types.Booking
+	if err := json.Unmarshal(event.Data, &booking); err != nil {
+		return err
+	}
+	if booking.TillDate.Before(time.Now()) {
+		return nil
+	}
+
+	return m.QueueARI(ctx, bson.M{"roomID": booking.RoomID}, booking.FromDate, booking.TillDate)
+}
+
+func (m *Manager) nightlyARI(ctx context.Context, mapping *types.ChannelMapping, room *types.Room, night time.Time) (types.ARIUpdate, error) {
+	update := types.ARIUpdate{
+		RoomCode:     mapping.RoomCode,
+		RatePlanCode: mapping.RatePlanCode,
+		Date:         night,
+		Rate:         room.Price,
+	}
+
+	next := night.AddDate(0, 0, 1)
+	err := m.checker.CheckRestrictions(ctx, room, night, next)
+	var restricted *availability.RestrictionError
+	if errors.As(err, &restricted) {
+		update.Closed = true
+		return update, nil
+	}
+	if err != nil {
+		return update, err
+	}
+
+	ok, err := m.checker.IsRoomAvailable(ctx, availability.Request{
+		RoomID:   room.ID,
+		FromDate: night,
+		TillDate: next,
+	})
+	if err != nil {
+		return update, err
+	}
+	if ok {
+		update.Available = 1
+	}
+
+	return update, nil
+}
+
+// DispatchOutbox pushes due ARI messages to their channels. Failures are
+// retried with exponential backoff until maxAttempts is reached.
+func (m *Manager) DispatchOutbox(ctx context.Context) error {
+	messages, err := m.store.Channel.GetDueMessages(ctx, time.Now(), dispatchBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range messages {
+		var pushErr error
+		if adapter, ok := m.adapters[msg.Channel]; ok {
+			pushErr = adapter.PushARI(ctx, msg.Updates)
+		} else {
+			pushErr = fmt.Errorf("no adapter registered for channel %s", msg.Channel)
+		}
+
+		update := bson.M{"attempts": msg.Attempts + 1}
+		switch {
+		case pushErr == nil:
+			update["status"] = types.OutboxStatusSent
+			update["lastError"] = ""
+		case msg.Attempts+1 >= maxAttempts:
+			update["status"] = types.OutboxStatusFailed
+			update["lastError"] = pushErr.Error()
+		default:
+			update["nextAttemptAt"] = time.Now().Add(retry.Backoff(msg.Attempts+1, baseBackoff, maxBackoff))
+			update["lastError"] = pushErr.Error()
+		}
+		if err := m.store.Channel.UpdateMessage(ctx, msg.ID, update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PullReservations pulls new reservations from every channel, books them and
+// acknowledges the outcome. A reservation that could not be processed because
+// of an internal error is left unacknowledged and pulled again next time.
+func (m *Manager) PullReservations(ctx context.Context) error {
+	names := make([]string, 0, len(m.adapters))
+	for name := range m.adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		adapter := m.adapters[name]
+		reservations, err := adapter.PullReservations(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pulling %s reservations: %w", name, err))
+			continue
+		}
+		for _, reservation := range reservations {
+			ack, err := m.apply(ctx, name, reservation)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("applying %s reservation %s: %w", name, reservation.ID, err))
+				continue
+			}
+			if !ack.Success {
+				slog.WarnContext(ctx, "channel reservation rejected", "channel", name, "reservation", reservation.ID, "reason", ack.Error)
+			}
+			if err := adapter.AckReservation(ctx, reservation.ID, ack); err != nil {
+				errs = append(errs, fmt.Errorf("acking %s reservation %s: %w", name, reservation.ID, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) apply(ctx context.Context, name string, r Reservation) (Ack, error) {
+	existing, err := m.store.Booking.GetBookings(ctx, bson.M{"channel": name, "channelRef": r.ID})
+	if err != nil {
+		return Ack{}, err
+	}
+
+	if r.IsCancelled() {
+		for _, booking := range existing {
+			canceled, err := m.store.Booking.CancelBooking(ctx, booking.ID)
+			if err != nil {
+				return Ack{}, err
+			}
+			if !canceled {
+				continue
+			}
+			if err := m.waitlist.Release(ctx, booking.RoomID); err != nil {
+				return Ack{}, err
+			}
+		}
+		return Ack{Success: true}, nil
+	}
+	if len(existing) > 0 {
+		return Ack{Success: true, BookingRef: existing[0].ID.Hex()}, nil
+	}
+
+	if reason := validateReservation(r); len(reason) > 0 {
+		return Ack{Error: reason}, nil
+	}
+
+	mappings, err := m.store.Channel.GetMappings(ctx, bson.M{
+		"channel":      name,
+		"roomCode":     r.RoomCode,
+		"ratePlanCode": r.RatePlanCode,
+	})
+	if err != nil {
+		return Ack{}, err
+	}
+	if len(mappings) == 0 {
+		return Ack{Error: fmt.Sprintf("unknown room code %s with rate plan %s", r.RoomCode, r.RatePlanCode)}, nil
+	}
+
+	room, err := m.store.Room.GetRoomByID(ctx, mappings[0].RoomID.Hex())
+	if err != nil {
+		return Ack{}, err
+	}
+
+	overbooked, err := m.checker.CheckBooking(ctx, room, availability.Request{
+		RoomID:     room.ID,
+		FromDate:   r.Arrival,
+		TillDate:   r.Departure,
+		NumPersons: r.NumPersons,
+	})
+	var restricted *availability.RestrictionError
+	if errors.Is(err, availability.ErrUnavailable) {
+		metrics.AvailabilityConflicts.WithLabelValues(metrics.ConflictUnavailable).Inc()
+		return Ack{Error: err.Error()}, nil
+	}
+	if errors.As(err, &restricted) {
+		metrics.AvailabilityConflicts.WithLabelValues(metrics.ConflictRestricted).Inc()
+		return Ack{Error: err.Error()}, nil
+	}
+	if err != nil {
+		return Ack{}, err
+	}
+
+	price := r.Total
+	if price <= 0 {
+		price = room.PriceFor(r.Arrival, r.Departure)
+	}
+	booking, err := m.store.Booking.BookRoom(ctx, &types.Booking{
+		RoomID:     room.ID,
+		FromDate:   r.Arrival,
+		TillDate:   r.Departure,
+		NumPersons: r.NumPersons,
+		Price:      price,
+		Channel:    name,
+		ChannelRef: r.ID,
+		GuestName:  r.GuestName,
+		Overbooked: overbooked,
+	})
+	if err != nil {
+		return Ack{}, err
+	}
+
+	return Ack{Success: true, BookingRef: booking.ID.Hex()}, nil
+}
+
+// validateReservation mirrors the checks HandleBookRoom applies to its params.
+func validateReservation(r Reservation) string {
+	if r.Arrival.Before(time.Now()) || r.Departure.Before(time.Now()) {
+		return "arrival and departure should be in the future"
+	}
+	if !r.Arrival.Before(r.Departure) {
+		return "arrival should be before departure"
+	}
+	if r.NumPersons <= 0 {
+		return "invalid number of persons"
+	}
+	return ""
+}
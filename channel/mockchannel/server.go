@@ -0,0 +1,127 @@
+// Package mockchannel is an in-process online travel agency used to exercise
+// channel adapters in tests and during local development.
+package mockchannel
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aboronilov/go-hotel-reservation/channel"
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+type Server struct {
+	apiKey string
+
+	mu            sync.Mutex
+	ari           []types.ARIUpdate
+	pending       []channel.Reservation
+	acks          map[string]channel.Ack
+	failARIPushes int
+}
+
+func NewServer(apiKey string) *Server {
+	return &Server{
+		apiKey: apiKey,
+		acks:   map[string]channel.Ack{},
+	}
+}
+
+// AddReservation queues a reservation to be returned by the next pull.
+func (s *Server) AddReservation(r channel.Reservation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, r)
+}
+
+// ARI returns every update pushed so far.
+func (s *Server) ARI() []types.ARIUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]types.ARIUpdate(nil), s.ari...)
+}
+
+// Ack returns the acknowledgement received for a reservation.
+func (s *Server) Ack(id string) (channel.Ack, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ack, ok := s.acks[id]
+	return ack, ok
+}
+
+// FailARIPushes makes the next n ARI pushes fail with a server error.
+func (s *Server) FailARIPushes(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failARIPushes = n
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Api-Key") != s.apiKey {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/ari":
+		s.handleARI(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/reservations":
+		s.handleReservations(w)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/reservations/") && strings.HasSuffix(r.URL.Path, "/ack"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/reservations/"), "/ack")
+		s.handleAck(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleARI(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Updates []types.ARIUpdate `json:"updates"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failARIPushes > 0 {
+		s.failARIPushes--
+		http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	s.ari = append(s.ari, body.Updates...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleReservations(w http.ResponseWriter) {
+	s.mu.Lock()
+	pending := append([]channel.Reservation{}, s.pending...)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pending)
+}
+
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request, id string) {
+	var ack channel.Ack
+	if err := json.NewDecoder(r.Body).Decode(&ack); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, reservation := range s.pending {
+		if reservation.ID == id {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.acks[id] = ack
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
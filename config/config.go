@@ -0,0 +1,368 @@
+// Package config loads the service settings. Every setting has a default and
+// can be set by a command line flag, a YAML config file and an environment
+// variable. Environment variables take precedence over the file, which takes
+// precedence over flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server    Server    `yaml:"server"`
+	DB        DB        `yaml:"db"`
+	Auth      Auth      `yaml:"auth"`
+	Mail      Mail      `yaml:"mail"`
+	Scheduler Scheduler `yaml:"scheduler"`
+	Channel   Channel   `yaml:"channel"`
+	Log       Log       `yaml:"log"`
+	Tracing   Tracing   `yaml:"tracing"`
+}
+
+type Server struct {
+	ListenAddr string `yaml:"listenAddr"`
+	// PublicURL is where users reach the service, used for links in emails.
+	// It defaults to localhost on the listen port.
+	PublicURL string `yaml:"publicURL"`
+	MediaDir  string `yaml:"mediaDir"`
+	// CalendarDir is the only directory calendar imports may read local
+	// files from. Without it imports only fetch http(s) URLs.
+	CalendarDir string `yaml:"calendarDir"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// on shutdown before their connections are closed.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+type DB struct {
+	URI  string `yaml:"uri"`
+	Name string `yaml:"name"`
+}
+
+type Auth struct {
+	JWTSecret string        `yaml:"jwtSecret"`
+	TokenTTL  time.Duration `yaml:"tokenTTL"`
+	// Limiter keeps login attempt counts in "memory", per instance, or in
+	// "mongo", shared by every instance.
+	Limiter string `yaml:"limiter"`
+	// IPLimit and EmailLimit are how many logins may be attempted from an
+	// IP and for an email within LimitWindow.
+	IPLimit     int           `yaml:"ipLimit"`
+	EmailLimit  int           `yaml:"emailLimit"`
+	LimitWindow time.Duration `yaml:"limitWindow"`
+	// MaxFailures failed logins in a row lock the account for
+	// LockoutDuration, or until it is unlocked from the emailed link.
+	MaxFailures     int           `yaml:"maxFailures"`
+	LockoutDuration time.Duration `yaml:"lockoutDuration"`
+	// TwoFactorIssuer names the service in authenticator apps. A login
+	// with two-factor authentication has ChallengeTTL to enter the code.
+	TwoFactorIssuer string        `yaml:"twoFactorIssuer"`
+	ChallengeTTL    time.Duration `yaml:"challengeTTL"`
+}
+
+// Mail sends through SMTP when SMTPAddr is set, and otherwise writes
+// messages to files in Dir.
+type Mail struct {
+	Dir          string `yaml:"dir"`
+	SMTPAddr     string `yaml:"smtpAddr"`
+	From         string `yaml:"from"`
+	SMTPUsername string `yaml:"smtpUsername"`
+	SMTPPassword string `yaml:"smtpPassword"`
+}
+
+type Scheduler struct {
+	Enabled bool `yaml:"enabled"`
+	// Intervals overrides how often a background job runs, by job name.
+	Intervals map[string]time.Duration `yaml:"intervals"`
+}
+
+// Channel configures the channel manager adapter. It is disabled without a
+// URL.
+type Channel struct {
+	Name   string `yaml:"name"`
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"apiKey"`
+}
+
+type Log struct {
+	// Level is one of debug, info, warn and error.
+	Level string `yaml:"level"`
+	// Format is json or text.
+	Format string `yaml:"format"`
+}
+
+// Tracing exports spans to the OTLP/HTTP collector at Endpoint with "otlp",
+// or as JSON lines to File, or stdout when empty, with "stdout".
+// Tracing is off with the "none" exporter.
+type Tracing struct {
+	Exporter string `yaml:"exporter"`
+	Endpoint string `yaml:"endpoint"`
+	File     string `yaml:"file"`
+	// SampleRatio is the share of new traces that are recorded. Requests
+	// that are part of a sampled trace upstream are always recorded.
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			ListenAddr:      ":5000",
+			MediaDir:        "media",
+			ShutdownTimeout: 15 * time.Second,
+		},
+		DB: DB{
+			URI:  "mongodb://localhost:27017",
+			Name: "hotel-reservation",
+		},
+		Auth: Auth{
+			TokenTTL:        72 * time.Hour,
+			Limiter:         "memory",
+			IPLimit:         50,
+			EmailLimit:      10,
+			LimitWindow:     15 * time.Minute,
+			MaxFailures:     5,
+			LockoutDuration: time.Hour,
+			TwoFactorIssuer: "Hotel Reservation",
+			ChallengeTTL:    5 * time.Minute,
+		},
+		Mail: Mail{
+			Dir: "mail",
+		},
+		Scheduler: Scheduler{
+			Enabled: true,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// setting binds a config field to its flag and environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value any
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"listenAddr", "LISTEN_ADDR", "The listen address of the server", &c.Server.ListenAddr},
+		{"publicURL", "PUBLIC_URL", "The URL users reach the server at", &c.Server.PublicURL},
+		{"mediaDir", "MEDIA_DIR", "The directory uploaded media is stored in", &c.Server.MediaDir},
+		{"calendarDir", "CALENDAR_DIR", "The directory calendar imports may read local files from", &c.Server.CalendarDir},
+		{"shutdownTimeout", "SHUTDOWN_TIMEOUT", "How long to drain requests on shutdown", &c.Server.ShutdownTimeout},
+		{"dbURI", "DB_URI", "The MongoDB connection string", &c.DB.URI},
+		{"dbName", "DB_NAME", "The MongoDB database name", &c.DB.Name},
+		{"jwtSecret", "JWT_SECRET", "The secret auth tokens are signed with", &c.Auth.JWTSecret},
+		{"tokenTTL", "TOKEN_TTL", "How long auth tokens are valid", &c.Auth.TokenTTL},
+		{"loginLimiter", "LOGIN_LIMITER", "Where login attempts are counted: memory or mongo", &c.Auth.Limiter},
+		{"loginIPLimit", "LOGIN_IP_LIMIT", "Login attempts allowed per IP within the limit window", &c.Auth.IPLimit},
+		{"loginEmailLimit", "LOGIN_EMAIL_LIMIT", "Login attempts allowed per email within the limit window", &c.Auth.EmailLimit},
+		{"loginLimitWindow", "LOGIN_LIMIT_WINDOW", "The window login attempts are counted in", &c.Auth.LimitWindow},
+		{"loginMaxFailures", "LOGIN_MAX_FAILURES", "Failed logins in a row before the account is locked", &c.Auth.MaxFailures},
+		{"lockoutDuration", "LOCKOUT_DURATION", "How long a locked account stays locked", &c.Auth.LockoutDuration},
+		{"twoFactorIssuer", "TWO_FACTOR_ISSUER", "The service name shown in authenticator apps", &c.Auth.TwoFactorIssuer},
+		{"challengeTTL", "TWO_FACTOR_CHALLENGE_TTL", "How long a login has to enter its two-factor code", &c.Auth.ChallengeTTL},
+		{"mailDir", "MAIL_DIR", "The directory emails are written to without SMTP", &c.Mail.Dir},
+		{"smtpAddr", "SMTP_ADDR", "The SMTP server host:port", &c.Mail.SMTPAddr},
+		{"mailFrom", "SMTP_FROM", "The sender address of emails", &c.Mail.From},
+		{"smtpUsername", "SMTP_USERNAME", "The SMTP username", &c.Mail.SMTPUsername},
+		{"smtpPassword", "SMTP_PASSWORD", "The SMTP password", &c.Mail.SMTPPassword},
+		{"scheduler", "SCHEDULER_ENABLED", "Whether to run background jobs", &c.Scheduler.Enabled},
+		{"channelName", "CHANNEL_NAME", "The channel manager name", &c.Channel.Name},
+		{"channelURL", "CHANNEL_URL", "The channel manager URL", &c.Channel.URL},
+		{"channelAPIKey", "CHANNEL_API_KEY", "The channel manager API key", &c.Channel.APIKey},
+		{"logLevel", "LOG_LEVEL", "The minimum log level: debug, info, warn or error", &c.Log.Level},
+		{"logFormat", "LOG_FORMAT", "The log format: json or text", &c.Log.Format},
+		{"tracingExporter", "TRACING_EXPORTER", "Where spans are exported: none, otlp or stdout", &c.Tracing.Exporter},
+		{"tracingEndpoint", "TRACING_ENDPOINT", "The OTLP/HTTP collector URL", &c.Tracing.Endpoint},
+		{"tracingFile", "TRACING_FILE", "The file the stdout exporter writes to", &c.Tracing.File},
+		{"tracingSampleRatio", "TRACING_SAMPLE_RATIO", "The share of new traces that are recorded", &c.Tracing.SampleRatio},
+	}
+}
+
+func (s setting) set(raw string) error {
+	switch v := s.value.(type) {
+	case *string:
+		*v = raw
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.flag, err)
+		}
+		*v = b
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.flag, err)
+		}
+		*v = n
+	case *float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.flag, err)
+		}
+		*v = f
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.flag, err)
+		}
+		*v = d
+	}
+	return nil
+}
+
+// Load registers the settings as flags on fs, parses args and merges the
+// defaults, flags, the config file given by -config or CONFIG_FILE, and the
+// environment. Callers may register their own flags on fs first. The result
+// is not validated.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+
+	flagged := map[string]string{}
+	settings := cfg.settings()
+	for _, s := range settings {
+		record := func(raw string) error {
+			flagged[s.flag] = raw
+			return nil
+		}
+		if _, ok := s.value.(*bool); ok {
+			fs.BoolFunc(s.flag, s.usage, record)
+		} else {
+			fs.Func(s.flag, s.usage, record)
+		}
+	}
+	path := fs.String("config", "", "Path to a YAML config file")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	for _, s := range settings {
+		if raw, ok := flagged[s.flag]; ok {
+			if err := s.set(raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if env := os.Getenv("CONFIG_FILE"); len(env) > 0 {
+		*path = env
+	}
+	if len(*path) > 0 {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if raw, ok := os.LookupEnv(s.env); ok && len(raw) > 0 {
+			if err := s.set(raw); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+
+	if len(cfg.Server.PublicURL) == 0 {
+		cfg.Server.PublicURL = "http://localhost" + cfg.Server.ListenAddr
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting, so a misconfigured server refuses
+// to start instead of failing on first use.
+func (c *Config) Validate() error {
+	var errs []error
+	if len(c.Server.ListenAddr) == 0 {
+		errs = append(errs, errors.New("server.listenAddr is required"))
+	}
+	if u, err := url.Parse(c.Server.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		errs = append(errs, errors.New("server.publicURL should be an absolute http(s) URL"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdownTimeout should be positive"))
+	}
+	if len(c.DB.URI) == 0 {
+		errs = append(errs, errors.New("db.uri is required"))
+	}
+	if len(c.DB.Name) == 0 {
+		errs = append(errs, errors.New("db.name is required"))
+	}
+	if len(c.Auth.JWTSecret) == 0 {
+		errs = append(errs, errors.New("auth.jwtSecret is required"))
+	}
+	if c.Auth.TokenTTL <= 0 {
+		errs = append(errs, errors.New("auth.tokenTTL should be positive"))
+	}
+	if c.Auth.Limiter != "memory" && c.Auth.Limiter != "mongo" {
+		errs = append(errs, errors.New("auth.limiter should be memory or mongo"))
+	}
+	if c.Auth.IPLimit <= 0 || c.Auth.EmailLimit <= 0 || c.Auth.MaxFailures <= 0 {
+		errs = append(errs, errors.New("auth.ipLimit, auth.emailLimit and auth.maxFailures should be positive"))
+	}
+	if c.Auth.LimitWindow <= 0 || c.Auth.LockoutDuration <= 0 || c.Auth.ChallengeTTL <= 0 {
+		errs = append(errs, errors.New("auth.limitWindow, auth.lockoutDuration and auth.challengeTTL should be positive"))
+	}
+	if len(c.Mail.SMTPAddr) > 0 && len(c.Mail.From) == 0 {
+		errs = append(errs, errors.New("mail.from is required to send through SMTP"))
+	}
+	for name, interval := range c.Scheduler.Intervals {
+		if interval <= 0 {
+			errs = append(errs, fmt.Errorf("scheduler.intervals.%s should be positive", name))
+		}
+	}
+	if len(c.Channel.URL) > 0 && len(c.Channel.Name) == 0 {
+		errs = append(errs, errors.New("channel.name is required with a channel URL"))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, errors.New("log.level should be debug, info, warn or error"))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, errors.New("log.format should be json or text"))
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			errs = append(errs, errors.New("tracing.endpoint should be an absolute http(s) URL with the otlp exporter"))
+		}
+	default:
+		errs = append(errs, errors.New("tracing.exporter should be none, otlp or stdout"))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sampleRatio should be between 0 and 1"))
+	}
+	return errors.Join(errs...)
+}
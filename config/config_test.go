@@ -0,0 +1,90 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+server:
+  listenAddr: ":7000"
+db:
+  name: from-file
+auth:
+  tokenTTL: 1h
+scheduler:
+  intervals:
+    channel-pull: 10m
+`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DB_NAME", "from-env")
+	t.Setenv("JWT_SECRET", "secret")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	args := []string{"-config", path, "-listenAddr", ":6000", "-dbName", "from-flag", "-mailDir", "flag-mail", "-scheduler=false", "extra"}
+	cfg, err := Load(fs, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// env over file over flags over defaults
+	if cfg.DB.Name != "from-env" {
+		t.Fatalf("expected the env db name but got %s", cfg.DB.Name)
+	}
+	if cfg.Server.ListenAddr != ":7000" {
+		t.Fatalf("expected the file listen address but got %s", cfg.Server.ListenAddr)
+	}
+	if cfg.Mail.Dir != "flag-mail" || cfg.Scheduler.Enabled {
+		t.Fatalf("expected the flag mail dir and scheduler but got %s and %v", cfg.Mail.Dir, cfg.Scheduler.Enabled)
+	}
+	if cfg.DB.URI != "mongodb://localhost:27017" {
+		t.Fatalf("expected the default db uri but got %s", cfg.DB.URI)
+	}
+	if cfg.Auth.TokenTTL != time.Hour || cfg.Scheduler.Intervals["channel-pull"] != 10*time.Minute {
+		t.Fatalf("expected durations from the file but got %v and %v", cfg.Auth.TokenTTL, cfg.Scheduler.Intervals)
+	}
+	if cfg.Server.PublicURL != "http://localhost:7000" {
+		t.Fatalf("expected the public url to default to the listen address but got %s", cfg.Server.PublicURL)
+	}
+	if fs.NArg() != 1 || fs.Arg(0) != "extra" {
+		t.Fatalf("expected the remaining arguments to be left to the caller but got %v", fs.Args())
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("db:\n  nmae: typo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path}); err == nil {
+		t.Fatal("expected an unknown key to be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.PublicURL = "localhost"
+	cfg.Mail.SMTPAddr = "smtp.example.com:587"
+	cfg.Scheduler.Intervals = map[string]time.Duration{"channel-pull": 0}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an invalid config")
+	}
+	for _, want := range []string{"publicURL", "jwtSecret", "mail.from", "channel-pull"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q to be reported in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AttemptStore counts attempts per key in fixed windows, so rate limits hold
+// across server instances.
+type AttemptStore interface {
+	// Incr counts an attempt for key and returns the count in the current
+	// window and when it ends. A window starts with the first attempt after
+	// the previous one ended.
+	Incr(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	// Count returns the count in the current window and when it ends.
+	Count(ctx context.Context, key string) (int, time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type attempts struct {
+	Key     string    `bson:"_id"`
+	Count   int       `bson:"count"`
+	ResetAt time.Time `bson:"resetAt"`
+}
+
+type MongoAttemptStore struct {
+	coll *mongo.Collection
+}
+
+func NewMongoAttemptStore(client *mongo.Client, dbname string) *MongoAttemptStore {
+	return &MongoAttemptStore{
+		coll: client.Database(dbname).Collection(ATTEMPT_COLLECTION),
+	}
+}
+
+func (s *MongoAttemptStore) Incr(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	// an update pipeline restarts a window that has ended in the same atomic
+	// operation that counts the attempt
+	current := bson.M{"$gt": bson.A{"$resetAt", now}}
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"count":   bson.M{"$cond": bson.A{current, bson.M{"$add": bson.A{"$count", 1}}, 1}},
+			"resetAt": bson.M{"$cond": bson.A{current, "$resetAt", now.Add(window)}},
+		}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var a attempts
+	if err := s.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&a); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return a.Count, a.ResetAt, nil
+}
+
+func (s *MongoAttemptStore) Count(ctx context.Context, key string) (int, time.Time, error) {
+	var a attempts
+	err := s.coll.FindOne(ctx, bson.M{"_id": key, "resetAt": bson.M{"$gt": time.Now()}}).Decode(&a)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return a.Count, a.ResetAt, nil
+}
+
+func (s *MongoAttemptStore) Reset(ctx context.Context, key string) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
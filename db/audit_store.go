@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxAppendAttempts bounds how often AddRecord retries when other writers
+// keep appending first.
+const maxAppendAttempts = 10
+
+// AuditStore is append-only: records can be added and read, never changed.
+type AuditStore interface {
+	// AddRecord appends the record to the chain, setting its ID, Seq,
+	// PrevHash and Hash.
+	AddRecord(context.Context, *types.AuditRecord) (*types.AuditRecord, error)
+	// GetRecords returns the newest records matching filter first.
+	GetRecords(ctx context.Context, filter bson.M, limit int64) ([]*types.AuditRecord, error)
+	// VerifyChain checks every record against the one before it.
+	VerifyChain(context.Context) (*types.AuditVerification, error)
+}
+
+type MongoAuditStore struct {
+	coll *mongo.Collection
+}
+
+func NewMongoAuditStore(client *mongo.Client, dbname string) *MongoAuditStore {
+	return &MongoAuditStore{
+		coll: client.Database(dbname).Collection(AUDIT_COLLECTION),
+	}
+}
+
+func (s *MongoAuditStore) AddRecord(ctx context.Context, record *types.AuditRecord) (*types.AuditRecord, error) {
+	for range maxAppendAttempts {
+		var last types.AuditRecord
+		err := s.coll.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})).Decode(&last)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+
+		record.ID = primitive.NewObjectID()
+		record.Seq = last.Seq + 1
+		record.PrevHash = last.Hash
+		record.CreatedAt = record.CreatedAt.UTC()
+		record.Hash = record.ComputeHash()
+
+		// the unique seq index turns a concurrent append into a duplicate
+		// key error, and the record is chained after the winner instead
+		_, err = s.coll.InsertOne(ctx, record)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+
+	return nil, fmt.Errorf("appending audit record %s: too much contention", record.Action)
+}
+
+func (s *MongoAuditStore) GetRecords(ctx context.Context, filter bson.M, limit int64) ([]*types.AuditRecord, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: -1}}).SetLimit(limit)
+	cur, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*types.AuditRecord{}
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (s *MongoAuditStore) VerifyChain(ctx context.Context) (*types.AuditVerification, error) {
+	cur, err := s.coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var (
+		verification = &types.AuditVerification{Valid: true}
+		prev         *types.AuditRecord
+	)
+	for cur.Next(ctx) {
+		var record types.AuditRecord
+		if err := cur.Decode(&record); err != nil {
+			return nil, err
+		}
+		if err := record.Follows(prev); err != nil {
+			verification.Valid = false
+			verification.Error = err.Error()
+			return verification, nil
+		}
+		verification.Checked++
+		prev = &record
+	}
+
+	return verification, cur.Err()
+}
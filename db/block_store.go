@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type BlockStore interface {
+	CreateBlock(context.Context, *types.RoomBlock) (*types.RoomBlock, error)
+	GetBlocks(context.Context, bson.M) ([]*types.RoomBlock, error)
+	GetBlockByCode(context.Context, string) (*types.RoomBlock, error)
+	ReleaseDueBlocks(context.Context, time.Time) (int64, error)
+}
+
+type MongoBlockStore struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+}
+
+func NewMongoBlockStore(client *mongo.Client, dbname string) *MongoBlockStore {
+	return &MongoBlockStore{
+		client: client,
+		coll:   client.Database(dbname).Collection(BLOCK_COLLECTION),
+	}
+}
+
+func (s *MongoBlockStore) CreateBlock(ctx context.Context, block *types.RoomBlock) (*types.RoomBlock, error) {
+	res, err := s.coll.InsertOne(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	block.ID = res.InsertedID.(primitive.ObjectID)
+
+	return block, nil
+}
+
+func (s *MongoBlockStore) GetBlocks(ctx context.Context, filter bson.M) ([]*types.RoomBlock, error) {
+	cur, err := s.coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*types.RoomBlock
+	if err := cur.All(ctx, &blocks); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+func (s *MongoBlockStore) GetBlockByCode(ctx context.Context, code string) (*types.RoomBlock, error) {
+	var block types.RoomBlock
+	if err := s.coll.FindOne(ctx, bson.M{"code": code}).Decode(&block); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// ReleaseDueBlocks marks blocks whose release date has passed as released,
+// returning their unpicked rooms to general inventory.
+func (s *MongoBlockStore) ReleaseDueBlocks(ctx context.Context, now time.Time) (int64, error) {
+	filter := bson.M{
+		"released":    false,
+		"releaseDate": bson.M{"$lte": now},
+	}
+	res, err := s.coll.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"released": true}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
@@ -2,11 +2,14 @@ package db
 
 import (
 	"context"
+	"log/slog"
+	"strings"
 
 	"github.com/aboronilov/go-hotel-reservation/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type BookingStore interface {
@@ -14,34 +17,39 @@ type BookingStore interface {
 	GetBookings(context.Context, bson.M) ([]*types.Booking, error)
 	GetBookingByID(context.Context, primitive.ObjectID) (*types.Booking, error)
 	UpdateBooking(context.Context, primitive.ObjectID, bson.M) error
+	CancelBooking(context.Context, primitive.ObjectID) (bool, error)
+	StreamBookings(context.Context, bson.M, func(*types.Booking) error) error
 }
 
 type MongoBookingStore struct {
 	client *mongo.Client
 	coll   *mongo.Collection
+	outbox *mongo.Collection
 
 	BookingStore
 }
 
-func NewMongoBookingStore(client *mongo.Client, isTest bool) *MongoBookingStore {
-	if isTest {
-		return &MongoBookingStore{
-			client: client,
-			coll:   client.Database(TestDBNAME).Collection(BOOKING_COLLECTION),
-		}
-	}
+func NewMongoBookingStore(client *mongo.Client, dbname string) *MongoBookingStore {
 	return &MongoBookingStore{
 		client: client,
-		coll:   client.Database(DBNAME).Collection(BOOKING_COLLECTION),
+		coll:   client.Database(dbname).Collection(BOOKING_COLLECTION),
+		outbox: client.Database(dbname).Collection(OUTBOX_COLLECTION),
 	}
 }
 
 func (s *MongoBookingStore) BookRoom(ctx context.Context, booking *types.Booking) (*types.Booking, error) {
-	res, err := s.coll.InsertOne(ctx, booking)
+	booking.ID = primitive.NewObjectID()
+	err := withTransaction(ctx, s.client, func(ctx context.Context) error {
+		if _, err := s.coll.InsertOne(ctx, booking); err != nil {
+			return err
+		}
+		return writeEvent(ctx, s.outbox, types.EventBookingCreated, booking.ID, booking)
+	})
 	if err != nil {
+		slog.WarnContext(ctx, "booking rolled back", "bookingID", booking.ID.Hex(), "err", err)
+		s.coll.DeleteOne(ctx, bson.M{"_id": booking.ID})
 		return nil, err
 	}
-	booking.ID = res.InsertedID.(primitive.ObjectID)
 
 	return booking, nil
 }
@@ -70,7 +78,111 @@ func (s *MongoBookingStore) GetBookingByID(ctx context.Context, oid primitive.Ob
 	return &booking, nil
 }
 
+// UpdateBooking applies update and records a booking.modified event when it
+// changes what the guest booked. Bookings are canceled with CancelBooking.
 func (s *MongoBookingStore) UpdateBooking(ctx context.Context, id primitive.ObjectID, update bson.M) error {
-	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	_, err := s.update(ctx, bson.M{"_id": id}, update, func(before, after *types.Booking) string {
+		if after.ChangedForGuest(before) {
+			return types.EventBookingModified
+		}
+		return ""
+	})
 	return err
 }
+
+// CancelBooking cancels the booking and records a booking.canceled event. It
+// reports false, and records nothing, when the booking was canceled already.
+func (s *MongoBookingStore) CancelBooking(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "canceled": bson.M{"$ne": true}}
+	return s.update(ctx, filter, bson.M{"canceled": true}, func(before, after *types.Booking) string {
+		return types.EventBookingCanceled
+	})
+}
+
+// update sets fields on the booking matching filter and records the event
+// eventType names for the change, if any. It reports whether a booking
+// matched. Without a transaction a failed event write leaves the update in
+// place, so the updated fields are set back as they were.
+func (s *MongoBookingStore) update(ctx context.Context, filter, update bson.M, eventType func(before, after *types.Booking) string) (bool, error) {
+	var prev bson.Raw
+	err := withTransaction(ctx, s.client, func(ctx context.Context) error {
+		prev = nil
+		raw, err := s.coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": update}).Raw()
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		prev = raw
+
+		var before, after types.Booking
+		if err := bson.Unmarshal(raw, &before); err != nil {
+			return err
+		}
+		if err := s.coll.FindOne(ctx, bson.M{"_id": before.ID}).Decode(&after); err != nil {
+			return err
+		}
+		if eventType := eventType(&before, &after); len(eventType) > 0 {
+			return writeEvent(ctx, s.outbox, eventType, after.ID, &after)
+		}
+		return nil
+	})
+	if err != nil {
+		if prev != nil {
+			slog.WarnContext(ctx, "booking update rolled back", "bookingID", prev.Lookup("_id").ObjectID().Hex(), "err", err)
+			s.coll.UpdateOne(ctx, bson.M{"_id": prev.Lookup("_id")}, revert(prev, update))
+		}
+		return false, err
+	}
+
+	return prev != nil, nil
+}
+
+// revert is the update that sets the fields of update back to their values
+// in prev, removing those prev did not have.
+func revert(prev bson.Raw, update bson.M) bson.M {
+	var (
+		set   = bson.M{}
+		unset = bson.M{}
+	)
+	for field := range update {
+		if value, err := prev.LookupErr(strings.Split(field, ".")...); err == nil {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+
+	revert := bson.M{}
+	if len(set) > 0 {
+		revert["$set"] = set
+	}
+	if len(unset) > 0 {
+		revert["$unset"] = unset
+	}
+	return revert
+}
+
+// StreamBookings decodes matching bookings one at a time, ordered by arrival,
+// so large exports do not have to be held in memory.
+func (s *MongoBookingStore) StreamBookings(ctx context.Context, filter bson.M, fn func(*types.Booking) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "fromDate", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var booking types.Booking
+		if err := cur.Decode(&booking); err != nil {
+			return err
+		}
+		if err := fn(&booking); err != nil {
+			return err
+		}
+	}
+
+	return cur.Err()
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CalendarStore interface {
+	SetFeedToken(context.Context, primitive.ObjectID, string) (*types.CalendarFeed, error)
+	GetFeedByToken(context.Context, string) (*types.CalendarFeed, error)
+	CreateImport(context.Context, *types.CalendarImport) (*types.CalendarImport, error)
+	GetImports(context.Context, bson.M) ([]*types.CalendarImport, error)
+	GetImportByID(context.Context, primitive.ObjectID) (*types.CalendarImport, error)
+	UpdateImport(context.Context, primitive.ObjectID, bson.M) error
+	DeleteImportByID(context.Context, primitive.ObjectID) error
+}
+
+type MongoCalendarStore struct {
+	client  *mongo.Client
+	feeds   *mongo.Collection
+	imports *mongo.Collection
+}
+
+func NewMongoCalendarStore(client *mongo.Client, dbname string) *MongoCalendarStore {
+	return &MongoCalendarStore{
+		client:  client,
+		feeds:   client.Database(dbname).Collection(CALENDAR_FEED_COLLECTION),
+		imports: client.Database(dbname).Collection(CALENDAR_IMPORT_COLLECTION),
+	}
+}
+
+// SetFeedToken creates the room's feed or rotates its token, invalidating the
+// previous feed URL.
+func (s *MongoCalendarStore) SetFeedToken(ctx context.Context, roomID primitive.ObjectID, token string) (*types.CalendarFeed, error) {
+	update := bson.M{"$set": bson.M{
+		"roomID":    roomID,
+		"token":     token,
+		"createdAt": time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var feed types.CalendarFeed
+	if err := s.feeds.FindOneAndUpdate(ctx, bson.M{"roomID": roomID}, update, opts).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	return &feed, nil
+}
+
+func (s *MongoCalendarStore) GetFeedByToken(ctx context.Context, token string) (*types.CalendarFeed, error) {
+	var feed types.CalendarFeed
+	if err := s.feeds.FindOne(ctx, bson.M{"token": token}).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	return &feed, nil
+}
+
+func (s *MongoCalendarStore) CreateImport(ctx context.Context, imp *types.CalendarImport) (*types.CalendarImport, error) {
+	res, err := s.imports.InsertOne(ctx, imp)
+	if err != nil {
+		return nil, err
+	}
+	imp.ID = res.InsertedID.(primitive.ObjectID)
+
+	return imp, nil
+}
+
+func (s *MongoCalendarStore) GetImports(ctx context.Context, filter bson.M) ([]*types.CalendarImport, error) {
+	cur, err := s.imports.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []*types.CalendarImport
+	if err := cur.All(ctx, &imports); err != nil {
+		return nil, err
+	}
+
+	return imports, nil
+}
+
+func (s *MongoCalendarStore) GetImportByID(ctx context.Context, oid primitive.ObjectID) (*types.CalendarImport, error) {
+	var imp types.CalendarImport
+	if err := s.imports.FindOne(ctx, bson.M{"_id": oid}).Decode(&imp); err != nil {
+		return nil, err
+	}
+
+	return &imp, nil
+}
+
+func (s *MongoCalendarStore) UpdateImport(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.imports.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
+
+func (s *MongoCalendarStore) DeleteImportByID(ctx context.Context, id primitive.ObjectID) error {
+	_, err := s.imports.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ChannelStore interface {
+	CreateMapping(context.Context, *types.ChannelMapping) (*types.ChannelMapping, error)
+	GetMappings(context.Context, bson.M) ([]*types.ChannelMapping, error)
+	DeleteMappingByID(context.Context, string) error
+	EnqueueMessage(context.Context, *types.ChannelMessage) (*types.ChannelMessage, error)
+	GetMessages(context.Context, bson.M) ([]*types.ChannelMessage, error)
+	GetDueMessages(context.Context, time.Time, int64) ([]*types.ChannelMessage, error)
+	UpdateMessage(context.Context, primitive.ObjectID, bson.M) error
+}
+
+type MongoChannelStore struct {
+	client   *mongo.Client
+	mappings *mongo.Collection
+	outbox   *mongo.Collection
+}
+
+func NewMongoChannelStore(client *mongo.Client, dbname string) *MongoChannelStore {
+	return &MongoChannelStore{
+		client:   client,
+		mappings: client.Database(dbname).Collection(CHANNEL_MAPPING_COLLECTION),
+		outbox:   client.Database(dbname).Collection(CHANNEL_OUTBOX_COLLECTION),
+	}
+}
+
+func (s *MongoChannelStore) CreateMapping(ctx context.Context, mapping *types.ChannelMapping) (*types.ChannelMapping, error) {
+	res, err := s.mappings.InsertOne(ctx, mapping)
+	if err != nil {
+		return nil, err
+	}
+	mapping.ID = res.InsertedID.(primitive.ObjectID)
+
+	return mapping, nil
+}
+
+func (s *MongoChannelStore) GetMappings(ctx context.Context, filter bson.M) ([]*types.ChannelMapping, error) {
+	cur, err := s.mappings.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []*types.ChannelMapping
+	if err := cur.All(ctx, &mappings); err != nil {
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+func (s *MongoChannelStore) DeleteMappingByID(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.mappings.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
+
+func (s *MongoChannelStore) EnqueueMessage(ctx context.Context, msg *types.ChannelMessage) (*types.ChannelMessage, error) {
+	res, err := s.outbox.InsertOne(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	msg.ID = res.InsertedID.(primitive.ObjectID)
+
+	return msg, nil
+}
+
+func (s *MongoChannelStore) GetMessages(ctx context.Context, filter bson.M) ([]*types.ChannelMessage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(100)
+	cur, err := s.outbox.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*types.ChannelMessage
+	if err := cur.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// GetDueMessages returns pending messages whose next attempt is due, oldest
+// first so pushes reach a channel in the order they were queued.
+func (s *MongoChannelStore) GetDueMessages(ctx context.Context, now time.Time, limit int64) ([]*types.ChannelMessage, error) {
+	filter := bson.M{
+		"status":        types.OutboxStatusPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}).SetLimit(limit)
+	cur, err := s.outbox.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*types.ChannelMessage
+	if err := cur.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (s *MongoChannelStore) UpdateMessage(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.outbox.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
@@ -1,18 +1,49 @@
 package db
 
 const (
-	TestDBNAME         = "test-hotel-reservation"
-	DBNAME             = "hotel-reservation"
-	DBURI              = "mongodb://localhost:27017"
-	HOTEL_COLLECTION   = "hotels"
-	USERS_COLLECTION   = "users"
-	ROOM_COLLECTION    = "rooms"
-	BOOKING_COLLECTION = "bookings"
+	HOTEL_COLLECTION                    = "hotels"
+	USERS_COLLECTION                    = "users"
+	ROOM_COLLECTION                     = "rooms"
+	BOOKING_COLLECTION                  = "bookings"
+	WAITLIST_COLLECTION                 = "waitlist"
+	BLOCK_COLLECTION                    = "blocks"
+	ITINERARY_COLLECTION                = "itineraries"
+	RESTRICTION_COLLECTION              = "restrictions"
+	OVERBOOKING_COLLECTION              = "overbooking"
+	CALENDAR_FEED_COLLECTION            = "calendar_feeds"
+	CALENDAR_IMPORT_COLLECTION          = "calendar_imports"
+	CHANNEL_MAPPING_COLLECTION          = "channel_mappings"
+	CHANNEL_OUTBOX_COLLECTION           = "channel_outbox"
+	WEBHOOK_COLLECTION                  = "webhooks"
+	WEBHOOK_DELIVERY_COLLECTION         = "webhook_deliveries"
+	OUTBOX_COLLECTION                   = "outbox"
+	NOTIFICATION_COLLECTION             = "notifications"
+	NOTIFICATION_PREFERENCES_COLLECTION = "notification_preferences"
+	REVIEW_COLLECTION                   = "reviews"
+	MIGRATION_COLLECTION                = "migrations"
+	ATTEMPT_COLLECTION                  = "auth_attempts"
+	AUDIT_COLLECTION                    = "audit_log"
+	SETTINGS_COLLECTION                 = "settings"
 )
 
 type Store struct {
-	User    UserStore
-	Hotel   HotelStore
-	Room    RoomStore
-	Booking BookingStore
+	User         UserStore
+	Hotel        HotelStore
+	Room         RoomStore
+	Booking      BookingStore
+	Waitlist     WaitlistStore
+	Block        BlockStore
+	Itinerary    ItineraryStore
+	Restriction  RestrictionStore
+	Overbooking  OverbookingStore
+	Report       ReportStore
+	Calendar     CalendarStore
+	Channel      ChannelStore
+	Webhook      WebhookStore
+	Event        EventStore
+	Notification NotificationStore
+	Review       ReviewStore
+	Attempt      AttemptStore
+	Audit        AuditStore
+	Settings     SettingsStore
 }
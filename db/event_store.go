@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EventStore reads the outbox of domain events. Events are written by the
+// stores that make the changes, see writeEvent.
+type EventStore interface {
+	GetEventByID(context.Context, primitive.ObjectID) (*types.Event, error)
+	GetPendingEvents(context.Context, time.Time, int64) ([]*types.Event, error)
+	UpdateEvent(context.Context, primitive.ObjectID, bson.M) error
+}
+
+type MongoEventStore struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+}
+
+func NewMongoEventStore(client *mongo.Client, dbname string) *MongoEventStore {
+	return &MongoEventStore{
+		client: client,
+		coll:   client.Database(dbname).Collection(OUTBOX_COLLECTION),
+	}
+}
+
+// writeEvent adds an event to the outbox. Stores call it from within the
+// transaction that makes the change, so an event is recorded if and only if
+// the change is.
+func writeEvent(ctx context.Context, outbox *mongo.Collection, eventType string, subjectID primitive.ObjectID, subject any) error {
+	data, err := json.Marshal(subject)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = outbox.InsertOne(ctx, &types.Event{
+		Type:          eventType,
+		SubjectID:     subjectID,
+		Data:          data,
+		OccurredAt:    now,
+		NextAttemptAt: now,
+	})
+	return err
+}
+
+func (s *MongoEventStore) GetEventByID(ctx context.Context, oid primitive.ObjectID) (*types.Event, error) {
+	var event types.Event
+	if err := s.coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// GetPendingEvents returns undispatched events that are due, in the order they
+// occurred. Dead events are left out.
+func (s *MongoEventStore) GetPendingEvents(ctx context.Context, now time.Time, limit int64) ([]*types.Event, error) {
+	filter := bson.M{
+		"dispatched":    false,
+		"dead":          bson.M{"$ne": true},
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "occurredAt", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(limit)
+	cur, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*types.Event
+	if err := cur.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *MongoEventStore) UpdateEvent(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
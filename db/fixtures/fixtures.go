@@ -31,11 +31,10 @@ func AddUser(store *db.Store, firstName, lastName string, isAdmin bool) *types.U
 	return newUser
 }
 
-func AddHotel(store *db.Store, name, location string, rating int) *types.Hotel {
+func AddHotel(store *db.Store, name, location string) *types.Hotel {
 	hotel := &types.Hotel{
 		Name:     name,
 		Location: location,
-		Rating:   rating,
 		Rooms:    []primitive.ObjectID{},
 	}
 	insertedHotel, err := store.Hotel.CreateHotel(context.TODO(), hotel)
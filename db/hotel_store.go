@@ -2,11 +2,13 @@ package db
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/aboronilov/go-hotel-reservation/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type HotelStore interface {
@@ -15,6 +17,10 @@ type HotelStore interface {
 	CreateHotel(context.Context, *types.Hotel) (*types.Hotel, error)
 	DeleteHotelByID(context.Context, string) error
 	UpdateHotelByID(ctx context.Context, filter bson.M, params bson.M) error
+	GetHotelsNear(ctx context.Context, filter bson.M, point *types.GeoPoint, radiusKm float64) ([]*types.HotelDistance, error)
+	SearchHotels(ctx context.Context, text string, limit int64) ([]*types.HotelMatch, error)
+	ReorderImages(ctx context.Context, id primitive.ObjectID, keys []string, images []types.HotelImage) (bool, error)
+	GetSearchableHotels(ctx context.Context) ([]*types.Hotel, error)
 }
 
 type MongoHotelStore struct {
@@ -22,16 +28,10 @@ type MongoHotelStore struct {
 	coll   *mongo.Collection
 }
 
-func NewMongoHotelStore(client *mongo.Client, isTest bool) *MongoHotelStore {
-	if isTest {
-		return &MongoHotelStore{
-			client: client,
-			coll:   client.Database(TestDBNAME).Collection(HOTEL_COLLECTION),
-		}
-	}
+func NewMongoHotelStore(client *mongo.Client, dbname string) *MongoHotelStore {
 	return &MongoHotelStore{
 		client: client,
-		coll:   client.Database(DBNAME).Collection(HOTEL_COLLECTION),
+		coll:   client.Database(dbname).Collection(HOTEL_COLLECTION),
 	}
 }
 
@@ -56,6 +56,22 @@ func (s *MongoHotelStore) UpdateHotelByID(ctx context.Context, filter bson.M, up
 	return nil
 }
 
+// ReorderImages replaces the gallery of the hotel with images, provided it
+// still holds the images listed in keys, in that order. It reports false when
+// the gallery changed in the meantime.
+func (s *MongoHotelStore) ReorderImages(ctx context.Context, id primitive.ObjectID, keys []string, images []types.HotelImage) (bool, error) {
+	filter := bson.M{"_id": id, "images": bson.M{"$size": len(keys)}}
+	for i, key := range keys {
+		filter[fmt.Sprintf("images.%d.key", i)] = key
+	}
+	res, err := s.coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"images": images}})
+	if err != nil {
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
 func (s *MongoHotelStore) GetHotelByID(ctx context.Context, oid primitive.ObjectID) (*types.Hotel, error) {
 	var hotel types.Hotel
 	err := s.coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&hotel)
@@ -85,6 +101,28 @@ func (s *MongoHotelStore) GetHotels(ctx context.Context, filter bson.M) ([]*type
 	return hotels, nil
 }
 
+// GetSearchableHotels returns every hotel with only the fields free text
+// search matches against: name, city, description and amenities.
+func (s *MongoHotelStore) GetSearchableHotels(ctx context.Context) ([]*types.Hotel, error) {
+	opts := options.Find().SetProjection(bson.M{
+		"name":         1,
+		"address.city": 1,
+		"description":  1,
+		"amenities":    1,
+	})
+	cur, err := s.coll.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hotels := []*types.Hotel{}
+	if err := cur.All(ctx, &hotels); err != nil {
+		return nil, err
+	}
+
+	return hotels, nil
+}
+
 func (s *MongoHotelStore) DeleteHotelByID(ctx context.Context, id string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -98,3 +136,55 @@ func (s *MongoHotelStore) DeleteHotelByID(ctx context.Context, id string) error
 
 	return nil
 }
+
+// GetHotelsNear returns the hotels matching filter within radiusKm of point,
+// nearest first. $geoNear needs the 2dsphere index the migrations create.
+func (s *MongoHotelStore) GetHotelsNear(ctx context.Context, filter bson.M, point *types.GeoPoint, radiusKm float64) ([]*types.HotelDistance, error) {
+	if filter == nil {
+		filter = bson.M{}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: bson.M{
+			"near":               point,
+			"key":                "geo",
+			"distanceField":      "distanceKm",
+			"distanceMultiplier": 0.001,
+			"maxDistance":        radiusKm * 1000,
+			"spherical":          true,
+			"query":              filter,
+		}}},
+	}
+	cur, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	hotels := []*types.HotelDistance{}
+	if err := cur.All(ctx, &hotels); err != nil {
+		return nil, err
+	}
+
+	return hotels, nil
+}
+
+// SearchHotels runs a MongoDB text search over the hotels, using the text
+// index the migrations create, and returns the matches best first.
+func (s *MongoHotelStore) SearchHotels(ctx context.Context, text string, limit int64) ([]*types.HotelMatch, error) {
+	filter := bson.M{"$text": bson.M{"$search": text}}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "name", Value: 1}}).
+		SetLimit(limit)
+	cur, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hotels := []*types.HotelMatch{}
+	if err := cur.All(ctx, &hotels); err != nil {
+		return nil, err
+	}
+
+	return hotels, nil
+}
@@ -0,0 +1,12 @@
+package db
+
+import "context"
+
+//go:generate go run ../scripts/instrumentgen
+
+// Observer is told about store calls made through Instrument. Observe is
+// called before each call and may return a derived context for it; the
+// returned function is called with the call's error once it returns.
+type Observer interface {
+	Observe(ctx context.Context, store, method string) (context.Context, func(error))
+}
@@ -0,0 +1,803 @@
+// Code generated by scripts/instrumentgen. DO NOT EDIT.
+
+package db
+
+import (
+	"context"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Instrument wraps every store so observer is told about each call.
+func Instrument(store *Store, observer Observer) *Store {
+	return &Store{
+		User:         &instrumentedUserStore{next: store.User, observer: observer},
+		Hotel:        &instrumentedHotelStore{next: store.Hotel, observer: observer},
+		Room:         &instrumentedRoomStore{next: store.Room, observer: observer},
+		Booking:      &instrumentedBookingStore{next: store.Booking, observer: observer},
+		Waitlist:     &instrumentedWaitlistStore{next: store.Waitlist, observer: observer},
+		Block:        &instrumentedBlockStore{next: store.Block, observer: observer},
+		Itinerary:    &instrumentedItineraryStore{next: store.Itinerary, observer: observer},
+		Restriction:  &instrumentedRestrictionStore{next: store.Restriction, observer: observer},
+		Overbooking:  &instrumentedOverbookingStore{next: store.Overbooking, observer: observer},
+		Report:       &instrumentedReportStore{next: store.Report, observer: observer},
+		Calendar:     &instrumentedCalendarStore{next: store.Calendar, observer: observer},
+		Channel:      &instrumentedChannelStore{next: store.Channel, observer: observer},
+		Webhook:      &instrumentedWebhookStore{next: store.Webhook, observer: observer},
+		Event:        &instrumentedEventStore{next: store.Event, observer: observer},
+		Notification: &instrumentedNotificationStore{next: store.Notification, observer: observer},
+		Review:       &instrumentedReviewStore{next: store.Review, observer: observer},
+		Attempt:      &instrumentedAttemptStore{next: store.Attempt, observer: observer},
+		Audit:        &instrumentedAuditStore{next: store.Audit, observer: observer},
+		Settings:     &instrumentedSettingsStore{next: store.Settings, observer: observer},
+	}
+}
+
+type instrumentedUserStore struct {
+	next     UserStore
+	observer Observer
+}
+
+func (s *instrumentedUserStore) Drop(ctx context.Context) (err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "Drop")
+	err = s.next.Drop(ctx)
+	done(err)
+	return err
+}
+
+func (s *instrumentedUserStore) GetUserByEmail(ctx context.Context, a1 string) (r0 *types.User, err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "GetUserByEmail")
+	r0, err = s.next.GetUserByEmail(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedUserStore) GetUserByID(ctx context.Context, a1 string) (r0 *types.User, err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "GetUserByID")
+	r0, err = s.next.GetUserByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedUserStore) GetUsers(ctx context.Context) (r0 []*types.User, err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "GetUsers")
+	r0, err = s.next.GetUsers(ctx)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedUserStore) CreateUser(ctx context.Context, a1 *types.User) (r0 *types.User, err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "CreateUser")
+	r0, err = s.next.CreateUser(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedUserStore) DeleteUserByID(ctx context.Context, a1 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "DeleteUserByID")
+	err = s.next.DeleteUserByID(ctx, a1)
+	done(err)
+	return err
+}
+
+func (s *instrumentedUserStore) UpdateUserByID(ctx context.Context, a1 bson.M, a2 types.UpdateUserParams) (err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "UpdateUserByID")
+	err = s.next.UpdateUserByID(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+func (s *instrumentedUserStore) LockUser(ctx context.Context, a1 primitive.ObjectID, a2 time.Time, a3 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "LockUser")
+	err = s.next.LockUser(ctx, a1, a2, a3)
+	done(err)
+	return err
+}
+
+func (s *instrumentedUserStore) UnlockUser(ctx context.Context, a1 string) (r0 *types.User, err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "UnlockUser")
+	r0, err = s.next.UnlockUser(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedUserStore) SetTwoFactor(ctx context.Context, a1 primitive.ObjectID, a2 string, a3 bool, a4 []string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "SetTwoFactor")
+	err = s.next.SetTwoFactor(ctx, a1, a2, a3, a4)
+	done(err)
+	return err
+}
+
+func (s *instrumentedUserStore) UseTOTPStep(ctx context.Context, a1 primitive.ObjectID, a2 int64) (r0 bool, err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "UseTOTPStep")
+	r0, err = s.next.UseTOTPStep(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedUserStore) UseRecoveryCode(ctx context.Context, a1 primitive.ObjectID, a2 string) (r0 bool, err error) {
+	ctx, done := s.observer.Observe(ctx, "User", "UseRecoveryCode")
+	r0, err = s.next.UseRecoveryCode(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+type instrumentedHotelStore struct {
+	next     HotelStore
+	observer Observer
+}
+
+func (s *instrumentedHotelStore) GetHotelByID(ctx context.Context, a1 primitive.ObjectID) (r0 *types.Hotel, err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "GetHotelByID")
+	r0, err = s.next.GetHotelByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedHotelStore) GetHotels(ctx context.Context, a1 bson.M) (r0 []*types.Hotel, err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "GetHotels")
+	r0, err = s.next.GetHotels(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedHotelStore) CreateHotel(ctx context.Context, a1 *types.Hotel) (r0 *types.Hotel, err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "CreateHotel")
+	r0, err = s.next.CreateHotel(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedHotelStore) DeleteHotelByID(ctx context.Context, a1 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "DeleteHotelByID")
+	err = s.next.DeleteHotelByID(ctx, a1)
+	done(err)
+	return err
+}
+
+func (s *instrumentedHotelStore) UpdateHotelByID(ctx context.Context, a1 bson.M, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "UpdateHotelByID")
+	err = s.next.UpdateHotelByID(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+func (s *instrumentedHotelStore) GetHotelsNear(ctx context.Context, a1 bson.M, a2 *types.GeoPoint, a3 float64) (r0 []*types.HotelDistance, err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "GetHotelsNear")
+	r0, err = s.next.GetHotelsNear(ctx, a1, a2, a3)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedHotelStore) SearchHotels(ctx context.Context, a1 string, a2 int64) (r0 []*types.HotelMatch, err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "SearchHotels")
+	r0, err = s.next.SearchHotels(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedHotelStore) ReorderImages(ctx context.Context, a1 primitive.ObjectID, a2 []string, a3 []types.HotelImage) (r0 bool, err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "ReorderImages")
+	r0, err = s.next.ReorderImages(ctx, a1, a2, a3)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedHotelStore) GetSearchableHotels(ctx context.Context) (r0 []*types.Hotel, err error) {
+	ctx, done := s.observer.Observe(ctx, "Hotel", "GetSearchableHotels")
+	r0, err = s.next.GetSearchableHotels(ctx)
+	done(err)
+	return r0, err
+}
+
+type instrumentedRoomStore struct {
+	next     RoomStore
+	observer Observer
+}
+
+func (s *instrumentedRoomStore) GetRoomByID(ctx context.Context, a1 string) (r0 *types.Room, err error) {
+	ctx, done := s.observer.Observe(ctx, "Room", "GetRoomByID")
+	r0, err = s.next.GetRoomByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedRoomStore) GetRooms(ctx context.Context, a1 bson.M) (r0 []*types.Room, err error) {
+	ctx, done := s.observer.Observe(ctx, "Room", "GetRooms")
+	r0, err = s.next.GetRooms(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedRoomStore) CreateRoom(ctx context.Context, a1 *types.Room) (r0 *types.Room, err error) {
+	ctx, done := s.observer.Observe(ctx, "Room", "CreateRoom")
+	r0, err = s.next.CreateRoom(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedRoomStore) DeleteRoomByID(ctx context.Context, a1 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Room", "DeleteRoomByID")
+	err = s.next.DeleteRoomByID(ctx, a1)
+	done(err)
+	return err
+}
+
+func (s *instrumentedRoomStore) UpdateRoomByID(ctx context.Context, a1 bson.M, a2 types.UpdateRoomParams) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Room", "UpdateRoomByID")
+	err = s.next.UpdateRoomByID(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+type instrumentedBookingStore struct {
+	next     BookingStore
+	observer Observer
+}
+
+func (s *instrumentedBookingStore) BookRoom(ctx context.Context, a1 *types.Booking) (r0 *types.Booking, err error) {
+	ctx, done := s.observer.Observe(ctx, "Booking", "BookRoom")
+	r0, err = s.next.BookRoom(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedBookingStore) GetBookings(ctx context.Context, a1 bson.M) (r0 []*types.Booking, err error) {
+	ctx, done := s.observer.Observe(ctx, "Booking", "GetBookings")
+	r0, err = s.next.GetBookings(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedBookingStore) GetBookingByID(ctx context.Context, a1 primitive.ObjectID) (r0 *types.Booking, err error) {
+	ctx, done := s.observer.Observe(ctx, "Booking", "GetBookingByID")
+	r0, err = s.next.GetBookingByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedBookingStore) UpdateBooking(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Booking", "UpdateBooking")
+	err = s.next.UpdateBooking(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+func (s *instrumentedBookingStore) CancelBooking(ctx context.Context, a1 primitive.ObjectID) (r0 bool, err error) {
+	ctx, done := s.observer.Observe(ctx, "Booking", "CancelBooking")
+	r0, err = s.next.CancelBooking(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedBookingStore) StreamBookings(ctx context.Context, a1 bson.M, a2 func(*types.Booking) error) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Booking", "StreamBookings")
+	err = s.next.StreamBookings(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+type instrumentedWaitlistStore struct {
+	next     WaitlistStore
+	observer Observer
+}
+
+func (s *instrumentedWaitlistStore) CreateEntry(ctx context.Context, a1 *types.WaitlistEntry) (r0 *types.WaitlistEntry, err error) {
+	ctx, done := s.observer.Observe(ctx, "Waitlist", "CreateEntry")
+	r0, err = s.next.CreateEntry(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedWaitlistStore) GetEntries(ctx context.Context, a1 bson.M) (r0 []*types.WaitlistEntry, err error) {
+	ctx, done := s.observer.Observe(ctx, "Waitlist", "GetEntries")
+	r0, err = s.next.GetEntries(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedWaitlistStore) GetEntryByID(ctx context.Context, a1 primitive.ObjectID) (r0 *types.WaitlistEntry, err error) {
+	ctx, done := s.observer.Observe(ctx, "Waitlist", "GetEntryByID")
+	r0, err = s.next.GetEntryByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedWaitlistStore) UpdateEntry(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Waitlist", "UpdateEntry")
+	err = s.next.UpdateEntry(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+type instrumentedBlockStore struct {
+	next     BlockStore
+	observer Observer
+}
+
+func (s *instrumentedBlockStore) CreateBlock(ctx context.Context, a1 *types.RoomBlock) (r0 *types.RoomBlock, err error) {
+	ctx, done := s.observer.Observe(ctx, "Block", "CreateBlock")
+	r0, err = s.next.CreateBlock(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedBlockStore) GetBlocks(ctx context.Context, a1 bson.M) (r0 []*types.RoomBlock, err error) {
+	ctx, done := s.observer.Observe(ctx, "Block", "GetBlocks")
+	r0, err = s.next.GetBlocks(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedBlockStore) GetBlockByCode(ctx context.Context, a1 string) (r0 *types.RoomBlock, err error) {
+	ctx, done := s.observer.Observe(ctx, "Block", "GetBlockByCode")
+	r0, err = s.next.GetBlockByCode(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedBlockStore) ReleaseDueBlocks(ctx context.Context, a1 time.Time) (r0 int64, err error) {
+	ctx, done := s.observer.Observe(ctx, "Block", "ReleaseDueBlocks")
+	r0, err = s.next.ReleaseDueBlocks(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+type instrumentedItineraryStore struct {
+	next     ItineraryStore
+	observer Observer
+}
+
+func (s *instrumentedItineraryStore) CreateItinerary(ctx context.Context, a1 *types.Itinerary, a2 []*types.Booking) (r0 *types.Itinerary, err error) {
+	ctx, done := s.observer.Observe(ctx, "Itinerary", "CreateItinerary")
+	r0, err = s.next.CreateItinerary(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedItineraryStore) GetItineraryByID(ctx context.Context, a1 primitive.ObjectID) (r0 *types.Itinerary, err error) {
+	ctx, done := s.observer.Observe(ctx, "Itinerary", "GetItineraryByID")
+	r0, err = s.next.GetItineraryByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedItineraryStore) UpdateItinerary(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Itinerary", "UpdateItinerary")
+	err = s.next.UpdateItinerary(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+type instrumentedRestrictionStore struct {
+	next     RestrictionStore
+	observer Observer
+}
+
+func (s *instrumentedRestrictionStore) CreateRestriction(ctx context.Context, a1 *types.StayRestriction) (r0 *types.StayRestriction, err error) {
+	ctx, done := s.observer.Observe(ctx, "Restriction", "CreateRestriction")
+	r0, err = s.next.CreateRestriction(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedRestrictionStore) GetRestrictions(ctx context.Context, a1 bson.M) (r0 []*types.StayRestriction, err error) {
+	ctx, done := s.observer.Observe(ctx, "Restriction", "GetRestrictions")
+	r0, err = s.next.GetRestrictions(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedRestrictionStore) DeleteRestrictionByID(ctx context.Context, a1 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Restriction", "DeleteRestrictionByID")
+	err = s.next.DeleteRestrictionByID(ctx, a1)
+	done(err)
+	return err
+}
+
+type instrumentedOverbookingStore struct {
+	next     OverbookingStore
+	observer Observer
+}
+
+func (s *instrumentedOverbookingStore) CreateAllowance(ctx context.Context, a1 *types.OverbookingAllowance) (r0 *types.OverbookingAllowance, err error) {
+	ctx, done := s.observer.Observe(ctx, "Overbooking", "CreateAllowance")
+	r0, err = s.next.CreateAllowance(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedOverbookingStore) GetAllowances(ctx context.Context, a1 bson.M) (r0 []*types.OverbookingAllowance, err error) {
+	ctx, done := s.observer.Observe(ctx, "Overbooking", "GetAllowances")
+	r0, err = s.next.GetAllowances(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedOverbookingStore) DeleteAllowanceByID(ctx context.Context, a1 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Overbooking", "DeleteAllowanceByID")
+	err = s.next.DeleteAllowanceByID(ctx, a1)
+	done(err)
+	return err
+}
+
+type instrumentedReportStore struct {
+	next     ReportStore
+	observer Observer
+}
+
+func (s *instrumentedReportStore) CountRooms(ctx context.Context, a1 primitive.ObjectID) (r0 int, err error) {
+	ctx, done := s.observer.Observe(ctx, "Report", "CountRooms")
+	r0, err = s.next.CountRooms(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedReportStore) GetSales(ctx context.Context, a1 primitive.ObjectID, a2 time.Time, a3 time.Time, a4 types.Granularity) (r0 []types.PeriodSales, err error) {
+	ctx, done := s.observer.Observe(ctx, "Report", "GetSales")
+	r0, err = s.next.GetSales(ctx, a1, a2, a3, a4)
+	done(err)
+	return r0, err
+}
+
+type instrumentedCalendarStore struct {
+	next     CalendarStore
+	observer Observer
+}
+
+func (s *instrumentedCalendarStore) SetFeedToken(ctx context.Context, a1 primitive.ObjectID, a2 string) (r0 *types.CalendarFeed, err error) {
+	ctx, done := s.observer.Observe(ctx, "Calendar", "SetFeedToken")
+	r0, err = s.next.SetFeedToken(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedCalendarStore) GetFeedByToken(ctx context.Context, a1 string) (r0 *types.CalendarFeed, err error) {
+	ctx, done := s.observer.Observe(ctx, "Calendar", "GetFeedByToken")
+	r0, err = s.next.GetFeedByToken(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedCalendarStore) CreateImport(ctx context.Context, a1 *types.CalendarImport) (r0 *types.CalendarImport, err error) {
+	ctx, done := s.observer.Observe(ctx, "Calendar", "CreateImport")
+	r0, err = s.next.CreateImport(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedCalendarStore) GetImports(ctx context.Context, a1 bson.M) (r0 []*types.CalendarImport, err error) {
+	ctx, done := s.observer.Observe(ctx, "Calendar", "GetImports")
+	r0, err = s.next.GetImports(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedCalendarStore) GetImportByID(ctx context.Context, a1 primitive.ObjectID) (r0 *types.CalendarImport, err error) {
+	ctx, done := s.observer.Observe(ctx, "Calendar", "GetImportByID")
+	r0, err = s.next.GetImportByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedCalendarStore) UpdateImport(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Calendar", "UpdateImport")
+	err = s.next.UpdateImport(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+func (s *instrumentedCalendarStore) DeleteImportByID(ctx context.Context, a1 primitive.ObjectID) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Calendar", "DeleteImportByID")
+	err = s.next.DeleteImportByID(ctx, a1)
+	done(err)
+	return err
+}
+
+type instrumentedChannelStore struct {
+	next     ChannelStore
+	observer Observer
+}
+
+func (s *instrumentedChannelStore) CreateMapping(ctx context.Context, a1 *types.ChannelMapping) (r0 *types.ChannelMapping, err error) {
+	ctx, done := s.observer.Observe(ctx, "Channel", "CreateMapping")
+	r0, err = s.next.CreateMapping(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedChannelStore) GetMappings(ctx context.Context, a1 bson.M) (r0 []*types.ChannelMapping, err error) {
+	ctx, done := s.observer.Observe(ctx, "Channel", "GetMappings")
+	r0, err = s.next.GetMappings(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedChannelStore) DeleteMappingByID(ctx context.Context, a1 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Channel", "DeleteMappingByID")
+	err = s.next.DeleteMappingByID(ctx, a1)
+	done(err)
+	return err
+}
+
+func (s *instrumentedChannelStore) EnqueueMessage(ctx context.Context, a1 *types.ChannelMessage) (r0 *types.ChannelMessage, err error) {
+	ctx, done := s.observer.Observe(ctx, "Channel", "EnqueueMessage")
+	r0, err = s.next.EnqueueMessage(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedChannelStore) GetMessages(ctx context.Context, a1 bson.M) (r0 []*types.ChannelMessage, err error) {
+	ctx, done := s.observer.Observe(ctx, "Channel", "GetMessages")
+	r0, err = s.next.GetMessages(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedChannelStore) GetDueMessages(ctx context.Context, a1 time.Time, a2 int64) (r0 []*types.ChannelMessage, err error) {
+	ctx, done := s.observer.Observe(ctx, "Channel", "GetDueMessages")
+	r0, err = s.next.GetDueMessages(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedChannelStore) UpdateMessage(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Channel", "UpdateMessage")
+	err = s.next.UpdateMessage(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+type instrumentedWebhookStore struct {
+	next     WebhookStore
+	observer Observer
+}
+
+func (s *instrumentedWebhookStore) CreateSubscription(ctx context.Context, a1 *types.WebhookSubscription) (r0 *types.WebhookSubscription, err error) {
+	ctx, done := s.observer.Observe(ctx, "Webhook", "CreateSubscription")
+	r0, err = s.next.CreateSubscription(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedWebhookStore) GetSubscriptions(ctx context.Context, a1 bson.M) (r0 []*types.WebhookSubscription, err error) {
+	ctx, done := s.observer.Observe(ctx, "Webhook", "GetSubscriptions")
+	r0, err = s.next.GetSubscriptions(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedWebhookStore) DeleteSubscriptionByID(ctx context.Context, a1 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Webhook", "DeleteSubscriptionByID")
+	err = s.next.DeleteSubscriptionByID(ctx, a1)
+	done(err)
+	return err
+}
+
+func (s *instrumentedWebhookStore) EnqueueDelivery(ctx context.Context, a1 *types.WebhookDelivery) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Webhook", "EnqueueDelivery")
+	err = s.next.EnqueueDelivery(ctx, a1)
+	done(err)
+	return err
+}
+
+func (s *instrumentedWebhookStore) GetDeliveries(ctx context.Context, a1 bson.M) (r0 []*types.WebhookDelivery, err error) {
+	ctx, done := s.observer.Observe(ctx, "Webhook", "GetDeliveries")
+	r0, err = s.next.GetDeliveries(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedWebhookStore) GetDeliveryByID(ctx context.Context, a1 string) (r0 *types.WebhookDelivery, err error) {
+	ctx, done := s.observer.Observe(ctx, "Webhook", "GetDeliveryByID")
+	r0, err = s.next.GetDeliveryByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedWebhookStore) GetDueDeliveries(ctx context.Context, a1 time.Time, a2 int64) (r0 []*types.WebhookDelivery, err error) {
+	ctx, done := s.observer.Observe(ctx, "Webhook", "GetDueDeliveries")
+	r0, err = s.next.GetDueDeliveries(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedWebhookStore) UpdateDelivery(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Webhook", "UpdateDelivery")
+	err = s.next.UpdateDelivery(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+type instrumentedEventStore struct {
+	next     EventStore
+	observer Observer
+}
+
+func (s *instrumentedEventStore) GetEventByID(ctx context.Context, a1 primitive.ObjectID) (r0 *types.Event, err error) {
+	ctx, done := s.observer.Observe(ctx, "Event", "GetEventByID")
+	r0, err = s.next.GetEventByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedEventStore) GetPendingEvents(ctx context.Context, a1 time.Time, a2 int64) (r0 []*types.Event, err error) {
+	ctx, done := s.observer.Observe(ctx, "Event", "GetPendingEvents")
+	r0, err = s.next.GetPendingEvents(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedEventStore) UpdateEvent(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Event", "UpdateEvent")
+	err = s.next.UpdateEvent(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+type instrumentedNotificationStore struct {
+	next     NotificationStore
+	observer Observer
+}
+
+func (s *instrumentedNotificationStore) EnsurePreferences(ctx context.Context, a1 *types.NotificationPreferences) (r0 *types.NotificationPreferences, err error) {
+	ctx, done := s.observer.Observe(ctx, "Notification", "EnsurePreferences")
+	r0, err = s.next.EnsurePreferences(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedNotificationStore) GetPreferencesByToken(ctx context.Context, a1 string) (r0 *types.NotificationPreferences, err error) {
+	ctx, done := s.observer.Observe(ctx, "Notification", "GetPreferencesByToken")
+	r0, err = s.next.GetPreferencesByToken(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedNotificationStore) UpdatePreferences(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Notification", "UpdatePreferences")
+	err = s.next.UpdatePreferences(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+func (s *instrumentedNotificationStore) EnqueueNotification(ctx context.Context, a1 *types.Notification) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Notification", "EnqueueNotification")
+	err = s.next.EnqueueNotification(ctx, a1)
+	done(err)
+	return err
+}
+
+func (s *instrumentedNotificationStore) GetNotifications(ctx context.Context, a1 bson.M) (r0 []*types.Notification, err error) {
+	ctx, done := s.observer.Observe(ctx, "Notification", "GetNotifications")
+	r0, err = s.next.GetNotifications(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedNotificationStore) GetDueNotifications(ctx context.Context, a1 time.Time, a2 int64) (r0 []*types.Notification, err error) {
+	ctx, done := s.observer.Observe(ctx, "Notification", "GetDueNotifications")
+	r0, err = s.next.GetDueNotifications(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedNotificationStore) UpdateNotification(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Notification", "UpdateNotification")
+	err = s.next.UpdateNotification(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+type instrumentedReviewStore struct {
+	next     ReviewStore
+	observer Observer
+}
+
+func (s *instrumentedReviewStore) CreateReview(ctx context.Context, a1 *types.Review) (r0 *types.Review, err error) {
+	ctx, done := s.observer.Observe(ctx, "Review", "CreateReview")
+	r0, err = s.next.CreateReview(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedReviewStore) GetReviews(ctx context.Context, a1 bson.M) (r0 []*types.Review, err error) {
+	ctx, done := s.observer.Observe(ctx, "Review", "GetReviews")
+	r0, err = s.next.GetReviews(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedReviewStore) GetReviewByID(ctx context.Context, a1 string) (r0 *types.Review, err error) {
+	ctx, done := s.observer.Observe(ctx, "Review", "GetReviewByID")
+	r0, err = s.next.GetReviewByID(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedReviewStore) UpdateReview(ctx context.Context, a1 primitive.ObjectID, a2 bson.M) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Review", "UpdateReview")
+	err = s.next.UpdateReview(ctx, a1, a2)
+	done(err)
+	return err
+}
+
+func (s *instrumentedReviewStore) UpdateHotelRating(ctx context.Context, a1 primitive.ObjectID) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Review", "UpdateHotelRating")
+	err = s.next.UpdateHotelRating(ctx, a1)
+	done(err)
+	return err
+}
+
+type instrumentedAttemptStore struct {
+	next     AttemptStore
+	observer Observer
+}
+
+func (s *instrumentedAttemptStore) Incr(ctx context.Context, a1 string, a2 time.Duration) (r0 int, r1 time.Time, err error) {
+	ctx, done := s.observer.Observe(ctx, "Attempt", "Incr")
+	r0, r1, err = s.next.Incr(ctx, a1, a2)
+	done(err)
+	return r0, r1, err
+}
+
+func (s *instrumentedAttemptStore) Count(ctx context.Context, a1 string) (r0 int, r1 time.Time, err error) {
+	ctx, done := s.observer.Observe(ctx, "Attempt", "Count")
+	r0, r1, err = s.next.Count(ctx, a1)
+	done(err)
+	return r0, r1, err
+}
+
+func (s *instrumentedAttemptStore) Reset(ctx context.Context, a1 string) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Attempt", "Reset")
+	err = s.next.Reset(ctx, a1)
+	done(err)
+	return err
+}
+
+type instrumentedAuditStore struct {
+	next     AuditStore
+	observer Observer
+}
+
+func (s *instrumentedAuditStore) AddRecord(ctx context.Context, a1 *types.AuditRecord) (r0 *types.AuditRecord, err error) {
+	ctx, done := s.observer.Observe(ctx, "Audit", "AddRecord")
+	r0, err = s.next.AddRecord(ctx, a1)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedAuditStore) GetRecords(ctx context.Context, a1 bson.M, a2 int64) (r0 []*types.AuditRecord, err error) {
+	ctx, done := s.observer.Observe(ctx, "Audit", "GetRecords")
+	r0, err = s.next.GetRecords(ctx, a1, a2)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedAuditStore) VerifyChain(ctx context.Context) (r0 *types.AuditVerification, err error) {
+	ctx, done := s.observer.Observe(ctx, "Audit", "VerifyChain")
+	r0, err = s.next.VerifyChain(ctx)
+	done(err)
+	return r0, err
+}
+
+type instrumentedSettingsStore struct {
+	next     SettingsStore
+	observer Observer
+}
+
+func (s *instrumentedSettingsStore) GetSecurityPolicy(ctx context.Context) (r0 *types.SecurityPolicy, err error) {
+	ctx, done := s.observer.Observe(ctx, "Settings", "GetSecurityPolicy")
+	r0, err = s.next.GetSecurityPolicy(ctx)
+	done(err)
+	return r0, err
+}
+
+func (s *instrumentedSettingsStore) UpdateSecurityPolicy(ctx context.Context, a1 *types.SecurityPolicy) (err error) {
+	ctx, done := s.observer.Observe(ctx, "Settings", "UpdateSecurityPolicy")
+	err = s.next.UpdateSecurityPolicy(ctx, a1)
+	done(err)
+	return err
+}
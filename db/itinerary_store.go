@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ItineraryStore interface {
+	CreateItinerary(context.Context, *types.Itinerary, []*types.Booking) (*types.Itinerary, error)
+	GetItineraryByID(context.Context, primitive.ObjectID) (*types.Itinerary, error)
+	UpdateItinerary(context.Context, primitive.ObjectID, bson.M) error
+}
+
+type MongoItineraryStore struct {
+	client   *mongo.Client
+	coll     *mongo.Collection
+	bookings *mongo.Collection
+	outbox   *mongo.Collection
+}
+
+func NewMongoItineraryStore(client *mongo.Client, dbname string) *MongoItineraryStore {
+	return &MongoItineraryStore{
+		client:   client,
+		coll:     client.Database(dbname).Collection(ITINERARY_COLLECTION),
+		bookings: client.Database(dbname).Collection(BOOKING_COLLECTION),
+		outbox:   client.Database(dbname).Collection(OUTBOX_COLLECTION),
+	}
+}
+
+// CreateItinerary inserts the itinerary and all of its bookings. Either every
+// booking is stored or none is.
+func (s *MongoItineraryStore) CreateItinerary(ctx context.Context, itinerary *types.Itinerary, bookings []*types.Booking) (*types.Itinerary, error) {
+	itinerary.ID = primitive.NewObjectID()
+	itinerary.BookingIDs = make([]primitive.ObjectID, len(bookings))
+	for i, booking := range bookings {
+		booking.ID = primitive.NewObjectID()
+		booking.ItineraryID = itinerary.ID
+		itinerary.BookingIDs[i] = booking.ID
+	}
+
+	err := withTransaction(ctx, s.client, func(ctx context.Context) error {
+		for _, booking := range bookings {
+			if _, err := s.bookings.InsertOne(ctx, booking); err != nil {
+				return err
+			}
+			if err := writeEvent(ctx, s.outbox, types.EventBookingCreated, booking.ID, booking); err != nil {
+				return err
+			}
+		}
+		_, err := s.coll.InsertOne(ctx, itinerary)
+		return err
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "itinerary rolled back", "itineraryID", itinerary.ID.Hex(), "err", err)
+		s.bookings.DeleteMany(ctx, bson.M{"itineraryID": itinerary.ID})
+		s.outbox.DeleteMany(ctx, bson.M{"subjectID": bson.M{"$in": itinerary.BookingIDs}})
+		return nil, err
+	}
+
+	return itinerary, nil
+}
+
+func (s *MongoItineraryStore) GetItineraryByID(ctx context.Context, oid primitive.ObjectID) (*types.Itinerary, error) {
+	var itinerary types.Itinerary
+	if err := s.coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&itinerary); err != nil {
+		return nil, err
+	}
+
+	return &itinerary, nil
+}
+
+func (s *MongoItineraryStore) UpdateItinerary(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
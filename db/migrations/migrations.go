@@ -0,0 +1,202 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All is the schema history of the service. Append new migrations with the
+// next version; never edit or renumber one that has shipped.
+var All = []Migration{
+	{
+		Version:     1,
+		Description: "unique user email",
+		Up: createIndex(db.USERS_COLLECTION, mongo.IndexModel{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName("email_unique").SetUnique(true),
+		}),
+		Down: dropIndex(db.USERS_COLLECTION, "email_unique"),
+	},
+	{
+		Version:     2,
+		Description: "booking overlap index",
+		Up: createIndex(db.BOOKING_COLLECTION, mongo.IndexModel{
+			Keys:    bson.D{{Key: "roomID", Value: 1}, {Key: "fromDate", Value: 1}, {Key: "tillDate", Value: 1}},
+			Options: options.Index().SetName("room_dates"),
+		}),
+		Down: dropIndex(db.BOOKING_COLLECTION, "room_dates"),
+	},
+	{
+		Version:     3,
+		Description: "hotel geospatial index",
+		Up: createIndex(db.HOTEL_COLLECTION, mongo.IndexModel{
+			Keys:    bson.D{{Key: "geo", Value: "2dsphere"}},
+			Options: options.Index().SetName("geo_2dsphere"),
+		}),
+		Down: dropIndex(db.HOTEL_COLLECTION, "geo_2dsphere"),
+	},
+	{
+		// a collection can only have one text index, so it covers every
+		// searchable field
+		Version:     4,
+		Description: "hotel text index",
+		Up: createIndex(db.HOTEL_COLLECTION, mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "name", Value: "text"},
+				{Key: "address.city", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "amenities", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("hotel_text").
+				SetWeights(bson.M{"name": 10, "address.city": 5, "amenities": 3, "description": 1}),
+		}),
+		Down: dropIndex(db.HOTEL_COLLECTION, "hotel_text"),
+	},
+	{
+		// login attempt counters are only needed until their window ends
+		Version:     5,
+		Description: "login attempt expiry",
+		Up: createIndex(db.ATTEMPT_COLLECTION, mongo.IndexModel{
+			Keys:    bson.D{{Key: "resetAt", Value: 1}},
+			Options: options.Index().SetName("attempt_expiry").SetExpireAfterSeconds(0),
+		}),
+		Down: dropIndex(db.ATTEMPT_COLLECTION, "attempt_expiry"),
+	},
+	{
+		// a unique sequence keeps the audit chain linear with concurrent
+		// writers. Records from before the chain have no seq, which the
+		// unique index would take for duplicates, so they are chained first.
+		Version:     6,
+		Description: "audit chain sequence",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			if err := chainAuditRecords(ctx, database); err != nil {
+				return err
+			}
+			return createIndex(db.AUDIT_COLLECTION, mongo.IndexModel{
+				Keys:    bson.D{{Key: "seq", Value: 1}},
+				Options: options.Index().SetName("audit_seq_unique").SetUnique(true),
+			})(ctx, database)
+		},
+		Down: dropIndex(db.AUDIT_COLLECTION, "audit_seq_unique"),
+	},
+	{
+		// one review per booking, also when two are sent at once. Reviews
+		// that slipped through before are removed but for the first.
+		Version:     7,
+		Description: "unique review per booking",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			if err := dropDuplicateReviews(ctx, database); err != nil {
+				return err
+			}
+			return createIndex(db.REVIEW_COLLECTION, mongo.IndexModel{
+				Keys:    bson.D{{Key: "bookingID", Value: 1}},
+				Options: options.Index().SetName("review_booking_unique").SetUnique(true),
+			})(ctx, database)
+		},
+		Down: dropIndex(db.REVIEW_COLLECTION, "review_booking_unique"),
+	},
+}
+
+// chainAuditRecords gives audit records written before the chain existed a
+// seq and hashes, in the order they were written. Records appended since are
+// chained again after them, as the chain could not be linear without the
+// index anyway.
+func chainAuditRecords(ctx context.Context, database *mongo.Database) error {
+	coll := database.Collection(db.AUDIT_COLLECTION)
+	legacy, err := coll.CountDocuments(ctx, bson.M{"seq": bson.M{"$exists": false}})
+	if err != nil || legacy == 0 {
+		return err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := coll.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	var prev types.AuditRecord
+	for cur.Next(ctx) {
+		var record types.AuditRecord
+		if err := cur.Decode(&record); err != nil {
+			return err
+		}
+		record.Seq = prev.Seq + 1
+		record.PrevHash = prev.Hash
+		record.Hash = record.ComputeHash()
+		_, err := coll.UpdateByID(ctx, record.ID, bson.M{"$set": bson.M{
+			"seq":      record.Seq,
+			"prevHash": record.PrevHash,
+			"hash":     record.Hash,
+		}})
+		if err != nil {
+			return err
+		}
+		prev = record
+	}
+
+	return cur.Err()
+}
+
+// dropDuplicateReviews keeps the first review of every booking and recomputes
+// the rating of the hotels that lost reviews.
+func dropDuplicateReviews(ctx context.Context, database *mongo.Database) error {
+	coll := database.Collection(db.REVIEW_COLLECTION)
+	cur, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$bookingID",
+			"hotelID": bson.M{"$first": "$hotelID"},
+			"ids":     bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var groups []struct {
+		HotelID primitive.ObjectID   `bson:"hotelID"`
+		IDs     []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return err
+	}
+	hotels := map[primitive.ObjectID]bool{}
+	for _, group := range groups {
+		if _, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}}); err != nil {
+			return err
+		}
+		hotels[group.HotelID] = true
+	}
+
+	reviews := db.NewMongoReviewStore(database.Client(), database.Name())
+	for hotelID := range hotels {
+		if err := reviews.UpdateHotelRating(ctx, hotelID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createIndex(collection string, index mongo.IndexModel) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, database *mongo.Database) error {
+		_, err := database.Collection(collection).Indexes().CreateOne(ctx, index)
+		return err
+	}
+}
+
+func dropIndex(collection, name string) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, database *mongo.Database) error {
+		_, err := database.Collection(collection).Indexes().DropOne(ctx, name)
+		return err
+	}
+}
@@ -0,0 +1,173 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is one versioned change to the database schema. Migrations are
+// applied in ascending version order and rolled back in reverse.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(context.Context, *mongo.Database) error
+	Down        func(context.Context, *mongo.Database) error
+}
+
+// Status tells whether a migration has been applied and when.
+type Status struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+}
+
+type record struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+// Migrator applies migrations to a database and tracks the applied versions
+// in its migrations collection.
+type Migrator struct {
+	database   *mongo.Database
+	coll       *mongo.Collection
+	migrations []Migration
+}
+
+func NewMigrator(database *mongo.Database, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		database:   database,
+		coll:       database.Collection(db.MIGRATION_COLLECTION),
+		migrations: sorted,
+	}
+}
+
+// Up applies every pending migration and returns the ones it applied. It
+// stops at the first failing migration, leaving the later ones pending.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := migration.Up(ctx, m.database); err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		rec := record{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+		}
+		// another instance applying the same migration concurrently already
+		// recorded it; migrations are idempotent so that is fine
+		if _, err := m.coll.InsertOne(ctx, rec); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns the ones it rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if err := migration.Down(ctx, m.database); err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		if _, err := m.coll.DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Status lists every known migration in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{
+			Version:     migration.Version,
+			Description: migration.Description,
+		}
+		if rec, ok := applied[migration.Version]; ok {
+			statuses[i].AppliedAt = &rec.AppliedAt
+		}
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]record, error) {
+	cur, err := m.coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []record
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := map[int]record{}
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) validate() error {
+	for i, migration := range m.migrations {
+		if migration.Version <= 0 {
+			return fmt.Errorf("migration %q: version should be positive", migration.Description)
+		}
+		if i > 0 && migration.Version == m.migrations[i-1].Version {
+			return fmt.Errorf("migration version %d is used twice", migration.Version)
+		}
+		if migration.Up == nil || migration.Down == nil {
+			return fmt.Errorf("migration %d: both up and down are required", migration.Version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/config"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.DB.URI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := client.Database("test-hotel-reservation")
+	defer database.Drop(ctx)
+
+	var ran []string
+	step := func(name string, err error) func(context.Context, *mongo.Database) error {
+		return func(context.Context, *mongo.Database) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+	var (
+		first  = Migration{Version: 1, Description: "first", Up: step("up1", nil), Down: step("down1", nil)}
+		second = Migration{Version: 2, Description: "second", Up: step("up2", nil), Down: step("down2", nil)}
+		broken = Migration{Version: 3, Description: "broken", Up: step("up3", errors.New("boom")), Down: step("down3", nil)}
+	)
+
+	// declared out of order, applied in version order
+	migrator := NewMigrator(database, []Migration{second, first})
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0].Version != 1 || applied[1].Version != 2 {
+		t.Fatalf("expected migrations 1 and 2 to be applied but got %+v", applied)
+	}
+	if applied, _ := migrator.Up(ctx); len(applied) != 0 {
+		t.Fatalf("expected nothing pending but applied %+v", applied)
+	}
+
+	migrator = NewMigrator(database, []Migration{first, second, broken})
+	if _, err := migrator.Up(ctx); err == nil {
+		t.Fatal("expected the failing migration to return an error")
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 3 || statuses[1].AppliedAt == nil || statuses[2].AppliedAt != nil {
+		t.Fatalf("expected 1 and 2 applied and 3 pending but got %+v", statuses)
+	}
+
+	rolledBack, err := migrator.Down(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rolledBack) != 1 || rolledBack[0].Version != 2 {
+		t.Fatalf("expected migration 2 to be rolled back but got %+v", rolledBack)
+	}
+	want := []string{"up1", "up2", "up3", "down2"}
+	if len(ran) != len(want) {
+		t.Fatalf("expected steps %v but ran %v", want, ran)
+	}
+	for i := range want {
+		if ran[i] != want[i] {
+			t.Fatalf("expected steps %v but ran %v", want, ran)
+		}
+	}
+
+	duplicate := NewMigrator(database, []Migration{first, {Version: 1, Description: "again", Up: first.Up, Down: first.Down}})
+	if _, err := duplicate.Up(ctx); err == nil {
+		t.Fatal("expected duplicate versions to be rejected")
+	}
+}
+
+func TestAllMigrationsAreValid(t *testing.T) {
+	if err := (&Migrator{migrations: All}).validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditChainMigrationChainsLegacyRecords(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.DB.URI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := client.Database("test-hotel-reservation")
+	defer database.Drop(ctx)
+
+	// records written before the chain have neither seq nor hashes
+	coll := database.Collection(db.AUDIT_COLLECTION)
+	for i, action := range []string{types.AuditUserCreate, types.AuditUserUpdate} {
+		_, err := coll.InsertOne(ctx, bson.M{
+			"action":    action,
+			"createdAt": time.Now().Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrator := NewMigrator(database, All)
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	store := db.NewMongoAuditStore(client, database.Name())
+	if _, err := store.AddRecord(ctx, &types.AuditRecord{Action: types.AuditUserDelete, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	verification, err := store.VerifyChain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Valid || verification.Checked != 3 {
+		t.Fatalf("expected the legacy records to be chained, got %+v", verification)
+	}
+}
+
+func TestReviewMigrationRecomputesRatings(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.DB.URI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := client.Database("test-hotel-reservation")
+	defer database.Drop(ctx)
+
+	// the rating was computed while the duplicate counted too
+	hotel := types.Hotel{ID: primitive.NewObjectID(), Name: "ibis", Rating: 4, ReviewCount: 2}
+	if _, err := database.Collection(db.HOTEL_COLLECTION).InsertOne(ctx, hotel); err != nil {
+		t.Fatal(err)
+	}
+	bookingID := primitive.NewObjectID()
+	for _, overall := range []float64{5, 3} {
+		_, err := database.Collection(db.REVIEW_COLLECTION).InsertOne(ctx, types.Review{
+			HotelID:   hotel.ID,
+			BookingID: bookingID,
+			Overall:   overall,
+			Status:    types.ReviewStatusApproved,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrator := NewMigrator(database, All)
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var updated types.Hotel
+	if err := database.Collection(db.HOTEL_COLLECTION).FindOne(ctx, bson.M{"_id": hotel.ID}).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Rating != 5 || updated.ReviewCount != 1 {
+		t.Fatalf("expected the rating of the remaining review, got %v from %d", updated.Rating, updated.ReviewCount)
+	}
+}
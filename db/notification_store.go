@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NotificationStore interface {
+	EnsurePreferences(context.Context, *types.NotificationPreferences) (*types.NotificationPreferences, error)
+	GetPreferencesByToken(context.Context, string) (*types.NotificationPreferences, error)
+	UpdatePreferences(context.Context, primitive.ObjectID, bson.M) error
+	EnqueueNotification(context.Context, *types.Notification) error
+	GetNotifications(context.Context, bson.M) ([]*types.Notification, error)
+	GetDueNotifications(context.Context, time.Time, int64) ([]*types.Notification, error)
+	UpdateNotification(context.Context, primitive.ObjectID, bson.M) error
+}
+
+type MongoNotificationStore struct {
+	client        *mongo.Client
+	preferences   *mongo.Collection
+	notifications *mongo.Collection
+}
+
+func NewMongoNotificationStore(client *mongo.Client, dbname string) *MongoNotificationStore {
+	return &MongoNotificationStore{
+		client:        client,
+		preferences:   client.Database(dbname).Collection(NOTIFICATION_PREFERENCES_COLLECTION),
+		notifications: client.Database(dbname).Collection(NOTIFICATION_COLLECTION),
+	}
+}
+
+// EnsurePreferences returns the preferences of defaults.UserID, storing
+// defaults first if the user has none yet.
+func (s *MongoNotificationStore) EnsurePreferences(ctx context.Context, defaults *types.NotificationPreferences) (*types.NotificationPreferences, error) {
+	var prefs types.NotificationPreferences
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"locale":       defaults.Locale,
+			"optedOut":     defaults.OptedOut,
+			"unsubscribed": defaults.Unsubscribed,
+			"token":        defaults.Token,
+		},
+	}
+	err := s.preferences.FindOneAndUpdate(ctx, bson.M{"userID": defaults.UserID}, update, opts).Decode(&prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+func (s *MongoNotificationStore) GetPreferencesByToken(ctx context.Context, token string) (*types.NotificationPreferences, error) {
+	var prefs types.NotificationPreferences
+	if err := s.preferences.FindOne(ctx, bson.M{"token": token}).Decode(&prefs); err != nil {
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+func (s *MongoNotificationStore) UpdatePreferences(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.preferences.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
+
+// EnqueueNotification stores a notification unless one with the same key
+// exists already.
+func (s *MongoNotificationStore) EnqueueNotification(ctx context.Context, notification *types.Notification) error {
+	opts := options.Update().SetUpsert(true)
+	_, err := s.notifications.UpdateOne(ctx, bson.M{"key": notification.Key}, bson.M{"$setOnInsert": notification}, opts)
+	return err
+}
+
+func (s *MongoNotificationStore) GetNotifications(ctx context.Context, filter bson.M) ([]*types.Notification, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(100)
+	cur, err := s.notifications.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []*types.Notification
+	if err := cur.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// GetDueNotifications returns pending notifications whose next attempt is
+// due, oldest first.
+func (s *MongoNotificationStore) GetDueNotifications(ctx context.Context, now time.Time, limit int64) ([]*types.Notification, error) {
+	filter := bson.M{
+		"status":        types.NotificationStatusPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}).SetLimit(limit)
+	cur, err := s.notifications.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []*types.Notification
+	if err := cur.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (s *MongoNotificationStore) UpdateNotification(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.notifications.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OverbookingStore interface {
+	CreateAllowance(context.Context, *types.OverbookingAllowance) (*types.OverbookingAllowance, error)
+	GetAllowances(context.Context, bson.M) ([]*types.OverbookingAllowance, error)
+	DeleteAllowanceByID(context.Context, string) error
+}
+
+type MongoOverbookingStore struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+}
+
+func NewMongoOverbookingStore(client *mongo.Client, dbname string) *MongoOverbookingStore {
+	return &MongoOverbookingStore{
+		client: client,
+		coll:   client.Database(dbname).Collection(OVERBOOKING_COLLECTION),
+	}
+}
+
+func (s *MongoOverbookingStore) CreateAllowance(ctx context.Context, allowance *types.OverbookingAllowance) (*types.OverbookingAllowance, error) {
+	res, err := s.coll.InsertOne(ctx, allowance)
+	if err != nil {
+		return nil, err
+	}
+	allowance.ID = res.InsertedID.(primitive.ObjectID)
+
+	return allowance, nil
+}
+
+func (s *MongoOverbookingStore) GetAllowances(ctx context.Context, filter bson.M) ([]*types.OverbookingAllowance, error) {
+	cur, err := s.coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowances []*types.OverbookingAllowance
+	if err := cur.All(ctx, &allowances); err != nil {
+		return nil, err
+	}
+
+	return allowances, nil
+}
+
+func (s *MongoOverbookingStore) DeleteAllowanceByID(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.coll.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReportStore interface {
+	CountRooms(context.Context, primitive.ObjectID) (int, error)
+	GetSales(ctx context.Context, hotelID primitive.ObjectID, from, till time.Time, granularity types.Granularity) ([]types.PeriodSales, error)
+}
+
+// MongoReportStore computes reports with aggregation pipelines over the
+// rooms and bookings collections.
+type MongoReportStore struct {
+	client   *mongo.Client
+	rooms    *mongo.Collection
+	bookings *mongo.Collection
+}
+
+func NewMongoReportStore(client *mongo.Client, dbname string) *MongoReportStore {
+	return &MongoReportStore{
+		client:   client,
+		rooms:    client.Database(dbname).Collection(ROOM_COLLECTION),
+		bookings: client.Database(dbname).Collection(BOOKING_COLLECTION),
+	}
+}
+
+func (s *MongoReportStore) CountRooms(ctx context.Context, hotelID primitive.ObjectID) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"hotelID": hotelID}}},
+		{{Key: "$count", Value: "rooms"}},
+	}
+	cur, err := s.rooms.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+
+	var res []struct {
+		Rooms int `bson:"rooms"`
+	}
+	if err := cur.All(ctx, &res); err != nil {
+		return 0, err
+	}
+	if len(res) == 0 {
+		return 0, nil
+	}
+
+	return res[0].Rooms, nil
+}
+
+// GetSales returns the room nights sold and revenue earned per period for the
+// nights in [from, till). Each booking is expanded into one document per
+// night so stays crossing a period boundary are split between periods, each
+// night carrying an equal share of the booking price.
+func (s *MongoReportStore) GetSales(ctx context.Context, hotelID primitive.ObjectID, from, till time.Time, granularity types.Granularity) ([]types.PeriodSales, error) {
+	from, till = types.Night(from), types.Night(till)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"canceled": bson.M{"$ne": true},
+			"walk":     bson.M{"$exists": false},
+			"fromDate": bson.M{"$lt": till},
+			"tillDate": bson.M{"$gt": from},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         ROOM_COLLECTION,
+			"localField":   "roomID",
+			"foreignField": "_id",
+			"as":           "room",
+		}}},
+		{{Key: "$unwind", Value: "$room"}},
+		{{Key: "$match", Value: bson.M{"room.hotelID": hotelID}}},
+		{{Key: "$addFields", Value: bson.M{
+			"arrival": bson.M{"$dateTrunc": bson.M{"date": "$fromDate", "unit": "day"}},
+			"nights": bson.M{"$max": bson.A{1, bson.M{"$dateDiff": bson.M{
+				"startDate": bson.M{"$dateTrunc": bson.M{"date": "$fromDate", "unit": "day"}},
+				"endDate":   bson.M{"$dateTrunc": bson.M{"date": "$tillDate", "unit": "day"}},
+				"unit":      "day",
+			}}}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"night":       bson.M{"$range": bson.A{0, "$nights"}},
+			"nightlyRate": bson.M{"$divide": bson.A{"$price", "$nights"}},
+		}}},
+		{{Key: "$unwind", Value: "$night"}},
+		{{Key: "$addFields", Value: bson.M{
+			"night": bson.M{"$dateAdd": bson.M{"startDate": "$arrival", "unit": "day", "amount": "$night"}},
+		}}},
+		{{Key: "$match", Value: bson.M{"night": bson.M{"$gte": from, "$lt": till}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateTrunc": bson.M{
+				"date":        "$night",
+				"unit":        string(granularity),
+				"startOfWeek": "monday",
+			}},
+			"soldNights": bson.M{"$sum": 1},
+			"revenue":    bson.M{"$sum": "$nightlyRate"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	cur, err := s.bookings.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	sales := []types.PeriodSales{}
+	if err := cur.All(ctx, &sales); err != nil {
+		return nil, err
+	}
+
+	return sales, nil
+}
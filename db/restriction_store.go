@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RestrictionStore interface {
+	CreateRestriction(context.Context, *types.StayRestriction) (*types.StayRestriction, error)
+	GetRestrictions(context.Context, bson.M) ([]*types.StayRestriction, error)
+	DeleteRestrictionByID(context.Context, string) error
+}
+
+type MongoRestrictionStore struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+}
+
+func NewMongoRestrictionStore(client *mongo.Client, dbname string) *MongoRestrictionStore {
+	return &MongoRestrictionStore{
+		client: client,
+		coll:   client.Database(dbname).Collection(RESTRICTION_COLLECTION),
+	}
+}
+
+func (s *MongoRestrictionStore) CreateRestriction(ctx context.Context, restriction *types.StayRestriction) (*types.StayRestriction, error) {
+	res, err := s.coll.InsertOne(ctx, restriction)
+	if err != nil {
+		return nil, err
+	}
+	restriction.ID = res.InsertedID.(primitive.ObjectID)
+
+	return restriction, nil
+}
+
+func (s *MongoRestrictionStore) GetRestrictions(ctx context.Context, filter bson.M) ([]*types.StayRestriction, error) {
+	cur, err := s.coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var restrictions []*types.StayRestriction
+	if err := cur.All(ctx, &restrictions); err != nil {
+		return nil, err
+	}
+
+	return restrictions, nil
+}
+
+func (s *MongoRestrictionStore) DeleteRestrictionByID(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.coll.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"math"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReviewStore interface {
+	CreateReview(context.Context, *types.Review) (*types.Review, error)
+	GetReviews(context.Context, bson.M) ([]*types.Review, error)
+	GetReviewByID(context.Context, string) (*types.Review, error)
+	UpdateReview(context.Context, primitive.ObjectID, bson.M) error
+	UpdateHotelRating(context.Context, primitive.ObjectID) error
+}
+
+type MongoReviewStore struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+	hotels *mongo.Collection
+}
+
+func NewMongoReviewStore(client *mongo.Client, dbname string) *MongoReviewStore {
+	return &MongoReviewStore{
+		client: client,
+		coll:   client.Database(dbname).Collection(REVIEW_COLLECTION),
+		hotels: client.Database(dbname).Collection(HOTEL_COLLECTION),
+	}
+}
+
+func (s *MongoReviewStore) CreateReview(ctx context.Context, review *types.Review) (*types.Review, error) {
+	res, err := s.coll.InsertOne(ctx, review)
+	if err != nil {
+		return nil, err
+	}
+	review.ID = res.InsertedID.(primitive.ObjectID)
+
+	return review, nil
+}
+
+func (s *MongoReviewStore) GetReviews(ctx context.Context, filter bson.M) ([]*types.Review, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cur, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var reviews []*types.Review
+	if err := cur.All(ctx, &reviews); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+func (s *MongoReviewStore) GetReviewByID(ctx context.Context, id string) (*types.Review, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var review types.Review
+	if err := s.coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&review); err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+func (s *MongoReviewStore) UpdateReview(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
+
+// UpdateHotelRating recomputes the hotel's rating and review count from its
+// approved reviews. The rating is rounded to one decimal.
+func (s *MongoReviewStore) UpdateHotelRating(ctx context.Context, hotelID primitive.ObjectID) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"hotelID": hotelID, "status": types.ReviewStatusApproved}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"average": bson.M{"$avg": "$overall"},
+			"count":   bson.M{"$sum": 1},
+		}}},
+	}
+	cur, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	var results []struct {
+		Average float64 `bson:"average"`
+		Count   int     `bson:"count"`
+	}
+	if err := cur.All(ctx, &results); err != nil {
+		return err
+	}
+
+	rating, count := 0.0, 0
+	if len(results) > 0 {
+		rating = math.Round(results[0].Average*10) / 10
+		count = results[0].Count
+	}
+
+	_, err = s.hotels.UpdateOne(ctx, bson.M{"_id": hotelID}, bson.M{"$set": bson.M{"rating": rating, "reviewCount": count}})
+	return err
+}
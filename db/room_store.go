@@ -24,17 +24,10 @@ type MongoRoomStore struct {
 	HotelStore
 }
 
-func NewMongoRoomStore(client *mongo.Client, hotelStore HotelStore, isTest bool) *MongoRoomStore {
-	if isTest {
-		return &MongoRoomStore{
-			client:     client,
-			coll:       client.Database(TestDBNAME).Collection(ROOM_COLLECTION),
-			HotelStore: hotelStore,
-		}
-	}
+func NewMongoRoomStore(client *mongo.Client, hotelStore HotelStore, dbname string) *MongoRoomStore {
 	return &MongoRoomStore{
 		client:     client,
-		coll:       client.Database(DBNAME).Collection(ROOM_COLLECTION),
+		coll:       client.Database(dbname).Collection(ROOM_COLLECTION),
 		HotelStore: hotelStore,
 	}
 }
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// securityPolicyID is the settings document of the security policy.
+const securityPolicyID = "security"
+
+type SettingsStore interface {
+	// GetSecurityPolicy returns the zero policy until one is saved.
+	GetSecurityPolicy(context.Context) (*types.SecurityPolicy, error)
+	UpdateSecurityPolicy(context.Context, *types.SecurityPolicy) error
+}
+
+type MongoSettingsStore struct {
+	coll *mongo.Collection
+}
+
+func NewMongoSettingsStore(client *mongo.Client, dbname string) *MongoSettingsStore {
+	return &MongoSettingsStore{
+		coll: client.Database(dbname).Collection(SETTINGS_COLLECTION),
+	}
+}
+
+func (s *MongoSettingsStore) GetSecurityPolicy(ctx context.Context) (*types.SecurityPolicy, error) {
+	var policy types.SecurityPolicy
+	err := s.coll.FindOne(ctx, bson.M{"_id": securityPolicyID}).Decode(&policy)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &policy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+func (s *MongoSettingsStore) UpdateSecurityPolicy(ctx context.Context, policy *types.SecurityPolicy) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := s.coll.ReplaceOne(ctx, bson.M{"_id": securityPolicyID}, policy, opts)
+	return err
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errCodeIllegalOperation is returned by standalone servers, such as the one
+// started by `make run_db`, when a transaction is requested.
+const errCodeIllegalOperation = 20
+
+// withTransaction runs fn inside a multi-document transaction. On servers that
+// do not support transactions fn runs without one, so callers must undo
+// partial writes themselves when it fails.
+func withTransaction(ctx context.Context, client *mongo.Client, fn func(context.Context) error) error {
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(errCodeIllegalOperation) {
+		slog.DebugContext(ctx, "transactions unsupported, writing without one")
+		return fn(ctx)
+	}
+
+	return err
+}
@@ -2,7 +2,8 @@ package db
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/aboronilov/go-hotel-reservation/types"
 	"go.mongodb.org/mongo-driver/bson"
@@ -23,23 +24,30 @@ type UserStore interface {
 	CreateUser(context.Context, *types.User) (*types.User, error)
 	DeleteUserByID(context.Context, string) error
 	UpdateUserByID(ctx context.Context, filter bson.M, params types.UpdateUserParams) error
+	LockUser(ctx context.Context, id primitive.ObjectID, until time.Time, unlockToken string) error
+	UnlockUser(ctx context.Context, unlockToken string) (*types.User, error)
+	// SetTwoFactor replaces the two-factor settings of a user; an empty
+	// secret turns two-factor authentication off.
+	SetTwoFactor(ctx context.Context, id primitive.ObjectID, secret string, enabled bool, recoveryCodes []string) error
+	// UseTOTPStep records that a code of step was used and reports false
+	// when a code of that or a later step was used already.
+	UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	// UseRecoveryCode removes a recovery code hash and reports whether the
+	// user had it.
+	UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error)
 }
 
 type MongoUserStore struct {
 	client *mongo.Client
 	coll   *mongo.Collection
+	outbox *mongo.Collection
 }
 
-func NewMongoUserStore(client *mongo.Client, isTest bool) *MongoUserStore {
-	if isTest {
-		return &MongoUserStore{
-			client: client,
-			coll:   client.Database(TestDBNAME).Collection(USERS_COLLECTION),
-		}
-	}
+func NewMongoUserStore(client *mongo.Client, dbname string) *MongoUserStore {
 	return &MongoUserStore{
 		client: client,
-		coll:   client.Database(DBNAME).Collection(USERS_COLLECTION),
+		coll:   client.Database(dbname).Collection(USERS_COLLECTION),
+		outbox: client.Database(dbname).Collection(OUTBOX_COLLECTION),
 	}
 }
 
@@ -87,11 +95,18 @@ func (s *MongoUserStore) GetUsers(ctx context.Context) ([]*types.User, error) {
 }
 
 func (s *MongoUserStore) CreateUser(ctx context.Context, user *types.User) (*types.User, error) {
-	res, err := s.coll.InsertOne(ctx, user)
+	user.ID = primitive.NewObjectID()
+	err := withTransaction(ctx, s.client, func(ctx context.Context) error {
+		if _, err := s.coll.InsertOne(ctx, user); err != nil {
+			return err
+		}
+		return writeEvent(ctx, s.outbox, types.EventUserCreated, user.ID, user)
+	})
 	if err != nil {
+		slog.WarnContext(ctx, "user creation rolled back", "userID", user.ID.Hex(), "err", err)
+		s.coll.DeleteOne(ctx, bson.M{"_id": user.ID})
 		return nil, err
 	}
-	user.ID = res.InsertedID.(primitive.ObjectID)
 
 	// fmt.Println("User created ---- >", user)
 
@@ -123,7 +138,70 @@ func (s *MongoUserStore) UpdateUserByID(ctx context.Context, filter bson.M, para
 	return nil
 }
 
+func (s *MongoUserStore) LockUser(ctx context.Context, id primitive.ObjectID, until time.Time, unlockToken string) error {
+	update := bson.M{
+		"$set": bson.M{"lockedUntil": until, "unlockToken": unlockToken},
+	}
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// UnlockUser unlocks the account the unlock token was issued for and returns
+// it as it was before.
+func (s *MongoUserStore) UnlockUser(ctx context.Context, unlockToken string) (*types.User, error) {
+	var user types.User
+	update := bson.M{
+		"$unset": bson.M{"lockedUntil": "", "unlockToken": ""},
+	}
+	if err := s.coll.FindOneAndUpdate(ctx, bson.M{"unlockToken": unlockToken}, update).Decode(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *MongoUserStore) SetTwoFactor(ctx context.Context, id primitive.ObjectID, secret string, enabled bool, recoveryCodes []string) error {
+	update := bson.M{
+		"$set": bson.M{"twoFactorEnabled": enabled, "totpSecret": secret, "recoveryCodes": recoveryCodes},
+	}
+	if len(secret) == 0 {
+		update = bson.M{
+			"$set":   bson.M{"twoFactorEnabled": false},
+			"$unset": bson.M{"totpSecret": "", "recoveryCodes": ""},
+		}
+	}
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (s *MongoUserStore) UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	// matching on the last step makes checking and recording it one atomic
+	// operation, so concurrent logins cannot both use a code
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"totpLastStep": bson.M{"$lt": step}},
+			bson.M{"totpLastStep": bson.M{"$exists": false}},
+		},
+	}
+	res, err := s.coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"totpLastStep": step}})
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount > 0, nil
+}
+
+func (s *MongoUserStore) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error) {
+	res, err := s.coll.UpdateOne(ctx, bson.M{"_id": id, "recoveryCodes": hash}, bson.M{"$pull": bson.M{"recoveryCodes": hash}})
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount > 0, nil
+}
+
 func (s *MongoUserStore) Drop(ctx context.Context) error {
-	fmt.Println("--- Dropping user collection")
+	slog.InfoContext(ctx, "dropping user collection")
 	return s.coll.Drop(ctx)
 }
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WaitlistStore interface {
+	CreateEntry(context.Context, *types.WaitlistEntry) (*types.WaitlistEntry, error)
+	GetEntries(context.Context, bson.M) ([]*types.WaitlistEntry, error)
+	GetEntryByID(context.Context, primitive.ObjectID) (*types.WaitlistEntry, error)
+	UpdateEntry(context.Context, primitive.ObjectID, bson.M) error
+}
+
+type MongoWaitlistStore struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+}
+
+func NewMongoWaitlistStore(client *mongo.Client, dbname string) *MongoWaitlistStore {
+	return &MongoWaitlistStore{
+		client: client,
+		coll:   client.Database(dbname).Collection(WAITLIST_COLLECTION),
+	}
+}
+
+func (s *MongoWaitlistStore) CreateEntry(ctx context.Context, entry *types.WaitlistEntry) (*types.WaitlistEntry, error) {
+	res, err := s.coll.InsertOne(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	entry.ID = res.InsertedID.(primitive.ObjectID)
+
+	return entry, nil
+}
+
+// GetEntries returns matching entries oldest first, which is the order
+// waitlisted guests are served in.
+func (s *MongoWaitlistStore) GetEntries(ctx context.Context, filter bson.M) ([]*types.WaitlistEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*types.WaitlistEntry
+	if err := cur.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *MongoWaitlistStore) GetEntryByID(ctx context.Context, oid primitive.ObjectID) (*types.WaitlistEntry, error) {
+	var entry types.WaitlistEntry
+	if err := s.coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (s *MongoWaitlistStore) UpdateEntry(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
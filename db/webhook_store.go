@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookStore interface {
+	CreateSubscription(context.Context, *types.WebhookSubscription) (*types.WebhookSubscription, error)
+	GetSubscriptions(context.Context, bson.M) ([]*types.WebhookSubscription, error)
+	DeleteSubscriptionByID(context.Context, string) error
+	EnqueueDelivery(context.Context, *types.WebhookDelivery) error
+	GetDeliveries(context.Context, bson.M) ([]*types.WebhookDelivery, error)
+	GetDeliveryByID(context.Context, string) (*types.WebhookDelivery, error)
+	GetDueDeliveries(context.Context, time.Time, int64) ([]*types.WebhookDelivery, error)
+	UpdateDelivery(context.Context, primitive.ObjectID, bson.M) error
+}
+
+type MongoWebhookStore struct {
+	client        *mongo.Client
+	subscriptions *mongo.Collection
+	deliveries    *mongo.Collection
+}
+
+func NewMongoWebhookStore(client *mongo.Client, dbname string) *MongoWebhookStore {
+	return &MongoWebhookStore{
+		client:        client,
+		subscriptions: client.Database(dbname).Collection(WEBHOOK_COLLECTION),
+		deliveries:    client.Database(dbname).Collection(WEBHOOK_DELIVERY_COLLECTION),
+	}
+}
+
+func (s *MongoWebhookStore) CreateSubscription(ctx context.Context, sub *types.WebhookSubscription) (*types.WebhookSubscription, error) {
+	res, err := s.subscriptions.InsertOne(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	sub.ID = res.InsertedID.(primitive.ObjectID)
+
+	return sub, nil
+}
+
+func (s *MongoWebhookStore) GetSubscriptions(ctx context.Context, filter bson.M) ([]*types.WebhookSubscription, error) {
+	cur, err := s.subscriptions.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*types.WebhookSubscription
+	if err := cur.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+func (s *MongoWebhookStore) DeleteSubscriptionByID(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.subscriptions.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
+
+// EnqueueDelivery stores a delivery unless one already exists for the same
+// event and subscription, so fanning out an event twice is harmless.
+func (s *MongoWebhookStore) EnqueueDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	filter := bson.M{
+		"eventID":        delivery.EventID,
+		"subscriptionID": delivery.SubscriptionID,
+	}
+	opts := options.Update().SetUpsert(true)
+	_, err := s.deliveries.UpdateOne(ctx, filter, bson.M{"$setOnInsert": delivery}, opts)
+	return err
+}
+
+func (s *MongoWebhookStore) GetDeliveries(ctx context.Context, filter bson.M) ([]*types.WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(100)
+	cur, err := s.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []*types.WebhookDelivery
+	if err := cur.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *MongoWebhookStore) GetDeliveryByID(ctx context.Context, id string) (*types.WebhookDelivery, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery types.WebhookDelivery
+	if err := s.deliveries.FindOne(ctx, bson.M{"_id": oid}).Decode(&delivery); err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// GetDueDeliveries returns pending deliveries whose next attempt is due,
+// oldest first.
+func (s *MongoWebhookStore) GetDueDeliveries(ctx context.Context, now time.Time, limit int64) ([]*types.WebhookDelivery, error) {
+	filter := bson.M{
+		"status":        types.DeliveryStatusPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}).SetLimit(limit)
+	cur, err := s.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []*types.WebhookDelivery
+	if err := cur.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *MongoWebhookStore) UpdateDelivery(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := s.deliveries.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	return err
+}
@@ -0,0 +1,90 @@
+// Package events carries domain events from the outbox to whoever reacts to
+// them. Stores record events in the same transaction as the change, the Relay
+// publishes them through a Broker, and subscribers register on the Broker.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+// Handler reacts to an event. Events are delivered at least once: a handler
+// sees an event again only when it failed, or when the relay stopped between
+// delivering and recording it, so handlers must still tolerate duplicates.
+type Handler func(context.Context, *types.Event) error
+
+// Broker publishes events to subscribers. LocalBroker delivers in-process; an
+// implementation backed by a message broker can be swapped in without
+// touching the stores or the subscribers.
+type Broker interface {
+	// Subscribe registers handler under name for the given event types, or
+	// for every event when none are given.
+	Subscribe(name string, handler Handler, eventTypes ...string)
+	// Publish returns once every interested subscriber has accepted the
+	// event. Subscribers named in the event's Delivered are skipped, and
+	// those that accept it are added. An error means the event must be
+	// published again.
+	Publish(context.Context, *types.Event) error
+}
+
+type subscription struct {
+	name       string
+	handler    Handler
+	eventTypes map[string]bool
+}
+
+func (s subscription) wants(eventType string) bool {
+	return len(s.eventTypes) == 0 || s.eventTypes[eventType]
+}
+
+// LocalBroker calls subscribers synchronously, in the order they subscribed.
+type LocalBroker struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+}
+
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{}
+}
+
+func (b *LocalBroker) Subscribe(name string, handler Handler, eventTypes ...string) {
+	sub := subscription{
+		name:       name,
+		handler:    handler,
+		eventTypes: map[string]bool{},
+	}
+	for _, eventType := range eventTypes {
+		sub.eventTypes[eventType] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions = append(b.subscriptions, sub)
+}
+
+// Publish runs every interested subscriber that has not had the event yet,
+// even after one of them fails, and reports all failures together.
+func (b *LocalBroker) Publish(ctx context.Context, event *types.Event) error {
+	b.mu.RLock()
+	subs := append([]subscription(nil), b.subscriptions...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if !sub.wants(event.Type) || slices.Contains(event.Delivered, sub.name) {
+			continue
+		}
+		if err := sub.handler(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.name, err))
+			continue
+		}
+		event.Delivered = append(event.Delivered, sub.name)
+	}
+
+	return errors.Join(errs...)
+}
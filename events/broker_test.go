@@ -0,0 +1,94 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+func TestLocalBrokerRoutesByEventType(t *testing.T) {
+	var (
+		broker   = NewLocalBroker()
+		bookings []string
+		all      []string
+	)
+	broker.Subscribe("bookings", func(ctx context.Context, event *types.Event) error {
+		bookings = append(bookings, event.Type)
+		return nil
+	}, types.EventBookingCreated, types.EventBookingCanceled)
+	broker.Subscribe("all", func(ctx context.Context, event *types.Event) error {
+		all = append(all, event.Type)
+		return nil
+	})
+
+	for _, eventType := range []string{types.EventBookingCreated, types.EventUserCreated, types.EventBookingCanceled} {
+		if err := broker.Publish(context.TODO(), &types.Event{Type: eventType}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(bookings) != 2 || bookings[0] != types.EventBookingCreated || bookings[1] != types.EventBookingCanceled {
+		t.Fatalf("expected only booking events, got %v", bookings)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected a subscriber without event types to see every event, got %v", all)
+	}
+}
+
+func TestLocalBrokerRunsEverySubscriberOnFailure(t *testing.T) {
+	var (
+		broker = NewLocalBroker()
+		called bool
+		failed = errors.New("smtp down")
+	)
+	broker.Subscribe("email", func(ctx context.Context, event *types.Event) error {
+		return failed
+	})
+	broker.Subscribe("webhooks", func(ctx context.Context, event *types.Event) error {
+		called = true
+		return nil
+	})
+
+	err := broker.Publish(context.TODO(), &types.Event{Type: types.EventUserCreated})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected the subscriber error to be reported, got %v", err)
+	}
+	if !called {
+		t.Fatal("expected the second subscriber to run despite the first failing")
+	}
+}
+
+func TestLocalBrokerSkipsSubscribersThatHadTheEvent(t *testing.T) {
+	var (
+		broker = NewLocalBroker()
+		event  = &types.Event{Type: types.EventUserCreated}
+		emails int
+		fail   = true
+	)
+	broker.Subscribe("email", func(ctx context.Context, event *types.Event) error {
+		emails++
+		return nil
+	})
+	broker.Subscribe("webhooks", func(ctx context.Context, event *types.Event) error {
+		if fail {
+			return errors.New("receiver down")
+		}
+		return nil
+	})
+
+	if err := broker.Publish(context.TODO(), event); err == nil {
+		t.Fatal("expected the failing subscriber to be reported")
+	}
+	fail = false
+	if err := broker.Publish(context.TODO(), event); err != nil {
+		t.Fatal(err)
+	}
+	if emails != 1 {
+		t.Fatalf("expected the retry to skip the subscriber that accepted the event, got %d calls", emails)
+	}
+	if len(event.Delivered) != 2 {
+		t.Fatalf("expected both subscribers to be recorded, got %v", event.Delivered)
+	}
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/retry"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// MaxAttempts is how often an event is published before it is marked
+	// dead, so one that no subscriber can handle does not retry forever.
+	MaxAttempts = 10
+
+	batchSize   = 100
+	baseBackoff = 5 * time.Second
+	maxBackoff  = 10 * time.Minute
+)
+
+// Relay moves events from the outbox to a Broker. An event is marked
+// dispatched only after the broker accepted it; otherwise it is published
+// again after a backoff to the subscribers that did not accept it yet, which
+// is what makes delivery at-least-once. After MaxAttempts failures the event
+// is marked dead and kept in the outbox.
+type Relay struct {
+	store  *db.Store
+	broker Broker
+}
+
+func NewRelay(store *db.Store, broker Broker) *Relay {
+	return &Relay{
+		store:  store,
+		broker: broker,
+	}
+}
+
+// Run publishes the pending events that are due. It is meant to be run by the
+// scheduler.
+func (r *Relay) Run(ctx context.Context) error {
+	events, err := r.store.Event.GetPendingEvents(ctx, time.Now(), batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		attempts := event.Attempts + 1
+		err := r.broker.Publish(ctx, event)
+		update := bson.M{"attempts": attempts, "delivered": event.Delivered}
+		if err != nil {
+			update["nextAttemptAt"] = time.Now().Add(retry.Backoff(attempts, baseBackoff, maxBackoff))
+			update["lastError"] = err.Error()
+			if attempts >= MaxAttempts {
+				update["dead"] = true
+				slog.WarnContext(ctx, "event relay gave up on event", "event", event.ID.Hex(), "type", event.Type, "attempts", attempts, "err", err)
+			}
+		} else {
+			update["dispatched"] = true
+			update["lastError"] = ""
+		}
+		if err := r.store.Event.UpdateEvent(ctx, event.ID, update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
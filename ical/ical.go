@@ -0,0 +1,175 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	maxLineOctets  = 75
+)
+
+// Event is the subset of a VEVENT needed to sync room availability.
+type Event struct {
+	UID     string
+	Start   time.Time
+	End     time.Time
+	Summary string
+	Status  string
+}
+
+// IsCanceled reports whether the calendar marked the event as canceled.
+func (e Event) IsCanceled() bool {
+	return strings.EqualFold(e.Status, "CANCELLED")
+}
+
+// Parse reads the VEVENTs of an iCalendar stream. Events without a UID or a
+// start date are skipped, and an event without an end lasts one day.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events  []Event
+		current *Event
+	)
+	for _, line := range lines {
+		name, params, value, ok := splitLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &Event{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current != nil && len(current.UID) > 0 && !current.Start.IsZero() {
+				if current.End.IsZero() {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+				events = append(events, *current)
+			}
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescape(value)
+		case name == "STATUS":
+			current.Status = value
+		case name == "DTSTART":
+			if current.Start, err = parseTime(params, value); err != nil {
+				return nil, fmt.Errorf("event %s: %w", current.UID, err)
+			}
+		case name == "DTEND":
+			if current.End, err = parseTime(params, value); err != nil {
+				return nil, fmt.Errorf("event %s: %w", current.UID, err)
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// Encode writes events as an iCalendar stream of all-day events.
+func Encode(w io.Writer, name string, events []Event) error {
+	bw := bufio.NewWriter(w)
+	write := func(line string) {
+		for len(line) > maxLineOctets {
+			n := maxLineOctets
+			for !utf8.RuneStart(line[n]) {
+				n--
+			}
+			bw.WriteString(line[:n] + "\r\n")
+			line = " " + line[n:]
+		}
+		bw.WriteString(line + "\r\n")
+	}
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//go-hotel-reservation//EN")
+	write("CALSCALE:GREGORIAN")
+	write("X-WR-CALNAME:" + escape(name))
+	stamp := time.Now().UTC().Format(dateTimeLayout) + "Z"
+	for _, event := range events {
+		write("BEGIN:VEVENT")
+		write("UID:" + event.UID)
+		write("DTSTAMP:" + stamp)
+		write("DTSTART;VALUE=DATE:" + event.Start.UTC().Format(dateLayout))
+		write("DTEND;VALUE=DATE:" + event.End.UTC().Format(dateLayout))
+		write("SUMMARY:" + escape(event.Summary))
+		write("END:VEVENT")
+	}
+	write("END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// unfold joins continuation lines, which start with a space or a tab, onto
+// the line before them.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitLine splits "NAME;PARAM=X:VALUE" into its parts.
+func splitLine(line string) (string, map[string]string, string, bool) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", nil, "", false
+	}
+	parts := strings.Split(line[:i], ";")
+	params := map[string]string{}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(k)] = v
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[i+1:], true
+}
+
+func parseTime(params map[string]string, value string) (time.Time, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		return time.Parse(dateLayout, value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(dateTimeLayout, strings.TrimSuffix(value, "Z"))
+	}
+	if tzid, ok := params["TZID"]; ok {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			return time.ParseInLocation(dateTimeLayout, value, loc)
+		}
+	}
+	return time.Parse(dateTimeLayout, value)
+}
+
+var (
+	escaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
@@ -0,0 +1,69 @@
+package ical
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/external.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	events, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+
+	first := events[0]
+	if first.UID != "res-1@example.com" || first.Summary != "Reserved, guest Smith" {
+		t.Fatalf("unexpected first event %+v", first)
+	}
+	if !first.Start.Equal(time.Date(2030, time.January, 5, 0, 0, 0, 0, time.UTC)) || !first.End.Equal(time.Date(2030, time.January, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected first event dates %s - %s", first.Start, first.End)
+	}
+	if !events[1].IsCanceled() || events[1].Start.Hour() != 14 {
+		t.Fatalf("expected second event to be a canceled timed event, got %+v", events[1])
+	}
+	if !events[2].End.Equal(events[2].Start.AddDate(0, 0, 1)) {
+		t.Fatalf("expected event without end to last a day, got %+v", events[2])
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	events := []Event{{
+		UID:     "booking-1",
+		Start:   time.Date(2030, time.May, 1, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2030, time.May, 4, 0, 0, 0, 0, time.UTC),
+		Summary: strings.Repeat("Booked; ", 20),
+	}}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, "Room 1", events); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Fatalf("expected lines to be folded, got %d octets", len(line))
+		}
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 1 || parsed[0].UID != "booking-1" || parsed[0].Summary != events[0].Summary {
+		t.Fatalf("expected event to survive a round trip, got %+v", parsed)
+	}
+	if !parsed[0].Start.Equal(events[0].Start) || !parsed[0].End.Equal(events[0].End) {
+		t.Fatalf("expected dates to survive a round trip, got %+v", parsed[0])
+	}
+}
@@ -0,0 +1,285 @@
+package ical
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/availability"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/metrics"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const fetchTimeout = 30 * time.Second
+
+// SyncResult counts the blocking bookings changed by one sync. Conflicts
+// lists the events that were not booked or moved because the room is taken
+// on their dates; they are tried again on the next sync.
+type SyncResult struct {
+	Created   int             `json:"created"`
+	Updated   int             `json:"updated"`
+	Canceled  int             `json:"canceled"`
+	Conflicts []EventConflict `json:"conflicts,omitempty"`
+}
+
+type EventConflict struct {
+	UID      string    `json:"uid"`
+	FromDate time.Time `json:"fromDate"`
+	TillDate time.Time `json:"tillDate"`
+}
+
+// ErrSource is returned for sources the importer may not read.
+var ErrSource = errors.New("calendar source should be an http(s) URL or a file in the calendar directory")
+
+// Importer mirrors external calendars as blocking bookings. Sources are
+// http(s) URLs or, when dir is set, paths of files inside dir, optionally
+// prefixed with file://.
+type Importer struct {
+	store    *db.Store
+	checker  *availability.Checker
+	waitlist *waitlist.Manager
+	client   *http.Client
+	dir      string
+}
+
+func NewImporter(store *db.Store, waitlist *waitlist.Manager, dir string) *Importer {
+	return &Importer{
+		store:    store,
+		checker:  availability.NewChecker(store),
+		waitlist: waitlist,
+		client:   &http.Client{Timeout: fetchTimeout},
+		dir:      dir,
+	}
+}
+
+// SyncAll syncs every configured import. A failing source does not stop the
+// others; its error is recorded on the import and returned at the end.
+func (i *Importer) SyncAll(ctx context.Context) error {
+	imports, err := i.store.Calendar.GetImports(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, imp := range imports {
+		if _, err := i.Sync(ctx, imp); err != nil {
+			errs = append(errs, fmt.Errorf("calendar import %s: %w", imp.ID.Hex(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Sync reads the import's source and brings its blocking bookings in line:
+// new events are booked, moved events are updated, and upcoming bookings
+// whose event was removed or canceled are canceled.
+func (i *Importer) Sync(ctx context.Context, imp *types.CalendarImport) (*SyncResult, error) {
+	result, err := i.sync(ctx, imp)
+	update := bson.M{"lastSyncedAt": time.Now(), "lastError": ""}
+	if err != nil {
+		update = bson.M{"lastError": err.Error()}
+	}
+	if err := i.store.Calendar.UpdateImport(ctx, imp.ID, update); err != nil {
+		return nil, err
+	}
+
+	return result, err
+}
+
+func (i *Importer) sync(ctx context.Context, imp *types.CalendarImport) (*SyncResult, error) {
+	r, err := i.open(ctx, imp.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	events, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bookings, err := i.store.Booking.GetBookings(ctx, bson.M{
+		"importID": imp.ID,
+		"canceled": bson.M{"$ne": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]*types.Booking{}
+	for _, booking := range bookings {
+		existing[booking.ExternalUID] = booking
+	}
+
+	var (
+		result = &SyncResult{}
+		now    = time.Now()
+		seen   = map[string]bool{}
+	)
+	for _, event := range events {
+		if event.IsCanceled() {
+			continue
+		}
+		seen[event.UID] = true
+
+		if booking, ok := existing[event.UID]; ok {
+			if booking.FromDate.Equal(event.Start) && booking.TillDate.Equal(event.End) {
+				continue
+			}
+			free, err := i.isFree(ctx, imp, event, booking.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !free {
+				result.conflict(imp, event)
+				continue
+			}
+			update := bson.M{"fromDate": event.Start, "tillDate": event.End}
+			if err := i.store.Booking.UpdateBooking(ctx, booking.ID, update); err != nil {
+				return nil, err
+			}
+			result.Updated++
+			continue
+		}
+		if event.End.Before(now) {
+			continue
+		}
+
+		free, err := i.isFree(ctx, imp, event, primitive.NilObjectID)
+		if err != nil {
+			return nil, err
+		}
+		if !free {
+			result.conflict(imp, event)
+			continue
+		}
+
+		booking := &types.Booking{
+			RoomID:      imp.RoomID,
+			ImportID:    imp.ID,
+			ExternalUID: event.UID,
+			FromDate:    event.Start,
+			TillDate:    event.End,
+		}
+		if _, err := i.store.Booking.BookRoom(ctx, booking); err != nil {
+			return nil, err
+		}
+		result.Created++
+	}
+
+	for uid, booking := range existing {
+		if seen[uid] || booking.TillDate.Before(now) {
+			continue
+		}
+		if err := i.Cancel(ctx, booking); err != nil {
+			return nil, err
+		}
+		result.Canceled++
+	}
+
+	return result, nil
+}
+
+// Cancel cancels a booking of an import and offers the freed room to the
+// waitlist.
+func (i *Importer) Cancel(ctx context.Context, booking *types.Booking) error {
+	canceled, err := i.store.Booking.CancelBooking(ctx, booking.ID)
+	if err != nil || !canceled {
+		return err
+	}
+
+	return i.waitlist.Release(ctx, booking.RoomID)
+}
+
+// isFree reports whether the room of the import is free for the event.
+// bookingID is the booking of the event when it is being moved.
+func (i *Importer) isFree(ctx context.Context, imp *types.CalendarImport, event Event, bookingID primitive.ObjectID) (bool, error) {
+	return i.checker.IsRoomAvailable(ctx, availability.Request{
+		RoomID:    imp.RoomID,
+		BookingID: bookingID,
+		FromDate:  event.Start,
+		TillDate:  event.End,
+	})
+}
+
+func (r *SyncResult) conflict(imp *types.CalendarImport, event Event) {
+	slog.Warn("calendar event conflicts with the room's bookings", "importID", imp.ID.Hex(), "uid", event.UID, "from", event.Start, "till", event.End)
+	metrics.AvailabilityConflicts.WithLabelValues(metrics.ConflictUnavailable).Inc()
+	r.Conflicts = append(r.Conflicts, EventConflict{UID: event.UID, FromDate: event.Start, TillDate: event.End})
+}
+
+// CheckSource returns ErrSource when the importer may not read source.
+func (i *Importer) CheckSource(source string) error {
+	if isURL(source) {
+		return nil
+	}
+	_, err := i.localPath(source)
+	return err
+}
+
+func (i *Importer) open(ctx context.Context, source string) (io.ReadCloser, error) {
+	if !isURL(source) {
+		path, err := i.localPath(source)
+		if err != nil {
+			return nil, err
+		}
+		return os.Open(path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: unexpected status %d", source, resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// localPath resolves a local source, relative to the calendar directory,
+// and makes sure it does not lead out of it, through symlinks neither.
+func (i *Importer) localPath(source string) (string, error) {
+	if len(i.dir) == 0 || (strings.Contains(source, "://") && !strings.HasPrefix(source, "file://")) {
+		return "", ErrSource
+	}
+	dir, err := filepath.Abs(i.dir)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Clean(strings.TrimPrefix(source, "file://"))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrSource
+	}
+
+	return path, nil
+}
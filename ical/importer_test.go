@@ -0,0 +1,41 @@
+package ical
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Symlink("/etc", filepath.Join(dir, "etc")); err != nil {
+		t.Fatal(err)
+	}
+
+	importer := NewImporter(nil, nil, dir)
+	for source, allowed := range map[string]bool{
+		"https://example.com/room.ics": true,
+		"http://example.com/room.ics":  true,
+		"room.ics":                     true,
+		filepath.Join(dir, "room.ics"): true,
+		"file://" + dir + "/room.ics":  true,
+		"/etc/passwd":                  false,
+		"file:///etc/passwd":           false,
+		"../room.ics":                  false,
+		"etc/passwd":                   false,
+		"ftp://example.com/room.ics":   false,
+	} {
+		err := importer.CheckSource(source)
+		if allowed && err != nil {
+			t.Fatalf("expected %s to be allowed, got %v", source, err)
+		}
+		if !allowed && !errors.Is(err, ErrSource) {
+			t.Fatalf("expected %s to be refused, got %v", source, err)
+		}
+	}
+
+	if err := NewImporter(nil, nil, "").CheckSource("room.ics"); !errors.Is(err, ErrSource) {
+		t.Fatalf("expected local files to be refused without a calendar directory, got %v", err)
+	}
+}
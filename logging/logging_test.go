@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestLoggerAddsRequestIDAndRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.With("jwtSecret", "s3cret").InfoContext(ctx, "login",
+		"password", "hunter2",
+		slog.Group("headers", "Authorization", "Bearer abc", "Accept", "*/*"),
+	)
+	logger.DebugContext(ctx, "hidden")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single json record but got %q: %v", buf.String(), err)
+	}
+	if record["requestID"] != "req-1" {
+		t.Fatalf("expected the request id but got %v", record["requestID"])
+	}
+	headers := record["headers"].(map[string]any)
+	if record["password"] != redacted || record["jwtSecret"] != redacted || headers["Authorization"] != redacted {
+		t.Fatalf("expected secrets to be redacted but got %v", record)
+	}
+	if headers["Accept"] != "*/*" {
+		t.Fatalf("expected other attributes to be kept but got %v", headers)
+	}
+}
+
+func TestNewRejectsBadSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Fatal("expected an unknown format to be rejected")
+	}
+	if _, err := New(&bytes.Buffer{}, "text", "loud"); err == nil {
+		t.Fatal("expected an unknown level to be rejected")
+	}
+}
@@ -4,48 +4,177 @@ import (
 	"context"
 	"flag"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/aboronilov/go-hotel-reservation/api"
+	"github.com/aboronilov/go-hotel-reservation/authguard"
+	"github.com/aboronilov/go-hotel-reservation/channel"
+	"github.com/aboronilov/go-hotel-reservation/config"
 	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/db/migrations"
+	"github.com/aboronilov/go-hotel-reservation/events"
+	"github.com/aboronilov/go-hotel-reservation/ical"
+	"github.com/aboronilov/go-hotel-reservation/logging"
+	"github.com/aboronilov/go-hotel-reservation/metrics"
+	"github.com/aboronilov/go-hotel-reservation/notifications"
+	"github.com/aboronilov/go-hotel-reservation/scheduler"
+	"github.com/aboronilov/go-hotel-reservation/search"
+	"github.com/aboronilov/go-hotel-reservation/storage"
+	"github.com/aboronilov/go-hotel-reservation/tracing"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/aboronilov/go-hotel-reservation/waitlist"
+	"github.com/aboronilov/go-hotel-reservation/webhook"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
-var config = fiber.Config{
+var fiberConfig = fiber.Config{
 	ErrorHandler: api.ErrorHandler,
 }
 
 func main() {
-	listenAddr := flag.String("listenAddr", ":5000", "The listen address of the server")
-	flag.Parse()
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
 
 	ctx := context.Background()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(db.DBURI))
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.DB.URI).SetMonitor(otelmongo.NewMonitor()))
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	applied, err := migrations.NewMigrator(client.Database(cfg.DB.Name), migrations.All).Up(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, migration := range applied {
+		slog.Info("applied migration", "version", migration.Version, "description", migration.Description)
+	}
+
 	// stores
-	hotelStore := db.NewMongoHotelStore(client, false)
-	roomStore := db.NewMongoRoomStore(client, hotelStore, false)
-	userStore := db.NewMongoUserStore(client, false)
-	bookingStore := db.NewMongoBookingStore(client, false)
+	hotelStore := db.NewMongoHotelStore(client, cfg.DB.Name)
+	roomStore := db.NewMongoRoomStore(client, hotelStore, cfg.DB.Name)
+	userStore := db.NewMongoUserStore(client, cfg.DB.Name)
+	bookingStore := db.NewMongoBookingStore(client, cfg.DB.Name)
+	waitlistStore := db.NewMongoWaitlistStore(client, cfg.DB.Name)
+	blockStore := db.NewMongoBlockStore(client, cfg.DB.Name)
+	itineraryStore := db.NewMongoItineraryStore(client, cfg.DB.Name)
+	restrictionStore := db.NewMongoRestrictionStore(client, cfg.DB.Name)
+	overbookingStore := db.NewMongoOverbookingStore(client, cfg.DB.Name)
+	reportStore := db.NewMongoReportStore(client, cfg.DB.Name)
+	calendarStore := db.NewMongoCalendarStore(client, cfg.DB.Name)
+	channelStore := db.NewMongoChannelStore(client, cfg.DB.Name)
+	webhookStore := db.NewMongoWebhookStore(client, cfg.DB.Name)
+	eventStore := db.NewMongoEventStore(client, cfg.DB.Name)
+	notificationStore := db.NewMongoNotificationStore(client, cfg.DB.Name)
+	reviewStore := db.NewMongoReviewStore(client, cfg.DB.Name)
+	attemptStore := db.NewMongoAttemptStore(client, cfg.DB.Name)
+	auditStore := db.NewMongoAuditStore(client, cfg.DB.Name)
+	settingsStore := db.NewMongoSettingsStore(client, cfg.DB.Name)
 	store := &db.Store{
-		User:    userStore,
-		Hotel:   hotelStore,
-		Room:    roomStore,
-		Booking: bookingStore,
+		User:         userStore,
+		Hotel:        hotelStore,
+		Room:         roomStore,
+		Booking:      bookingStore,
+		Waitlist:     waitlistStore,
+		Block:        blockStore,
+		Itinerary:    itineraryStore,
+		Restriction:  restrictionStore,
+		Overbooking:  overbookingStore,
+		Report:       reportStore,
+		Calendar:     calendarStore,
+		Channel:      channelStore,
+		Webhook:      webhookStore,
+		Event:        eventStore,
+		Notification: notificationStore,
+		Review:       reviewStore,
+		Attempt:      attemptStore,
+		Audit:        auditStore,
+		Settings:     settingsStore,
+	}
+	store = db.Instrument(db.Instrument(store, metrics.StoreObserver{}), tracing.StoreObserver{})
+	searcher := search.NewMongoSearcher(store.Hotel)
+	store.Hotel = searcher.Watch(store.Hotel)
+
+	// notifications
+	var notificationChannel notifications.Channel = notifications.NewFileChannel(cfg.Mail.Dir)
+	if len(cfg.Mail.SMTPAddr) > 0 {
+		notificationChannel = notifications.NewSMTPChannel(cfg.Mail.SMTPAddr, cfg.Mail.From, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword)
 	}
+	notifier := notifications.NewService(store, notificationChannel, cfg.Server.PublicURL)
+
+	// background jobs
+	waitlistManager := waitlist.NewManager(store, notifier, waitlist.DefaultHoldDuration)
+	jobs := scheduler.New(cfg.Scheduler.Intervals)
+	jobs.Every("waitlist-expire-holds", time.Minute, waitlistManager.ExpireHolds)
+	jobs.Every("room-blocks-release", time.Hour, func(ctx context.Context) error {
+		_, err := store.Block.ReleaseDueBlocks(ctx, time.Now())
+		return err
+	})
+	calendarImporter := ical.NewImporter(store, waitlistManager, cfg.Server.CalendarDir)
+	jobs.Every("calendar-import", 15*time.Minute, calendarImporter.SyncAll)
+	var adapters []channel.ChannelAdapter
+	if len(cfg.Channel.URL) > 0 {
+		adapters = append(adapters, channel.NewHTTPAdapter(cfg.Channel.Name, cfg.Channel.URL, cfg.Channel.APIKey))
+	}
+	channelManager := channel.NewManager(store, waitlistManager, adapters...)
+	jobs.Every("channel-outbox", time.Minute, channelManager.DispatchOutbox)
+	jobs.Every("channel-pull", 5*time.Minute, channelManager.PullReservations)
+	jobs.Every("channel-ari-refresh", time.Hour, channelManager.RefreshARI)
+
+	// domain events
+	broker := events.NewLocalBroker()
+	webhookDispatcher := webhook.NewDispatcher(store)
+	broker.Subscribe("webhooks", webhookDispatcher.HandleEvent, types.WebhookEvents...)
+	broker.Subscribe("notifications", notifier.HandleEvent, notifications.BookingEvents...)
+	broker.Subscribe("metrics", metrics.HandleEvent, types.EventBookingCreated, types.EventBookingCanceled)
+	broker.Subscribe("channels", channelManager.HandleEvent, channel.BookingEvents...)
+	jobs.Every("events-relay", 5*time.Second, events.NewRelay(store, broker).Run)
+	jobs.Every("webhook-deliver", 15*time.Second, webhookDispatcher.DeliverDue)
+	jobs.Every("notifications-schedule", time.Hour, notifier.QueueScheduled)
+	jobs.Every("notifications-deliver", 30*time.Second, notifier.DeliverDue)
+	if cfg.Scheduler.Enabled {
+		jobs.Start(ctx)
+	}
+
+	app := fiber.New(fiberConfig)
+	app.Use(api.RequestID, api.Tracing, api.AccessLog, api.Metrics)
+
+	// health
+	healthHandler := api.NewHealthHandler(client, jobs, cfg.Scheduler.Enabled)
+	app.Get("/healthz", healthHandler.HandleLiveness)
+	app.Get("/readyz", healthHandler.HandleReadiness)
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
-	app := fiber.New(config)
-	apiv1 := app.Group("/api/v1", api.JWTAuthentication(userStore))
+	apiv1 := app.Group("/api/v1", api.Traced("JWTAuthentication", api.JWTAuthentication(store.User, cfg.Auth)))
 	auth := app.Group("/api")
-	admin := apiv1.Group("/admin", api.AdminAuth)
+	admin := apiv1.Group("/admin", api.Traced("AdminAuth", api.AdminAuth), api.RequireTwoFactor(store.Settings), api.AuditAdmin(store.Audit))
 
 	// user
-	userHandler := api.NewUserHandler(userStore)
+	userHandler := api.NewUserHandler(store.User, store.Audit)
 	apiv1.Get("/user", userHandler.HandleListUsers)
 	apiv1.Get("/user/:id", userHandler.HandleGetUser)
 	apiv1.Post("/user", userHandler.HandleCreateUser)
@@ -53,27 +182,175 @@ func main() {
 	apiv1.Delete("/user/:id", userHandler.HandleDeleteUser)
 
 	// auth
-	authHandler := api.NewAuthHandler(userStore)
+	var loginCounter authguard.Counter = authguard.NewMemoryCounter()
+	if cfg.Auth.Limiter == "mongo" {
+		loginCounter = store.Attempt
+	}
+	loginGuard := authguard.NewGuard(store, loginCounter, notifier, cfg.Auth)
+	authHandler := api.NewAuthHandler(store.User, loginGuard, cfg.Auth)
 	auth.Post("/auth", authHandler.HandleAuthenticate)
+	auth.Get("/auth/unlock/:token", authHandler.HandleUnlock)
+	auth.Post("/auth/2fa", authHandler.HandleVerifyTwoFactor)
+
+	// two-factor authentication
+	twoFactorHandler := api.NewTwoFactorHandler(store, loginGuard, cfg.Auth)
+	apiv1.Post("/user/2fa/enroll", twoFactorHandler.HandleEnroll)
+	apiv1.Post("/user/2fa/confirm", twoFactorHandler.HandleConfirm)
+	apiv1.Post("/user/2fa/disable", twoFactorHandler.HandleDisable)
+	apiv1.Post("/user/2fa/recovery-codes", twoFactorHandler.HandleRegenerateRecoveryCodes)
 
 	// room
-	roomHandler := api.NewRoomHandler(store)
+	roomHandler := api.NewRoomHandler(store, waitlistManager)
 	apiv1.Post("/room/:id/book", roomHandler.HandleBookRoom)
 	apiv1.Get("/room", roomHandler.HandleListRooms)
 
 	// bookings
-	bookingHandler := api.NewBookingHandler(store)
+	bookingHandler := api.NewBookingHandler(store, waitlistManager)
 	apiv1.Get("/booking/:id", bookingHandler.HandleRetrieveBooking)
 	apiv1.Get("/booking/:id/cancel", bookingHandler.HandleCancelBooking)
 
+	// waitlist
+	waitlistHandler := api.NewWaitlistHandler(store, waitlistManager)
+	apiv1.Post("/waitlist", waitlistHandler.HandleCreateEntry)
+	apiv1.Get("/waitlist", waitlistHandler.HandleListEntries)
+	apiv1.Delete("/waitlist/:id", waitlistHandler.HandleWithdrawEntry)
+
+	// itineraries
+	itineraryHandler := api.NewItineraryHandler(store, waitlistManager)
+	apiv1.Post("/itinerary", itineraryHandler.HandleCreateItinerary)
+	apiv1.Get("/itinerary/:id", itineraryHandler.HandleRetrieveItinerary)
+	apiv1.Post("/itinerary/:id/pay", itineraryHandler.HandlePayItinerary)
+	apiv1.Get("/itinerary/:id/cancel", itineraryHandler.HandleCancelItinerary)
+	apiv1.Get("/itinerary/:id/booking/:bookingID/cancel", itineraryHandler.HandleCancelItineraryBooking)
+
+	// room blocks
+	blockHandler := api.NewBlockHandler(store, waitlistManager)
+	apiv1.Get("/block/:code", blockHandler.HandleRetrieveBlock)
+	apiv1.Post("/block/:code/book", blockHandler.HandleBookBlock)
+
 	// admin
 	admin.Get("/booking", bookingHandler.HandleListBookings)
+	admin.Get("/block", blockHandler.HandleListBlocks)
+	admin.Post("/block", blockHandler.HandleCreateBlock)
+
+	restrictionHandler := api.NewRestrictionHandler(store)
+	admin.Get("/restriction", restrictionHandler.HandleListRestrictions)
+	admin.Post("/restriction", restrictionHandler.HandleCreateRestriction)
+	admin.Delete("/restriction/:id", restrictionHandler.HandleDeleteRestriction)
+
+	overbookingHandler := api.NewOverbookingHandler(store, waitlistManager)
+	admin.Get("/overbooking", overbookingHandler.HandleListAllowances)
+	admin.Post("/overbooking", overbookingHandler.HandleCreateAllowance)
+	admin.Delete("/overbooking/:id", overbookingHandler.HandleDeleteAllowance)
+	admin.Get("/overbooking/report", overbookingHandler.HandleOversoldReport)
+	admin.Post("/booking/:id/walk", overbookingHandler.HandleWalkBooking)
+
+	auditHandler := api.NewAuditHandler(store.Audit)
+	admin.Get("/audit", auditHandler.HandleListRecords)
+	admin.Get("/audit/verify", auditHandler.HandleVerifyChain)
+
+	admin.Get("/security", twoFactorHandler.HandleGetPolicy)
+	admin.Put("/security", twoFactorHandler.HandleUpdatePolicy)
+	admin.Delete("/user/:id/2fa", twoFactorHandler.HandleResetUser)
+
+	reportHandler := api.NewReportHandler(store)
+	admin.Get("/reports/kpi", reportHandler.HandleKPIReport)
+
+	bulkHandler := api.NewBulkHandler(store)
+	admin.Post("/import/hotels", bulkHandler.HandleImportHotels)
+	admin.Post("/import/rooms", bulkHandler.HandleImportRooms)
+	admin.Get("/export/bookings", bulkHandler.HandleExportBookings)
+
+	// calendars
+	calendarHandler := api.NewCalendarHandler(store, calendarImporter)
+	auth.Get("/calendar/:token.ics", calendarHandler.HandleGetFeed)
+	admin.Post("/room/:id/calendar/feed", calendarHandler.HandleCreateFeed)
+	admin.Post("/room/:id/calendar/import", calendarHandler.HandleCreateImport)
+	admin.Get("/calendar/import", calendarHandler.HandleListImports)
+	admin.Post("/calendar/import/:id/sync", calendarHandler.HandleSyncImport)
+	admin.Delete("/calendar/import/:id", calendarHandler.HandleDeleteImport)
+
+	// channels
+	channelHandler := api.NewChannelHandler(store, channelManager)
+	admin.Get("/channel/mapping", channelHandler.HandleListMappings)
+	admin.Post("/channel/mapping", channelHandler.HandleCreateMapping)
+	admin.Delete("/channel/mapping/:id", channelHandler.HandleDeleteMapping)
+	admin.Get("/channel/outbox", channelHandler.HandleListOutbox)
+	admin.Post("/channel/ari/sync", channelHandler.HandleSyncARI)
+
+	// webhooks
+	webhookHandler := api.NewWebhookHandler(store, webhookDispatcher)
+	admin.Get("/webhook", webhookHandler.HandleListSubscriptions)
+	admin.Post("/webhook", webhookHandler.HandleCreateSubscription)
+	admin.Delete("/webhook/:id", webhookHandler.HandleDeleteSubscription)
+	admin.Get("/webhook/delivery", webhookHandler.HandleListDeliveries)
+	admin.Post("/webhook/delivery/:id/redeliver", webhookHandler.HandleRedeliver)
+
+	// notifications
+	notificationHandler := api.NewNotificationHandler(store)
+	auth.Get("/notifications/unsubscribe/:token", notificationHandler.HandleUnsubscribe)
+	apiv1.Get("/notifications", notificationHandler.HandleListOwnNotifications)
+	apiv1.Get("/notifications/preferences", notificationHandler.HandleGetPreferences)
+	apiv1.Put("/notifications/preferences", notificationHandler.HandleUpdatePreferences)
+	admin.Get("/notifications", notificationHandler.HandleListNotifications)
 
 	// hotel
-	hotelHandler := api.NewHotelHandler(store)
+	media := storage.NewLocalStorage(cfg.Server.MediaDir, "/media")
+	app.Static("/media", cfg.Server.MediaDir)
+	hotelHandler := api.NewHotelHandler(store, media)
+	searchHandler := api.NewSearchHandler(searcher)
 	apiv1.Get("/hotel", hotelHandler.HandleListHotels)
+	apiv1.Get("/hotel/search", searchHandler.HandleSearchHotels)
 	apiv1.Get("/hotel/:id/rooms", hotelHandler.HandleGetRooms)
 	apiv1.Get("/hotel/:id", hotelHandler.HandleRetrieveHotel)
+	admin.Put("/hotel/:id", hotelHandler.HandleUpdateHotel)
+	admin.Post("/hotel/:id/images", hotelHandler.HandleUploadImage)
+	admin.Put("/hotel/:id/images", hotelHandler.HandleReorderImages)
+	admin.Delete("/hotel/:id/images/:key", hotelHandler.HandleDeleteImage)
+
+	// reviews
+	reviewHandler := api.NewReviewHandler(store)
+	apiv1.Post("/review", reviewHandler.HandleCreateReview)
+	apiv1.Get("/hotel/:id/reviews", reviewHandler.HandleListHotelReviews)
+	admin.Get("/review", reviewHandler.HandleListReviews)
+	admin.Post("/review/:id/moderate", reviewHandler.HandleModerateReview)
+	admin.Post("/review/:id/reply", reviewHandler.HandleReplyReview)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- app.Listen(cfg.Server.ListenAddr)
+	}()
+
+	quit, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	app.Listen(*listenAddr)
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		slog.Error("server failed", "err", err)
+		exitCode = 1
+	case <-quit.Done():
+		slog.Info("shutting down", "drainTimeout", cfg.Server.ShutdownTimeout)
+		if err := app.ShutdownWithTimeout(cfg.Server.ShutdownTimeout); err != nil {
+			slog.Error("server shutdown failed", "err", err)
+			exitCode = 1
+		}
+	}
+
+	// the jobs may still use the database, so stop them before disconnecting
+	jobs.Stop()
+	disconnectCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := client.Disconnect(disconnectCtx); err != nil {
+		slog.Error("mongo disconnect failed", "err", err)
+		exitCode = 1
+	}
+	if err := shutdownTracing(disconnectCtx); err != nil {
+		slog.Error("flushing spans failed", "err", err)
+		exitCode = 1
+	}
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
 }
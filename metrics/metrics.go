@@ -0,0 +1,127 @@
+// Package metrics defines the Prometheus metrics of the service. They are
+// registered with the default registry, which main exposes at /metrics.
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const namespace = "hotel"
+
+// Reasons an availability check turns a booking down.
+const (
+	ConflictUnavailable = "unavailable"
+	ConflictRestricted  = "restricted"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	StoreDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_operation_duration_seconds",
+		Help:      "Latency of store calls by store, method and outcome (ok, not_found or error).",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"store", "method", "outcome"})
+
+	BookingsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_created_total",
+		Help:      "Bookings created by source: direct, ical or the channel name.",
+	}, []string{"source"})
+
+	BookingsCanceled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_canceled_total",
+		Help:      "Bookings canceled by source: direct, ical or the channel name.",
+	}, []string{"source"})
+
+	AvailabilityConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "availability_conflicts_total",
+		Help:      "Bookings turned down by the availability check, by reason.",
+	}, []string{"reason"})
+
+	FailedLogins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failed_logins_total",
+		Help:      "Failed login attempts by reason.",
+	}, []string{"reason"})
+)
+
+// StoreObserver records the latency of store calls, which makes it a
+// db.Observer.
+type StoreObserver struct{}
+
+func (StoreObserver) Observe(ctx context.Context, store, method string) (context.Context, func(error)) {
+	start := time.Now()
+	return ctx, func(err error) {
+		StoreDuration.WithLabelValues(store, method, outcome(err)).Observe(time.Since(start).Seconds())
+	}
+}
+
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return "not_found"
+	default:
+		return "error"
+	}
+}
+
+// HandleEvent counts created and canceled bookings. Counting domain events
+// rather than handler calls covers bookings from every source, including
+// calendar imports and channel managers. The relay retries an event only for
+// the subscribers that failed it, so another subscriber failing does not
+// count a booking twice.
+func HandleEvent(ctx context.Context, event *types.Event) error {
+	var counter *prometheus.CounterVec
+	switch event.Type {
+	case types.EventBookingCreated:
+		counter = BookingsCreated
+	case types.EventBookingCanceled:
+		counter = BookingsCanceled
+	default:
+		return nil
+	}
+
+	var booking types.Booking
+	if err := json.Unmarshal(event.Data, &booking); err != nil {
+		return err
+	}
+	counter.WithLabelValues(source(&booking)).Inc()
+
+	return nil
+}
+
+func source(booking *types.Booking) string {
+	switch {
+	case len(booking.Channel) > 0:
+		return booking.Channel
+	case !booking.ImportID.IsZero():
+		return "ical"
+	default:
+		return "direct"
+	}
+}
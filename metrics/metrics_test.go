@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeHotelStore struct {
+	db.HotelStore
+}
+
+func (fakeHotelStore) GetHotelByID(ctx context.Context, id primitive.ObjectID) (*types.Hotel, error) {
+	if id.IsZero() {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &types.Hotel{ID: id}, nil
+}
+
+func TestStoreObserver(t *testing.T) {
+	store := db.Instrument(&db.Store{Hotel: fakeHotelStore{}}, StoreObserver{})
+
+	id := primitive.NewObjectID()
+	hotel, err := store.Hotel.GetHotelByID(context.Background(), id)
+	if err != nil || hotel.ID != id {
+		t.Fatalf("expected the call to be passed through but got %v, %v", hotel, err)
+	}
+	if _, err := store.Hotel.GetHotelByID(context.Background(), primitive.NilObjectID); err != mongo.ErrNoDocuments {
+		t.Fatalf("expected the store error to be returned but got %v", err)
+	}
+
+	for _, outcome := range []string{"ok", "not_found"} {
+		var m dto.Metric
+		if err := StoreDuration.WithLabelValues("Hotel", "GetHotelByID", outcome).(prometheus.Metric).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if m.GetHistogram().GetSampleCount() != 1 {
+			t.Fatalf("expected one %s call to be observed but got %d", outcome, m.GetHistogram().GetSampleCount())
+		}
+	}
+}
+
+func TestHandleEventCountsBySource(t *testing.T) {
+	bookings := []struct {
+		booking types.Booking
+		source  string
+	}{
+		{types.Booking{}, "direct"},
+		{types.Booking{ImportID: primitive.NewObjectID()}, "ical"},
+		{types.Booking{Channel: "booking.com"}, "booking.com"},
+	}
+	for _, b := range bookings {
+		data, err := json.Marshal(b.booking)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := testutil.ToFloat64(BookingsCanceled.WithLabelValues(b.source))
+		if err := HandleEvent(context.Background(), &types.Event{Type: types.EventBookingCanceled, Data: data}); err != nil {
+			t.Fatal(err)
+		}
+		if got := testutil.ToFloat64(BookingsCanceled.WithLabelValues(b.source)) - before; got != 1 {
+			t.Fatalf("expected one canceled %s booking to be counted but got %v", b.source, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a rendered notification ready to be sent.
+type Message struct {
+	ID             string
+	To             string
+	Subject        string
+	Body           string
+	UnsubscribeURL string
+}
+
+// Channel delivers messages to users. Send is retried by the Service, so a
+// channel only needs to report failures.
+type Channel interface {
+	Name() string
+	Send(context.Context, Message) error
+}
+
+// SMTPChannel sends messages as plain text email.
+type SMTPChannel struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPChannel sends through the server at addr (host:port). Without a
+// username the server is used without authentication.
+func NewSMTPChannel(addr, from, username, password string) *SMTPChannel {
+	var auth smtp.Auth
+	if len(username) > 0 {
+		host, _, _ := strings.Cut(addr, ":")
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPChannel{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}
+}
+
+func (c *SMTPChannel) Name() string {
+	return "smtp"
+}
+
+func (c *SMTPChannel) Send(_ context.Context, msg Message) error {
+	return smtp.SendMail(c.addr, c.auth, c.from, []string{msg.To}, formatEmail(c.from, msg))
+}
+
+// FileChannel writes each message as an .eml file into a directory, so emails
+// can be inspected during development without a mail server.
+type FileChannel struct {
+	dir string
+}
+
+func NewFileChannel(dir string) *FileChannel {
+	return &FileChannel{
+		dir: dir,
+	}
+}
+
+func (c *FileChannel) Name() string {
+	return "file"
+}
+
+func (c *FileChannel) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	name := filepath.Join(c.dir, msg.ID+".eml")
+	return os.WriteFile(name, formatEmail("noreply@localhost", msg), 0o644)
+}
+
+func formatEmail(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if len(msg.UnsubscribeURL) > 0 {
+		fmt.Fprintf(&b, "List-Unsubscribe: <%s>\r\n", msg.UnsubscribeURL)
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,322 @@
+// Package notifications renders and sends messages to guests: booking
+// confirmations, modifications and cancellations, pre-arrival reminders,
+// receipts and waitlist offers. Every message goes through the notification
+// log, which is also the retry queue.
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/random"
+	"github.com/aboronilov/go-hotel-reservation/retry"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	MaxAttempts = 5
+	// ReminderLead is how long before arrival the pre-arrival reminder goes
+	// out, and how long after departure a receipt is still sent.
+	ReminderLead = 48 * time.Hour
+
+	baseBackoff = time.Minute
+	maxBackoff  = time.Hour
+	batchSize   = 50
+	dateLayout  = "2006-01-02"
+	// redacted stands in for secrets in the logged copy of a message.
+	redacted = "REDACTED"
+)
+
+type Service struct {
+	store   *db.Store
+	channel Channel
+	baseURL string
+}
+
+// NewService sends through channel. baseURL is the public address of the API,
+// used to build unsubscribe links.
+func NewService(store *db.Store, channel Channel, baseURL string) *Service {
+	return &Service{
+		store:   store,
+		channel: channel,
+		baseURL: baseURL,
+	}
+}
+
+// BookingEvents are the events HandleEvent should be subscribed to.
+var BookingEvents = []string{
+	types.EventBookingCreated,
+	types.EventBookingModified,
+	types.EventBookingCanceled,
+}
+
+// HandleEvent queues the message matching a booking event. It is subscribed
+// to the event broker.
+func (s *Service) HandleEvent(ctx context.Context, event *types.Event) error {
+	var template string
+	switch event.Type {
+	case types.EventBookingCreated:
+		template = types.TemplateBookingConfirmation
+	case types.EventBookingModified:
+		template = types.TemplateBookingModification
+	case types.EventBookingCanceled:
+		template = types.TemplateBookingCancellation
+	default:
+		return nil
+	}
+
+	var booking types.Booking
+	if err := json.Unmarshal(event.Data, &booking); err != nil {
+		return err
+	}
+	if booking.UserID.IsZero() {
+		return nil
+	}
+
+	data, err := s.bookingData(ctx, &booking)
+	if err != nil {
+		return err
+	}
+
+	return s.queue(ctx, event.ID.Hex()+":"+template, booking.UserID, template, data)
+}
+
+// NotifyHold tells a waitlisted guest about their hold, which makes Service a
+// waitlist.Notifier.
+func (s *Service) NotifyHold(ctx context.Context, entry *types.WaitlistEntry) error {
+	data, err := s.roomData(ctx, entry.HoldRoomID)
+	if err != nil {
+		return err
+	}
+	data.FromDate = entry.FromDate.Format(dateLayout)
+	data.TillDate = entry.TillDate.Format(dateLayout)
+	data.NumPersons = entry.NumPersons
+	data.HoldExpiresAt = entry.HoldExpiresAt.UTC().Format("2006-01-02 15:04 MST")
+
+	key := fmt.Sprintf("hold:%s:%d", entry.ID.Hex(), entry.HoldExpiresAt.Unix())
+	return s.queue(ctx, key, entry.UserID, types.TemplateWaitlistHold, data)
+}
+
+// NotifyLockout sends the owner of a locked account the link to unlock it,
+// which makes Service an authguard.Notifier. The message is sent right away
+// and logged without the link: the unlock token is only stored hashed, so
+// reading the database is not enough to unlock accounts. A failed send is not
+// retried, the lock still runs out on its own.
+func (s *Service) NotifyLockout(ctx context.Context, user *types.User, unlockToken string) error {
+	data := TemplateData{
+		LockedUntil: user.LockedUntil.UTC().Format("2006-01-02 15:04 MST"),
+		UnlockURL:   s.unlockURL(unlockToken),
+	}
+	key := fmt.Sprintf("lockout:%s:%d", user.ID.Hex(), user.LockedUntil.Unix())
+	n, data, err := s.prepare(ctx, key, user.ID, types.TemplateAccountLocked, data)
+	if err != nil || n == nil {
+		return err
+	}
+
+	if n.Status == types.NotificationStatusPending {
+		n.Attempts = 1
+		err := s.channel.Send(ctx, Message{
+			ID:             n.ID.Hex(),
+			To:             n.Recipient,
+			Subject:        n.Subject,
+			Body:           n.Body,
+			UnsubscribeURL: n.Unsubscribe,
+		})
+		if err != nil {
+			n.Status = types.NotificationStatusFailed
+			n.LastError = err.Error()
+		} else {
+			n.Status = types.NotificationStatusSent
+			n.SentAt = time.Now().UTC()
+		}
+	}
+
+	data.UnlockURL = s.unlockURL(redacted)
+	if n.Subject, n.Body, err = Render(n.Locale, n.Template, data); err != nil {
+		return err
+	}
+	return s.store.Notification.EnqueueNotification(ctx, n)
+}
+
+func (s *Service) unlockURL(token string) string {
+	return fmt.Sprintf("%s/api/auth/unlock/%s", s.baseURL, token)
+}
+
+// QueueScheduled queues pre-arrival reminders for stays starting within
+// ReminderLead and receipts for stays that ended within ReminderLead. Each
+// booking gets at most one of each. It is meant to be run by the scheduler.
+func (s *Service) QueueScheduled(ctx context.Context) error {
+	now := time.Now()
+	scheduled := []struct {
+		template string
+		field    string
+		from     time.Time
+		till     time.Time
+	}{
+		{types.TemplatePreArrivalReminder, "fromDate", now, now.Add(ReminderLead)},
+		{types.TemplateReceipt, "tillDate", now.Add(-ReminderLead), now},
+	}
+
+	for _, sc := range scheduled {
+		bookings, err := s.store.Booking.GetBookings(ctx, bson.M{
+			sc.field:   bson.M{"$gte": sc.from, "$lte": sc.till},
+			"canceled": false,
+			"userID":   bson.M{"$exists": true},
+			"walk":     bson.M{"$exists": false},
+		})
+		if err != nil {
+			return err
+		}
+		for _, booking := range bookings {
+			data, err := s.bookingData(ctx, booking)
+			if err != nil {
+				return err
+			}
+			if err := s.queue(ctx, sc.template+":"+booking.ID.Hex(), booking.UserID, sc.template, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeliverDue sends pending notifications whose next attempt is due, retrying
+// failures with exponential backoff up to MaxAttempts. It is meant to be run
+// by the scheduler.
+func (s *Service) DeliverDue(ctx context.Context) error {
+	notifications, err := s.store.Notification.GetDueNotifications(ctx, time.Now(), batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		sendErr := s.channel.Send(ctx, Message{
+			ID:             n.ID.Hex(),
+			To:             n.Recipient,
+			Subject:        n.Subject,
+			Body:           n.Body,
+			UnsubscribeURL: n.Unsubscribe,
+		})
+
+		update := bson.M{"attempts": n.Attempts + 1}
+		switch {
+		case sendErr == nil:
+			update["status"] = types.NotificationStatusSent
+			update["sentAt"] = time.Now().UTC()
+			update["lastError"] = ""
+		case n.Attempts+1 >= MaxAttempts:
+			update["status"] = types.NotificationStatusFailed
+			update["lastError"] = sendErr.Error()
+		default:
+			update["nextAttemptAt"] = time.Now().Add(retry.Backoff(n.Attempts+1, baseBackoff, maxBackoff))
+			update["lastError"] = sendErr.Error()
+		}
+		if err := s.store.Notification.UpdateNotification(ctx, n.ID, update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queue renders a message for the user and adds it to the log. Messages the
+// user opted out of are logged as skipped and never sent.
+func (s *Service) queue(ctx context.Context, key string, userID primitive.ObjectID, template string, data TemplateData) error {
+	n, _, err := s.prepare(ctx, key, userID, template, data)
+	if err != nil || n == nil {
+		return err
+	}
+
+	return s.store.Notification.EnqueueNotification(ctx, n)
+}
+
+// prepare renders a message for the user, returning nil when the user is
+// gone. The data it was rendered with is returned as well.
+func (s *Service) prepare(ctx context.Context, key string, userID primitive.ObjectID, template string, data TemplateData) (*types.Notification, TemplateData, error) {
+	user, err := s.store.User.GetUserByID(ctx, userID.Hex())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, data, nil
+	}
+	if err != nil {
+		return nil, data, err
+	}
+
+	token, err := random.Token()
+	if err != nil {
+		return nil, data, err
+	}
+	prefs, err := s.store.Notification.EnsurePreferences(ctx, &types.NotificationPreferences{
+		UserID:   user.ID,
+		Locale:   types.DefaultLocale,
+		OptedOut: []string{},
+		Token:    token,
+	})
+	if err != nil {
+		return nil, data, err
+	}
+
+	data.FirstName = user.FirstName
+	data.UnsubscribeURL = fmt.Sprintf("%s/api/notifications/unsubscribe/%s", s.baseURL, prefs.Token)
+	subject, body, err := Render(prefs.Locale, template, data)
+	if err != nil {
+		return nil, data, err
+	}
+
+	status := types.NotificationStatusPending
+	if !prefs.Wants(template) {
+		status = types.NotificationStatusSkipped
+	}
+	now := time.Now().UTC()
+	return &types.Notification{
+		ID:            primitive.NewObjectID(),
+		Key:           key,
+		UserID:        user.ID,
+		Template:      template,
+		Locale:        prefs.Locale,
+		Recipient:     user.Email,
+		Subject:       subject,
+		Body:          body,
+		Unsubscribe:   data.UnsubscribeURL,
+		Status:        status,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, data, nil
+}
+
+func (s *Service) bookingData(ctx context.Context, booking *types.Booking) (TemplateData, error) {
+	data, err := s.roomData(ctx, booking.RoomID)
+	if err != nil {
+		return data, err
+	}
+	data.BookingID = booking.ID.Hex()
+	data.FromDate = booking.FromDate.Format(dateLayout)
+	data.TillDate = booking.TillDate.Format(dateLayout)
+	data.Nights = types.Nights(booking.FromDate, booking.TillDate)
+	data.NumPersons = booking.NumPersons
+	data.Price = fmt.Sprintf("%.2f", booking.Price)
+	return data, nil
+}
+
+func (s *Service) roomData(ctx context.Context, roomID primitive.ObjectID) (TemplateData, error) {
+	var data TemplateData
+	room, err := s.store.Room.GetRoomByID(ctx, roomID.Hex())
+	if err != nil {
+		return data, err
+	}
+	hotel, err := s.store.Hotel.GetHotelByID(ctx, room.HotelID)
+	if err != nil {
+		return data, err
+	}
+	data.HotelName = hotel.Name
+	data.HotelLocation = hotel.Location
+	data.RoomSize = room.Size
+	return data, nil
+}
@@ -0,0 +1,56 @@
+package notifications
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+// Every template file defines a "subject" and a "body" template. Files live
+// under templates/<locale>/<name>.tmpl.
+//
+//go:embed templates
+var templateFS embed.FS
+
+// TemplateData is what templates can refer to. Dates are preformatted.
+type TemplateData struct {
+	FirstName      string
+	BookingID      string
+	HotelName      string
+	HotelLocation  string
+	RoomSize       string
+	FromDate       string
+	TillDate       string
+	Nights         int
+	NumPersons     int
+	Price          string
+	HoldExpiresAt  string
+	LockedUntil    string
+	UnlockURL      string
+	UnsubscribeURL string
+}
+
+// Render renders a template in locale, falling back to the default locale
+// when the template has not been translated.
+func Render(locale, name string, data TemplateData) (subject, body string, err error) {
+	tmpl, err := template.ParseFS(templateFS, fmt.Sprintf("templates/%s/%s.tmpl", locale, name))
+	if err != nil && locale != types.DefaultLocale {
+		tmpl, err = template.ParseFS(templateFS, fmt.Sprintf("templates/%s/%s.tmpl", types.DefaultLocale, name))
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	var s, b strings.Builder
+	if err := tmpl.ExecuteTemplate(&s, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&b, "body", data); err != nil {
+		return "", "", err
+	}
+
+	return strings.TrimSpace(s.String()), strings.TrimSpace(b.String()) + "\n", nil
+}
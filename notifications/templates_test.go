@@ -0,0 +1,99 @@
+package notifications
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+func TestRenderEveryTemplate(t *testing.T) {
+	data := TemplateData{
+		FirstName:      "James",
+		BookingID:      "b-1",
+		HotelName:      "Ibis",
+		HotelLocation:  "Paris",
+		RoomSize:       "small",
+		FromDate:       "2030-01-05",
+		TillDate:       "2030-01-08",
+		Nights:         3,
+		NumPersons:     2,
+		Price:          "300.00",
+		HoldExpiresAt:  "2030-01-01 12:00 UTC",
+		UnsubscribeURL: "http://localhost/api/notifications/unsubscribe/token",
+	}
+
+	for _, locale := range types.Locales {
+		for _, template := range types.NotificationTemplates {
+			subject, body, err := Render(locale, template, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, template, err)
+			}
+			if !strings.Contains(subject, "Ibis") {
+				t.Fatalf("%s/%s: expected the hotel in the subject, got %q", locale, template, subject)
+			}
+			if !strings.Contains(body, "James") || !strings.Contains(body, data.UnsubscribeURL) {
+				t.Fatalf("%s/%s: expected the guest and unsubscribe link in the body, got %q", locale, template, body)
+			}
+		}
+	}
+}
+
+func TestRenderFallsBackToDefaultLocale(t *testing.T) {
+	subject, _, err := Render("fr", types.TemplateReceipt, TemplateData{HotelName: "Ibis"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Your receipt from Ibis" {
+		t.Fatalf("expected the english subject, got %q", subject)
+	}
+
+	subject, _, err = Render("es", types.TemplateReceipt, TemplateData{HotelName: "Ibis"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Tu recibo de Ibis" {
+		t.Fatalf("expected the spanish subject, got %q", subject)
+	}
+}
+
+func TestRenderAccountLocked(t *testing.T) {
+	data := TemplateData{FirstName: "James", LockedUntil: "2030-01-01 12:00 UTC", UnlockURL: "http://localhost/api/auth/unlock/token"}
+	for _, locale := range types.Locales {
+		_, body, err := Render(locale, types.TemplateAccountLocked, data)
+		if err != nil {
+			t.Fatalf("%s: %v", locale, err)
+		}
+		if !strings.Contains(body, data.UnlockURL) || !strings.Contains(body, data.LockedUntil) {
+			t.Fatalf("%s: expected the unlock link and lockout end in the body, got %q", locale, body)
+		}
+	}
+}
+
+func TestFileChannelWritesEmail(t *testing.T) {
+	dir := t.TempDir()
+	channel := NewFileChannel(dir)
+	err := channel.Send(context.TODO(), Message{
+		ID:             "n-1",
+		To:             "james@foo.com",
+		Subject:        "Hello",
+		Body:           "line one\nline two\n",
+		UnsubscribeURL: "http://localhost/unsubscribe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "n-1.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: james@foo.com\r\n", "Subject: Hello\r\n", "List-Unsubscribe: <http://localhost/unsubscribe>\r\n", "\r\n\r\nline one\r\nline two\r\n"} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("expected %q in\n%s", want, b)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Package random generates the secrets handed out in links and signatures:
+// feed and unsubscribe tokens, webhook secrets and unlock tokens.
+package random
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Token returns a random 192 bit token, hex encoded.
+func Token() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,16 @@
+// Package retry holds what the background senders share for retrying failed
+// attempts.
+package retry
+
+import "time"
+
+// Backoff is the delay before retrying after the given number of failed
+// attempts: base after the first, doubling with every further one, and never
+// more than max.
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	d := base << (attempts - 1)
+	if d <= 0 || d > max {
+		return max
+	}
+	return d
+}
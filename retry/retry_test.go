@@ -0,0 +1,22 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffIsCapped(t *testing.T) {
+	const (
+		base = 30 * time.Second
+		max  = 2 * time.Hour
+	)
+	if got := Backoff(1, base, max); got != base {
+		t.Fatalf("expected the first retry after %s, got %s", base, got)
+	}
+	if got := Backoff(3, base, max); got != 4*base {
+		t.Fatalf("expected the third retry after %s, got %s", 4*base, got)
+	}
+	if got := Backoff(40, base, max); got != max {
+		t.Fatalf("expected backoff to be capped at %s, got %s", max, got)
+	}
+}
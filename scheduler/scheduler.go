@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// stallFactor is how many intervals a job may go without finishing a run
+// before it is reported as stalled.
+const stallFactor = 3
+
+// JobFunc is a unit of background work. It is called once per interval.
+type JobFunc func(context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      JobFunc
+
+	// guarded by Scheduler.mu
+	running       bool
+	lastRunAt     time.Time
+	lastSuccessAt time.Time
+	lastError     string
+	failures      int
+}
+
+// JobStatus is a snapshot of how a job has been doing.
+type JobStatus struct {
+	Name          string     `json:"name"`
+	Interval      string     `json:"interval"`
+	Running       bool       `json:"running"`
+	LastRunAt     *time.Time `json:"lastRunAt,omitempty"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	// Failures counts the runs that failed since the last success.
+	Failures int `json:"failures"`
+	// Stalled is set when the job has not finished a run for several
+	// intervals, which usually means it hangs.
+	Stalled bool `json:"stalled"`
+}
+
+// Scheduler runs registered jobs periodically, each in its own goroutine.
+type Scheduler struct {
+	intervals map[string]time.Duration
+	jobs      []*job
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	mu        sync.Mutex
+	startedAt time.Time
+	started   bool
+}
+
+// New returns a scheduler. intervals overrides the interval of jobs by name.
+func New(intervals map[string]time.Duration) *Scheduler {
+	return &Scheduler{
+		intervals: intervals,
+	}
+}
+
+// Every registers fn to run every interval once the scheduler is started.
+func (s *Scheduler) Every(name string, interval time.Duration, fn JobFunc) {
+	if override, ok := s.intervals[name]; ok {
+		interval = override
+	}
+	s.jobs = append(s.jobs, &job{
+		name:     name,
+		interval: interval,
+		run:      fn,
+	})
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.started = true
+	s.startedAt = time.Now()
+	s.mu.Unlock()
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+}
+
+// Stop cancels all jobs and waits for the running ones to return.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.started = false
+	s.mu.Unlock()
+}
+
+// Running reports whether the scheduler has been started and not stopped.
+func (s *Scheduler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.started
+}
+
+// Status returns the status of every job in registration order.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		status := JobStatus{
+			Name:      j.name,
+			Interval:  j.interval.String(),
+			Running:   j.running,
+			LastError: j.lastError,
+			Failures:  j.failures,
+		}
+		if !j.lastRunAt.IsZero() {
+			lastRunAt := j.lastRunAt
+			status.LastRunAt = &lastRunAt
+		}
+		if !j.lastSuccessAt.IsZero() {
+			lastSuccessAt := j.lastSuccessAt
+			status.LastSuccessAt = &lastSuccessAt
+		}
+		if s.started {
+			// a job that never ran is measured from the scheduler start
+			since := s.startedAt
+			if j.running || j.lastRunAt.After(since) {
+				since = j.lastRunAt
+			}
+			status.Stalled = now.Sub(since) > stallFactor*j.interval
+		}
+		statuses[i] = status
+	}
+
+	return statuses
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ctx.Err() != nil {
+				return
+			}
+			s.run(ctx, j)
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	s.mu.Lock()
+	j.running = true
+	j.lastRunAt = time.Now()
+	s.mu.Unlock()
+
+	err := j.run(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.running = false
+	if err != nil {
+		slog.ErrorContext(ctx, "job failed", "job", j.name, "err", err)
+		j.lastError = err.Error()
+		j.failures++
+		return
+	}
+	j.lastSuccessAt = time.Now()
+	j.lastError = ""
+	j.failures = 0
+}
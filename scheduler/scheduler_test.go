@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerStatus(t *testing.T) {
+	var calls atomic.Int32
+	s := New(map[string]time.Duration{"flaky": 10 * time.Millisecond})
+	s.Every("ok", 10*time.Millisecond, func(context.Context) error { return nil })
+	// the override replaces the hour
+	s.Every("flaky", time.Hour, func(context.Context) error {
+		if calls.Add(1) < 3 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	s.Every("hung", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if s.Running() {
+		t.Fatal("expected the scheduler not to run before Start")
+	}
+	s.Start(context.Background())
+
+	deadline := time.Now().Add(2 * time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	statuses := s.Status()
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 jobs but got %d", len(statuses))
+	}
+	ok, flaky, hung := statuses[0], statuses[1], statuses[2]
+	if ok.LastSuccessAt == nil || ok.Stalled || ok.Failures != 0 {
+		t.Fatalf("expected ok to succeed but got %+v", ok)
+	}
+	if flaky.Interval != "10ms" || flaky.LastSuccessAt == nil || flaky.Failures != 0 || flaky.LastError != "" {
+		t.Fatalf("expected flaky to recover but got %+v", flaky)
+	}
+	if !hung.Running || !hung.Stalled {
+		t.Fatalf("expected hung to be stalled but got %+v", hung)
+	}
+
+	s.Stop()
+	if s.Running() {
+		t.Fatal("expected the scheduler to stop")
+	}
+	if statuses := s.Status(); statuses[2].Running || statuses[2].Failures != 1 {
+		t.Fatalf("expected the hung job to be canceled but got %+v", statuses[2])
+	}
+}
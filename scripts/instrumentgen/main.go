@@ -0,0 +1,252 @@
+// Command instrumentgen writes db/instrument_gen.go, which wraps every store
+// interface of db.Store in a decorator reporting each call to a db.Observer.
+// Run it with go generate from the db package after changing a store
+// interface.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const output = "instrument_gen.go"
+
+type method struct {
+	name    string
+	params  []string
+	results []string
+}
+
+type generator struct {
+	fset       *token.FileSet
+	interfaces map[string]*ast.InterfaceType
+	imports    map[string]string // package name -> path
+	used       map[string]bool
+}
+
+func main() {
+	dir := "."
+	if len(os.Args) > 1 {
+		dir = os.Args[1]
+	}
+
+	g := &generator{
+		fset:       token.NewFileSet(),
+		interfaces: map[string]*ast.InterfaceType{},
+		imports:    map[string]string{},
+		used:       map[string]bool{"context": true},
+	}
+	fields, err := g.parse(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintln(&body, "// Instrument wraps every store so observer is told about each call.")
+	fmt.Fprintln(&body, "func Instrument(store *Store, observer Observer) *Store {")
+	fmt.Fprintln(&body, "return &Store{")
+	for _, f := range fields {
+		fmt.Fprintf(&body, "%s: &instrumented%s{next: store.%s, observer: observer},\n", f[0], f[1], f[0])
+	}
+	fmt.Fprintln(&body, "}\n}")
+
+	for _, f := range fields {
+		label, iface := f[0], f[1]
+		methods, err := g.methods(iface)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(&body, "\ntype instrumented%s struct {\nnext %s\nobserver Observer\n}\n", iface, iface)
+		for _, m := range methods {
+			g.writeMethod(&body, label, iface, m)
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintln(&src, "// Code generated by scripts/instrumentgen. DO NOT EDIT.")
+	fmt.Fprintln(&src, "\npackage db\n\nimport (")
+	var names []string
+	for name := range g.used {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return g.path(names[i]) < g.path(names[j]) })
+	for _, name := range names {
+		if p := g.path(name); filepath.Base(p) != name {
+			fmt.Fprintf(&src, "%s %q\n", name, p)
+		} else {
+			fmt.Fprintf(&src, "%q\n", p)
+		}
+	}
+	fmt.Fprintln(&src, ")")
+	src.Write(body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		log.Fatalf("formatting generated code: %v\n%s", err, src.String())
+	}
+	if err := os.WriteFile(filepath.Join(dir, output), formatted, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parse collects the interfaces and imports of the package and returns the
+// db.Store fields as (field name, interface name) pairs.
+func (g *generator) parse(dir string) ([][2]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var store *ast.StructType
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") || filepath.Base(path) == output {
+			continue
+		}
+		file, err := parser.ParseFile(g.fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, imp := range file.Imports {
+			p, _ := strconv.Unquote(imp.Path.Value)
+			name := filepath.Base(p)
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			g.imports[name] = p
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			switch t := spec.Type.(type) {
+			case *ast.InterfaceType:
+				g.interfaces[spec.Name.Name] = t
+			case *ast.StructType:
+				if spec.Name.Name == "Store" {
+					store = t
+				}
+			}
+			return false
+		})
+	}
+	if store == nil {
+		return nil, fmt.Errorf("no Store struct in %s", dir)
+	}
+
+	var fields [][2]string
+	for _, field := range store.Fields.List {
+		ident, ok := field.Type.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("Store field %s is not a local interface", field.Names[0].Name)
+		}
+		fields = append(fields, [2]string{field.Names[0].Name, ident.Name})
+	}
+	return fields, nil
+}
+
+// methods returns the method set of the named interface, including the
+// methods of interfaces embedded in it.
+func (g *generator) methods(name string) ([]method, error) {
+	iface, ok := g.interfaces[name]
+	if !ok {
+		return nil, fmt.Errorf("interface %s not found", name)
+	}
+
+	var methods []method
+	for _, field := range iface.Methods.List {
+		switch t := field.Type.(type) {
+		case *ast.Ident:
+			embedded, err := g.methods(t.Name)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, embedded...)
+		case *ast.FuncType:
+			m := method{name: field.Names[0].Name}
+			for _, param := range t.Params.List {
+				for range max(1, len(param.Names)) {
+					m.params = append(m.params, g.expr(param.Type))
+				}
+			}
+			if t.Results != nil {
+				for _, result := range t.Results.List {
+					for range max(1, len(result.Names)) {
+						m.results = append(m.results, g.expr(result.Type))
+					}
+				}
+			}
+			methods = append(methods, m)
+		default:
+			return nil, fmt.Errorf("unsupported method in %s", name)
+		}
+	}
+	return methods, nil
+}
+
+func (g *generator) writeMethod(w *bytes.Buffer, label, iface string, m method) {
+	if len(m.params) == 0 || m.params[0] != "context.Context" {
+		log.Fatalf("%s.%s should take a context.Context first", iface, m.name)
+	}
+	if len(m.results) == 0 || m.results[len(m.results)-1] != "error" {
+		log.Fatalf("%s.%s should return an error last", iface, m.name)
+	}
+
+	var params, args []string
+	for i, p := range m.params {
+		name := fmt.Sprintf("a%d", i)
+		if i == 0 {
+			name = "ctx"
+		}
+		params = append(params, name+" "+p)
+		args = append(args, name)
+	}
+	var results, named []string
+	for i, r := range m.results {
+		name := fmt.Sprintf("r%d", i)
+		if i == len(m.results)-1 {
+			name = "err"
+		}
+		results = append(results, name)
+		named = append(named, name+" "+r)
+	}
+
+	fmt.Fprintf(w, "\nfunc (s *instrumented%s) %s(%s) (%s) {\n", iface, m.name, strings.Join(params, ", "), strings.Join(named, ", "))
+	fmt.Fprintf(w, "ctx, done := s.observer.Observe(ctx, %q, %q)\n", label, m.name)
+	fmt.Fprintf(w, "%s = s.next.%s(%s)\n", strings.Join(results, ", "), m.name, strings.Join(args, ", "))
+	fmt.Fprintln(w, "done(err)")
+	fmt.Fprintf(w, "return %s\n}\n", strings.Join(results, ", "))
+}
+
+// expr prints a type expression and records the packages it refers to.
+func (g *generator) expr(e ast.Expr) string {
+	ast.Inspect(e, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				g.used[pkg.Name] = true
+			}
+		}
+		return true
+	})
+	var buf bytes.Buffer
+	printer.Fprint(&buf, g.fset, e)
+	return buf.String()
+}
+
+func (g *generator) path(name string) string {
+	if p, ok := g.imports[name]; ok {
+		return p
+	}
+	return name
+}
@@ -2,38 +2,90 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
+	"github.com/aboronilov/go-hotel-reservation/config"
 	"github.com/aboronilov/go-hotel-reservation/db"
 	"github.com/aboronilov/go-hotel-reservation/db/fixtures"
+	"github.com/aboronilov/go-hotel-reservation/db/migrations"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func main() {
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	ctx := context.Background()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(db.DBURI))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.DB.URI))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	collections := []string{db.HOTEL_COLLECTION, db.ROOM_COLLECTION, db.USERS_COLLECTION, db.BOOKING_COLLECTION}
+	collections := []string{
+		db.HOTEL_COLLECTION,
+		db.ROOM_COLLECTION,
+		db.USERS_COLLECTION,
+		db.BOOKING_COLLECTION,
+		db.WAITLIST_COLLECTION,
+		db.BLOCK_COLLECTION,
+		db.ITINERARY_COLLECTION,
+		db.RESTRICTION_COLLECTION,
+		db.OVERBOOKING_COLLECTION,
+		db.CALENDAR_FEED_COLLECTION,
+		db.CALENDAR_IMPORT_COLLECTION,
+		db.CHANNEL_MAPPING_COLLECTION,
+		db.CHANNEL_OUTBOX_COLLECTION,
+		db.WEBHOOK_COLLECTION,
+		db.WEBHOOK_DELIVERY_COLLECTION,
+		db.OUTBOX_COLLECTION,
+		db.NOTIFICATION_COLLECTION,
+		db.NOTIFICATION_PREFERENCES_COLLECTION,
+		db.REVIEW_COLLECTION,
+		db.ATTEMPT_COLLECTION,
+		db.AUDIT_COLLECTION,
+		db.SETTINGS_COLLECTION,
+		db.MIGRATION_COLLECTION,
+	}
 	for _, collection := range collections {
-		err := client.Database(db.DBNAME).Collection(collection).Drop(ctx)
+		err := client.Database(cfg.DB.Name).Collection(collection).Drop(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
 
-	hotelStore := db.NewMongoHotelStore(client, false)
+	if _, err := migrations.NewMigrator(client.Database(cfg.DB.Name), migrations.All).Up(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	hotelStore := db.NewMongoHotelStore(client, cfg.DB.Name)
 
 	store := &db.Store{
-		User:    db.NewMongoUserStore(client, false),
-		Room:    db.NewMongoRoomStore(client, hotelStore, false),
-		Hotel:   db.NewMongoHotelStore(client, false),
-		Booking: db.NewMongoBookingStore(client, false),
+		User:         db.NewMongoUserStore(client, cfg.DB.Name),
+		Room:         db.NewMongoRoomStore(client, hotelStore, cfg.DB.Name),
+		Hotel:        db.NewMongoHotelStore(client, cfg.DB.Name),
+		Booking:      db.NewMongoBookingStore(client, cfg.DB.Name),
+		Waitlist:     db.NewMongoWaitlistStore(client, cfg.DB.Name),
+		Block:        db.NewMongoBlockStore(client, cfg.DB.Name),
+		Itinerary:    db.NewMongoItineraryStore(client, cfg.DB.Name),
+		Restriction:  db.NewMongoRestrictionStore(client, cfg.DB.Name),
+		Overbooking:  db.NewMongoOverbookingStore(client, cfg.DB.Name),
+		Report:       db.NewMongoReportStore(client, cfg.DB.Name),
+		Calendar:     db.NewMongoCalendarStore(client, cfg.DB.Name),
+		Channel:      db.NewMongoChannelStore(client, cfg.DB.Name),
+		Webhook:      db.NewMongoWebhookStore(client, cfg.DB.Name),
+		Event:        db.NewMongoEventStore(client, cfg.DB.Name),
+		Notification: db.NewMongoNotificationStore(client, cfg.DB.Name),
+		Review:       db.NewMongoReviewStore(client, cfg.DB.Name),
+		Attempt:      db.NewMongoAttemptStore(client, cfg.DB.Name),
+		Audit:        db.NewMongoAuditStore(client, cfg.DB.Name),
+		Settings:     db.NewMongoSettingsStore(client, cfg.DB.Name),
 	}
 
 	newAdmin := fixtures.AddUser(store, "Jack", "Bauer", true)
@@ -42,7 +94,7 @@ func main() {
 	newUser := fixtures.AddUser(store, "Tony", "Almeida", false)
 	fmt.Println("user --->", newUser.ID)
 
-	newHotel := fixtures.AddHotel(store, "IBIS", "New York", 5)
+	newHotel := fixtures.AddHotel(store, "IBIS", "New York")
 	fmt.Println("hotel --->", newHotel.ID)
 
 	from := time.Now().AddDate(0, 0, 1)
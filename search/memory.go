@@ -0,0 +1,139 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Score factors for query terms that only approximately match an indexed
+// term.
+const (
+	prefixFactor = 0.8
+	typoFactor   = 0.6
+)
+
+// MemoryIndex is an in-process inverted index over hotels. It needs no
+// database, which makes it the searcher of choice for tests, but it only
+// knows the hotels added to it.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	hotels   map[primitive.ObjectID]*types.Hotel
+	postings map[string]map[primitive.ObjectID]float64
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		hotels:   map[primitive.ObjectID]*types.Hotel{},
+		postings: map[string]map[primitive.ObjectID]float64{},
+	}
+}
+
+// Add indexes the hotels, replacing any earlier version of them.
+func (idx *MemoryIndex) Add(hotels ...*types.Hotel) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, hotel := range hotels {
+		idx.remove(hotel.ID)
+		idx.hotels[hotel.ID] = hotel
+		for _, field := range hotelFields(hotel) {
+			for _, term := range tokenize(field.text) {
+				if idx.postings[term] == nil {
+					idx.postings[term] = map[primitive.ObjectID]float64{}
+				}
+				idx.postings[term][hotel.ID] += float64(field.weight)
+			}
+		}
+	}
+}
+
+func (idx *MemoryIndex) Remove(id primitive.ObjectID) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *MemoryIndex) remove(id primitive.ObjectID) {
+	if _, ok := idx.hotels[id]; !ok {
+		return
+	}
+	delete(idx.hotels, id)
+	for term, docs := range idx.postings {
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+}
+
+// Search scores every hotel containing a query term, or a term within a few
+// typos or starting with it, by the field weight and the rarity of the term.
+// Hotels matching more of the query rank higher.
+func (idx *MemoryIndex) Search(ctx context.Context, query string, limit int) ([]*types.HotelMatch, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := tokenize(query)
+	scores := map[primitive.ObjectID]float64{}
+	matched := map[primitive.ObjectID]int{}
+	for _, term := range terms {
+		best := map[primitive.ObjectID]float64{}
+		for indexed, docs := range idx.postings {
+			factor := matchFactor(term, indexed)
+			if factor == 0 {
+				continue
+			}
+			idf := math.Log(1 + float64(len(idx.hotels))/float64(len(docs)))
+			for id, weight := range docs {
+				best[id] = max(best[id], factor*idf*weight)
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+			matched[id]++
+		}
+	}
+
+	matches := []*types.HotelMatch{}
+	for id, score := range scores {
+		matches = append(matches, &types.HotelMatch{
+			Hotel: *idx.hotels[id],
+			Score: score * float64(matched[id]) / float64(len(terms)),
+		})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
+
+// matchFactor tells how well a query term matches an indexed term, from 1
+// for the same term down to 0 for no match.
+func matchFactor(term, indexed string) float64 {
+	if term == indexed {
+		return 1
+	}
+	if len(term) >= 3 && strings.HasPrefix(indexed, term) {
+		return prefixFactor
+	}
+	if limit := maxEdits(term); limit > 0 {
+		if d := distance(term, indexed, limit); d <= limit {
+			return math.Pow(typoFactor, float64(d))
+		}
+	}
+	return 0
+}
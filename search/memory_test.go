@@ -0,0 +1,113 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newHotel(name, city, description string, amenities ...string) *types.Hotel {
+	return &types.Hotel{
+		ID:          primitive.NewObjectID(),
+		Name:        name,
+		Address:     &types.Address{City: city},
+		Description: description,
+		Amenities:   amenities,
+	}
+}
+
+func names(matches []*types.HotelMatch) []string {
+	var names []string
+	for _, match := range matches {
+		names = append(names, match.Name)
+	}
+	return names
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	var (
+		idx     = NewMemoryIndex()
+		ritz    = newHotel("Ritz", "Paris", "Luxury palace on Place Vendome", types.AmenitySpa, types.AmenityPool)
+		ibis    = newHotel("Ibis Centre", "Paris", "Budget rooms close to the station", types.AmenityWifi)
+		savoy   = newHotel("Savoy", "London", "Riverside hotel with a famous bar and a pool", types.AmenityBar, types.AmenityPool)
+		seaside = newHotel("Paris Beach Resort", "Nice", "Rooms facing the sea", types.AmenityBeachAccess)
+		ctx     = context.Background()
+	)
+	idx.Add(ritz, ibis, savoy, seaside)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// a name hit outranks a city hit
+		{"paris", []string{"Paris Beach Resort", "Ibis Centre", "Ritz"}},
+		// typos
+		{"Londno", []string{"Savoy"}},
+		{"luxary palace", []string{"Ritz"}},
+		// plurals, and more mentions rank higher
+		{"pools", []string{"Savoy", "Ritz"}},
+		// hotels matching every term rank first
+		{"ritz paris", []string{"Ritz", "Paris Beach Resort", "Ibis Centre"}},
+		// prefixes
+		{"budg", []string{"Ibis Centre"}},
+		{"the", nil},
+		{"xyz", nil},
+	}
+	for _, tt := range tests {
+		matches, err := idx.Search(ctx, tt.query, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := names(matches)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%q: expected %v but got %v", tt.query, tt.want, got)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%q: expected %v but got %v", tt.query, tt.want, got)
+			}
+		}
+	}
+
+	matches, _ := idx.Search(ctx, "paris", 1)
+	if len(matches) != 1 {
+		t.Fatalf("expected the limit to apply but got %d matches", len(matches))
+	}
+
+	idx.Remove(ritz.ID)
+	ritz.Name = "Ritz Carlton"
+	idx.Add(ibis)
+	if matches, _ := idx.Search(ctx, "palace", 10); len(matches) != 0 {
+		t.Fatalf("expected removed hotels not to match but got %v", names(matches))
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"london", "london", 0},
+		{"london", "londno", 1},
+		{"london", "londn", 1},
+		{"luxary", "luxury", 1},
+		{"paris", "berlin", 3},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b, 2); got != tt.want {
+			t.Fatalf("distance(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	vocabulary := map[string]bool{"london": true, "paris": true, "pool": true}
+	if got := correct("londno", vocabulary); got != "london" {
+		t.Fatalf("expected london but got %s", got)
+	}
+	if got := correct("spa", vocabulary); got != "spa" {
+		t.Fatalf("expected short terms to be kept but got %s", got)
+	}
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// vocabularyTTL is how long the terms used to correct typos are cached.
+const vocabularyTTL = 5 * time.Minute
+
+// MongoSearcher searches the hotels collection through its text index.
+// MongoDB only matches whole (stemmed) words, so query terms that appear in
+// no hotel are first corrected to the closest term that does.
+type MongoSearcher struct {
+	store db.HotelStore
+
+	mu         sync.Mutex
+	vocabulary map[string]bool
+	loadedAt   time.Time
+}
+
+func NewMongoSearcher(store db.HotelStore) *MongoSearcher {
+	return &MongoSearcher{
+		store: store,
+	}
+}
+
+func (s *MongoSearcher) Search(ctx context.Context, query string, limit int) ([]*types.HotelMatch, error) {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return []*types.HotelMatch{}, nil
+	}
+
+	vocabulary, err := s.loadVocabulary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, term := range terms {
+		if !vocabulary[term] {
+			terms[i] = correct(term, vocabulary)
+		}
+	}
+
+	return s.store.SearchHotels(ctx, strings.Join(terms, " "), int64(limit))
+}
+
+func (s *MongoSearcher) loadVocabulary(ctx context.Context) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.vocabulary != nil && time.Since(s.loadedAt) < vocabularyTTL {
+		return s.vocabulary, nil
+	}
+
+	hotels, err := s.store.GetSearchableHotels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vocabulary := map[string]bool{}
+	for _, hotel := range hotels {
+		for _, field := range hotelFields(hotel) {
+			for _, term := range tokenize(field.text) {
+				vocabulary[term] = true
+			}
+		}
+	}
+	s.vocabulary = vocabulary
+	s.loadedAt = time.Now()
+
+	return vocabulary, nil
+}
+
+// invalidate drops the cached vocabulary, so the next search reloads it.
+func (s *MongoSearcher) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vocabulary = nil
+}
+
+// Watch wraps store so that creating, updating or deleting a hotel through it
+// invalidates the vocabulary, and new hotel names are corrected to right away.
+func (s *MongoSearcher) Watch(store db.HotelStore) db.HotelStore {
+	return &watchedHotelStore{HotelStore: store, searcher: s}
+}
+
+type watchedHotelStore struct {
+	db.HotelStore
+	searcher *MongoSearcher
+}
+
+func (w *watchedHotelStore) CreateHotel(ctx context.Context, hotel *types.Hotel) (*types.Hotel, error) {
+	defer w.searcher.invalidate()
+	return w.HotelStore.CreateHotel(ctx, hotel)
+}
+
+func (w *watchedHotelStore) UpdateHotelByID(ctx context.Context, filter bson.M, update bson.M) error {
+	defer w.searcher.invalidate()
+	return w.HotelStore.UpdateHotelByID(ctx, filter, update)
+}
+
+func (w *watchedHotelStore) DeleteHotelByID(ctx context.Context, id string) error {
+	defer w.searcher.invalidate()
+	return w.HotelStore.DeleteHotelByID(ctx, id)
+}
+
+// correct returns the vocabulary term closest to term within its typo
+// allowance, or term itself when there is none.
+func correct(term string, vocabulary map[string]bool) string {
+	limit := maxEdits(term)
+	best, bestDistance := term, limit+1
+	for candidate := range vocabulary {
+		d := distance(term, candidate, limit)
+		if d < bestDistance || (d == bestDistance && d <= limit && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+// fakeHotelStore keeps hotels in memory and records the text searched for.
+type fakeHotelStore struct {
+	db.HotelStore
+	hotels   []*types.Hotel
+	searched string
+}
+
+func (s *fakeHotelStore) CreateHotel(_ context.Context, hotel *types.Hotel) (*types.Hotel, error) {
+	s.hotels = append(s.hotels, hotel)
+	return hotel, nil
+}
+
+func (s *fakeHotelStore) GetSearchableHotels(context.Context) ([]*types.Hotel, error) {
+	return s.hotels, nil
+}
+
+func (s *fakeHotelStore) SearchHotels(_ context.Context, text string, _ int64) ([]*types.HotelMatch, error) {
+	s.searched = text
+	return []*types.HotelMatch{}, nil
+}
+
+func TestMongoSearcherReloadsTheVocabularyAfterWrites(t *testing.T) {
+	var (
+		store    = &fakeHotelStore{hotels: []*types.Hotel{newHotel("Ritz", "Paris", "")}}
+		searcher = NewMongoSearcher(store)
+		hotels   = searcher.Watch(store)
+		ctx      = context.Background()
+	)
+
+	if _, err := searcher.Search(ctx, "ritzy", 10); err != nil {
+		t.Fatal(err)
+	}
+	if store.searched != "ritz" {
+		t.Fatalf("expected the typo to be corrected, got %q", store.searched)
+	}
+
+	if _, err := hotels.CreateHotel(ctx, newHotel("Ritzy", "Paris", "")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := searcher.Search(ctx, "ritzy", 10); err != nil {
+		t.Fatal(err)
+	}
+	if store.searched != "ritzy" {
+		t.Fatalf("expected the new hotel name to be searched for, got %q", store.searched)
+	}
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/aboronilov/go-hotel-reservation/types"
+)
+
+// Searcher finds hotels matching a free text query, best matches first.
+// Queries are matched against the hotel name, city, description and
+// amenities and tolerate small typos.
+type Searcher interface {
+	Search(ctx context.Context, query string, limit int) ([]*types.HotelMatch, error)
+}
+
+// Field weights, so a hit in the name ranks above one in the description.
+const (
+	nameWeight        = 10
+	cityWeight        = 5
+	amenityWeight     = 3
+	descriptionWeight = 1
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "at": true, "by": true, "for": true,
+	"in": true, "near": true, "of": true, "on": true, "or": true, "the": true,
+	"to": true, "with": true,
+}
+
+// tokenize splits text into lower-cased terms on anything that is not a
+// letter or digit, drops stop words and strips plural s.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if stopWords[field] {
+			continue
+		}
+		if len(field) > 3 && strings.HasSuffix(field, "s") && !strings.HasSuffix(field, "ss") {
+			field = strings.TrimSuffix(field, "s")
+		}
+		terms = append(terms, field)
+	}
+
+	return terms
+}
+
+// maxEdits is how many typos a query term may contain. Short terms must match
+// exactly, or every three letter word would match every other.
+func maxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n <= 3:
+		return 0
+	case n <= 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// distance returns the Damerau-Levenshtein (optimal string alignment) edit
+// distance between a and b, giving up with limit+1 once it is exceeded.
+func distance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+type field struct {
+	text   string
+	weight int
+}
+
+// hotelFields returns the searchable text of a hotel with its weight.
+func hotelFields(hotel *types.Hotel) []field {
+	fields := []field{
+		{hotel.Name, nameWeight},
+		{strings.Join(hotel.Amenities, " "), amenityWeight},
+		{hotel.Description, descriptionWeight},
+	}
+	if hotel.Address != nil {
+		fields = append(fields, field{hotel.Address.City, cityWeight})
+	}
+	return fields
+}
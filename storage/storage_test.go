@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(dir, "/media/")
+
+	key, err := s.Save(context.TODO(), ".png", strings.NewReader("image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(key, ".png") {
+		t.Fatalf("expected the key to keep the extension, got %s", key)
+	}
+	if url := s.URL(key); url != "/media/"+key {
+		t.Fatalf("unexpected url %s", url)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "image" {
+		t.Fatalf("unexpected content %q", b)
+	}
+
+	if err := s.Delete(context.TODO(), key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, key)); !os.IsNotExist(err) {
+		t.Fatal("expected the file to be deleted")
+	}
+	if err := s.Delete(context.TODO(), "../outside"); err != ErrInvalidKey {
+		t.Fatalf("expected keys outside the directory to be rejected, got %v", err)
+	}
+}
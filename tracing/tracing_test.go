@@ -0,0 +1,36 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aboronilov/go-hotel-reservation/config"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupExportsToFile(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Setup(context.Background(), config.Tracing{Exporter: "stdout", File: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, done := StoreObserver{}.Observe(context.Background(), "Booking", "BookRoom")
+	done(nil)
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"Name":"Booking.BookRoom"`) {
+		t.Fatalf("expected the store span in the file but got %s", b)
+	}
+}
@@ -0,0 +1,95 @@
+package twofactor
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, base32 encoded.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFCVectors(t *testing.T) {
+	// the RFC lists 8 digit codes, ours are their last 6 digits
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != want {
+			t.Fatalf("expected code %s at %d, got %s", want, unix, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := Code(rfcSecret, Step(now))
+
+	step, ok := Validate(rfcSecret, code, now)
+	if !ok || step != Step(now) {
+		t.Fatalf("expected the current code to be valid for step %d, got %d %v", Step(now), step, ok)
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(Period)); !ok {
+		t.Fatalf("expected the previous code to be accepted")
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(3*Period)); ok {
+		t.Fatalf("expected an old code to be rejected")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now); ok {
+		t.Fatalf("expected a short code to be rejected")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(ProvisioningURI("Hotel Reservation", "jane@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Fatalf("unexpected URI %s", u)
+	}
+	if u.Path != "/Hotel Reservation:jane@example.com" {
+		t.Fatalf("unexpected label %q", u.Path)
+	}
+	if u.Query().Get("secret") != secret || u.Query().Get("issuer") != "Hotel Reservation" {
+		t.Fatalf("unexpected parameters %s", u.RawQuery)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d", recoveryCodeCount, len(codes))
+	}
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if seen[code] {
+			t.Fatalf("duplicate recovery code %s", code)
+		}
+		seen[code] = true
+		if HashRecoveryCode(code) != hashes[i] {
+			t.Fatalf("hash of %s does not match", code)
+		}
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if HashRecoveryCode(typed) != hashes[i] {
+			t.Fatalf("expected %q to be accepted for %s", typed, code)
+		}
+	}
+}
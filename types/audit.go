@@ -0,0 +1,148 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AuditLoginLockout     = "auth.lockout"
+	AuditLoginUnlock      = "auth.unlock"
+	AuditLoginRateLimited = "auth.rate_limited"
+	AuditLoginWhileLocked = "auth.locked_attempt"
+	AuditTwoFactorEnable  = "auth.2fa_enable"
+	AuditTwoFactorDisable = "auth.2fa_disable"
+	AuditTwoFactorReset   = "auth.2fa_reset"
+	AuditRecoveryCodes    = "auth.2fa_recovery_codes"
+	AuditRecoveryCodeUsed = "auth.2fa_recovery_used"
+	AuditSecurityPolicy   = "settings.security"
+	AuditUserCreate       = "user.create"
+	AuditUserUpdate       = "user.update"
+	AuditUserDelete       = "user.delete"
+	AuditBookingCreate    = "booking.create"
+	AuditBookingCancel    = "booking.cancel"
+)
+
+// AuditRecord is an entry of the audit trail. ActorID is the user who acted,
+// when known, and TargetID what was acted on. Records are chained: each
+// carries the hash of the one before it, so editing or removing a record
+// breaks the chain from there on.
+type AuditRecord struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Seq        int64              `bson:"seq" json:"seq"`
+	Action     string             `bson:"action" json:"action"`
+	ActorID    primitive.ObjectID `bson:"actorID,omitempty" json:"actorID,omitempty"`
+	TargetType string             `bson:"targetType,omitempty" json:"targetType,omitempty"`
+	TargetID   string             `bson:"targetID,omitempty" json:"targetID,omitempty"`
+	Changes    []AuditChange      `bson:"changes,omitempty" json:"changes,omitempty"`
+	IP         string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	RequestID  string             `bson:"requestID,omitempty" json:"requestID,omitempty"`
+	Details    map[string]string  `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	PrevHash   string             `bson:"prevHash" json:"prevHash"`
+	Hash       string             `bson:"hash" json:"hash"`
+}
+
+// AuditChange is a field that changed, with its JSON value before and after.
+// Before is empty for created fields and After for removed ones.
+type AuditChange struct {
+	Field  string          `bson:"field" json:"field"`
+	Before json.RawMessage `bson:"before,omitempty" json:"before,omitempty"`
+	After  json.RawMessage `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// ComputeHash hashes the record, including PrevHash, without its own Hash.
+func (r *AuditRecord) ComputeHash() string {
+	c := *r
+	c.Hash = ""
+	// the database keeps milliseconds in UTC
+	c.CreatedAt = c.CreatedAt.UTC().Truncate(time.Millisecond)
+	b, _ := json.Marshal(c)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Follows reports why the record does not continue the chain after prev, or
+// nil when it does. prev is nil for the first record.
+func (r *AuditRecord) Follows(prev *AuditRecord) error {
+	var (
+		seq  int64 = 1
+		hash string
+	)
+	if prev != nil {
+		seq, hash = prev.Seq+1, prev.Hash
+	}
+	switch {
+	case r.Seq != seq:
+		return fmt.Errorf("expected record %d but found %d", seq, r.Seq)
+	case r.PrevHash != hash:
+		return fmt.Errorf("record %d does not link to the record before it", r.Seq)
+	case r.Hash != r.ComputeHash():
+		return fmt.Errorf("record %d was modified", r.Seq)
+	}
+	return nil
+}
+
+// AuditVerification is the result of checking the audit chain.
+type AuditVerification struct {
+	Valid   bool   `json:"valid"`
+	Checked int64  `json:"checked"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AuditDiff compares the JSON encodings of before and after field by field.
+// Either may be nil, for created and deleted targets. Fields hidden from JSON,
+// like password hashes, never end up in the audit trail.
+func AuditDiff(before, after any) ([]AuditChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for field := range beforeFields {
+		fields = append(fields, field)
+	}
+	for field := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	var changes []AuditChange
+	for _, field := range fields {
+		b, a := beforeFields[field], afterFields[field]
+		if bytes.Equal(b, a) {
+			continue
+		}
+		changes = append(changes, AuditChange{Field: field, Before: b, After: a})
+	}
+	return changes, nil
+}
+
+func jsonFields(v any) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if v == nil {
+		return fields, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
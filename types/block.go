@@ -0,0 +1,91 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RoomBlock reserves a number of rooms per night at a hotel for a group under
+// a negotiated rate. Attendees book against it with Code. Rooms nobody picked
+// go back to general inventory on ReleaseDate.
+type RoomBlock struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	Code          string               `bson:"code" json:"code"`
+	Name          string               `bson:"name" json:"name"`
+	HotelID       primitive.ObjectID   `bson:"hotelID" json:"hotelID"`
+	RoomType      string               `bson:"roomType,omitempty" json:"roomType,omitempty"`
+	RoomsPerNight int                  `bson:"roomsPerNight" json:"roomsPerNight"`
+	RoomIDs       []primitive.ObjectID `bson:"roomIDs" json:"roomIDs"`
+	Rate          float64              `bson:"rate" json:"rate"`
+	FromDate      time.Time            `bson:"fromDate" json:"fromDate"`
+	TillDate      time.Time            `bson:"tillDate" json:"tillDate"`
+	ReleaseDate   time.Time            `bson:"releaseDate" json:"releaseDate"`
+	Released      bool                 `bson:"released" json:"released"`
+	CreatedAt     time.Time            `bson:"createdAt" json:"createdAt"`
+}
+
+type CreateRoomBlockParams struct {
+	Name          string    `json:"name"`
+	HotelID       string    `json:"hotelID"`
+	RoomType      string    `json:"roomType"`
+	RoomsPerNight int       `json:"roomsPerNight"`
+	Rate          float64   `json:"rate"`
+	FromDate      time.Time `json:"fromDate"`
+	TillDate      time.Time `json:"tillDate"`
+	ReleaseDate   time.Time `json:"releaseDate"`
+}
+
+func (params CreateRoomBlockParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(params.Name) == 0 {
+		errors["name"] = "name is required"
+	}
+	if !primitive.IsValidObjectID(params.HotelID) {
+		errors["hotelID"] = "invalid hotelID"
+	}
+	if params.RoomsPerNight <= 0 {
+		errors["roomsPerNight"] = "roomsPerNight should be positive"
+	}
+	if params.Rate <= 0 {
+		errors["rate"] = "rate should be positive"
+	}
+	if params.FromDate.Before(time.Now()) {
+		errors["fromDate"] = "fromDate should be in the future"
+	}
+	if !params.FromDate.Before(params.TillDate) {
+		errors["tillDate"] = "tillDate should be after fromDate"
+	}
+	if params.ReleaseDate.Before(time.Now()) || params.ReleaseDate.After(params.FromDate) {
+		errors["releaseDate"] = "releaseDate should be between now and fromDate"
+	}
+	return errors
+}
+
+func NewRoomBlockFromParams(code string, params CreateRoomBlockParams) *RoomBlock {
+	hotelID, _ := primitive.ObjectIDFromHex(params.HotelID)
+	return &RoomBlock{
+		Code:          code,
+		Name:          params.Name,
+		HotelID:       hotelID,
+		RoomType:      params.RoomType,
+		RoomsPerNight: params.RoomsPerNight,
+		RoomIDs:       []primitive.ObjectID{},
+		Rate:          params.Rate,
+		FromDate:      params.FromDate,
+		TillDate:      params.TillDate,
+		ReleaseDate:   params.ReleaseDate,
+		CreatedAt:     time.Now(),
+	}
+}
+
+// IsActive reports whether the block still withholds its rooms from general
+// inventory at the given time.
+func (b *RoomBlock) IsActive(now time.Time) bool {
+	return !b.Released && now.Before(b.ReleaseDate)
+}
+
+// Covers reports whether a stay falls entirely within the block dates.
+func (b *RoomBlock) Covers(from, till time.Time) bool {
+	return !from.Before(b.FromDate) && !till.After(b.TillDate)
+}
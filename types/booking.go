@@ -7,11 +7,42 @@ import (
 )
 
 type Booking struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID     primitive.ObjectID `bson:"userID,omitempty" json:"userID,omitempty"`
-	RoomID     primitive.ObjectID `bson:"roomID,omitempty" json:"roomID,omitempty"`
-	NumPersons int                `bson:"numPersons,omitempty" json:"numPersons,omitempty"`
-	FromDate   time.Time          `bson:"fromDate,omitempty" json:"fromDate,omitempty"`
-	TillDate   time.Time          `bson:"tillDate,omitempty" json:"tillDate,omitempty"`
-	Canceled   bool               `bson:"canceled" json:"canceled"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID      primitive.ObjectID `bson:"userID,omitempty" json:"userID,omitempty"`
+	RoomID      primitive.ObjectID `bson:"roomID,omitempty" json:"roomID,omitempty"`
+	NumPersons  int                `bson:"numPersons,omitempty" json:"numPersons,omitempty"`
+	FromDate    time.Time          `bson:"fromDate,omitempty" json:"fromDate,omitempty"`
+	TillDate    time.Time          `bson:"tillDate,omitempty" json:"tillDate,omitempty"`
+	Price       float64            `bson:"price" json:"price"`
+	BlockID     primitive.ObjectID `bson:"blockID,omitempty" json:"blockID,omitempty"`
+	ItineraryID primitive.ObjectID `bson:"itineraryID,omitempty" json:"itineraryID,omitempty"`
+	ImportID    primitive.ObjectID `bson:"importID,omitempty" json:"importID,omitempty"`
+	ExternalUID string             `bson:"externalUID,omitempty" json:"externalUID,omitempty"`
+	Channel     string             `bson:"channel,omitempty" json:"channel,omitempty"`
+	ChannelRef  string             `bson:"channelRef,omitempty" json:"channelRef,omitempty"`
+	GuestName   string             `bson:"guestName,omitempty" json:"guestName,omitempty"`
+	Overbooked  bool               `bson:"overbooked,omitempty" json:"overbooked,omitempty"`
+	Walk        *Walk              `bson:"walk,omitempty" json:"walk,omitempty"`
+	Canceled    bool               `bson:"canceled" json:"canceled"`
+}
+
+// ChangedForGuest reports whether the booking differs from before in what
+// the guest booked: the room, the dates, the number of persons or the price.
+func (b *Booking) ChangedForGuest(before *Booking) bool {
+	return b.RoomID != before.RoomID ||
+		!b.FromDate.Equal(before.FromDate) ||
+		!b.TillDate.Equal(before.TillDate) ||
+		b.NumPersons != before.NumPersons ||
+		b.Price != before.Price
+}
+
+// Nights returns the number of calendar nights between two dates, at least
+// one. It counts the same nights as CoversNight, so prices, stay restrictions
+// and occupancy agree.
+func Nights(from, till time.Time) int {
+	nights := int(Night(till).Sub(Night(from)).Hours() / 24)
+	if nights < 1 {
+		nights = 1
+	}
+	return nights
 }
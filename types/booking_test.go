@@ -0,0 +1,38 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNightsCountsCalendarNights(t *testing.T) {
+	day := func(d, hour int) time.Time {
+		return time.Date(2030, 1, d, hour, 0, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		from, till time.Time
+		nights     int
+	}{
+		{day(10, 0), day(12, 0), 2},
+		// a partial day is no extra night
+		{day(10, 10), day(11, 14), 1},
+		{day(10, 22), day(11, 8), 1},
+		{day(10, 10), day(10, 14), 1},
+	}
+	for _, c := range cases {
+		if nights := Nights(c.from, c.till); nights != c.nights {
+			t.Fatalf("expected %d nights from %s till %s, got %d", c.nights, c.from, c.till, nights)
+		}
+		booking := Booking{FromDate: c.from, TillDate: c.till}
+		covered := 0
+		for night := Night(c.from); night.Before(Night(c.till)); night = night.AddDate(0, 0, 1) {
+			if booking.CoversNight(night) {
+				covered++
+			}
+		}
+		// a same day stay covers no night but is charged one
+		if max(covered, 1) != c.nights {
+			t.Fatalf("expected CoversNight to agree on %d nights, got %d", c.nights, covered)
+		}
+	}
+}
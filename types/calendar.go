@@ -0,0 +1,38 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CalendarFeed publishes a room's booked nights as an iCalendar feed under
+// a secret token.
+type CalendarFeed struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RoomID    primitive.ObjectID `bson:"roomID" json:"roomID"`
+	Token     string             `bson:"token" json:"token"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// CalendarImport periodically reads an external iCalendar file or URL and
+// mirrors its events as blocking bookings on a room.
+type CalendarImport struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RoomID       primitive.ObjectID `bson:"roomID" json:"roomID"`
+	Source       string             `bson:"source" json:"source"`
+	LastSyncedAt time.Time          `bson:"lastSyncedAt,omitempty" json:"lastSyncedAt,omitempty"`
+	LastError    string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+}
+
+type CreateCalendarImportParams struct {
+	Source string `json:"source"`
+}
+
+func (params CreateCalendarImportParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(params.Source) == 0 {
+		errors["source"] = "source is required"
+	}
+	return errors
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultRatePlanCode is the rate plan a room is sold under when its mapping
+// does not name one.
+const DefaultRatePlanCode = "BAR"
+
+// ChannelMapping ties one of our rooms to the room and rate plan codes an
+// online travel agency uses for it.
+type ChannelMapping struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Channel      string             `bson:"channel" json:"channel"`
+	RoomID       primitive.ObjectID `bson:"roomID" json:"roomID"`
+	RoomCode     string             `bson:"roomCode" json:"roomCode"`
+	RatePlanCode string             `bson:"ratePlanCode" json:"ratePlanCode"`
+}
+
+type CreateChannelMappingParams struct {
+	Channel      string `json:"channel"`
+	RoomID       string `json:"roomID"`
+	RoomCode     string `json:"roomCode"`
+	RatePlanCode string `json:"ratePlanCode"`
+}
+
+func (params CreateChannelMappingParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(params.Channel) == 0 {
+		errors["channel"] = "channel is required"
+	}
+	if !primitive.IsValidObjectID(params.RoomID) {
+		errors["roomID"] = "invalid roomID"
+	}
+	if len(params.RoomCode) == 0 {
+		errors["roomCode"] = "roomCode is required"
+	}
+	return errors
+}
+
+func NewChannelMappingFromParams(params CreateChannelMappingParams) *ChannelMapping {
+	roomID, _ := primitive.ObjectIDFromHex(params.RoomID)
+	ratePlanCode := params.RatePlanCode
+	if len(ratePlanCode) == 0 {
+		ratePlanCode = DefaultRatePlanCode
+	}
+	return &ChannelMapping{
+		Channel:      params.Channel,
+		RoomID:       roomID,
+		RoomCode:     params.RoomCode,
+		RatePlanCode: ratePlanCode,
+	}
+}
+
+// ARIUpdate is the availability, rate and inventory of a mapped room for one
+// night, as pushed to a channel.
+type ARIUpdate struct {
+	RoomCode     string    `bson:"roomCode" json:"roomCode"`
+	RatePlanCode string    `bson:"ratePlanCode" json:"ratePlanCode"`
+	Date         time.Time `bson:"date" json:"date"`
+	Available    int       `bson:"available" json:"available"`
+	Rate         float64   `bson:"rate" json:"rate"`
+	Closed       bool      `bson:"closed" json:"closed"`
+}
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending"
+	OutboxStatusSent    OutboxStatus = "sent"
+	OutboxStatusFailed  OutboxStatus = "failed"
+)
+
+// ChannelMessage is an ARI push waiting in the channel outbox. Failed pushes
+// are retried with exponential backoff until they run out of attempts.
+type ChannelMessage struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Channel       string             `bson:"channel" json:"channel"`
+	Updates       []ARIUpdate        `bson:"updates" json:"updates"`
+	Status        OutboxStatus       `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time          `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	LastError     string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+}
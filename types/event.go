@@ -0,0 +1,37 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventBookingCreated  = "booking.created"
+	EventBookingCanceled = "booking.canceled"
+	EventBookingModified = "booking.modified"
+	EventUserCreated     = "user.created"
+)
+
+// Event is a domain event. Stores write it to the outbox in the same
+// transaction as the change it describes, and the event relay publishes it to
+// subscribers afterwards. Data holds the JSON encoding of the changed
+// resource, identified by SubjectID.
+type Event struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Type          string             `bson:"type" json:"type"`
+	SubjectID     primitive.ObjectID `bson:"subjectID" json:"subjectID"`
+	Data          json.RawMessage    `bson:"data" json:"data"`
+	OccurredAt    time.Time          `bson:"occurredAt" json:"occurredAt"`
+	Dispatched    bool               `bson:"dispatched" json:"dispatched"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time          `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	LastError     string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+
+	// Delivered names the subscribers that accepted the event, so a retry
+	// only reaches the ones that failed.
+	Delivered []string `bson:"delivered,omitempty" json:"delivered,omitempty"`
+	// Dead is set once the relay gave up on the event after MaxAttempts.
+	Dead bool `bson:"dead" json:"dead"`
+}
@@ -1,17 +1,216 @@
 package types
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"regexp"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxDescriptionLength = 10000
+
+var timeOfDayReg = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
 
 type Hotel struct {
-	ID       primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
-	Name     string               `bson:"name" json:"name"`
-	Location string               `bson:"location" json:"location"`
-	Rooms    []primitive.ObjectID `bson:"rooms" json:"rooms"`
-	Rating   int                  `bson:"rating" json:"rating"`
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string               `bson:"name" json:"name"`
+	Location    string               `bson:"location" json:"location"`
+	Address     *Address             `bson:"address,omitempty" json:"address,omitempty"`
+	Geo         *GeoPoint            `bson:"geo,omitempty" json:"geo,omitempty"`
+	Description string               `bson:"description,omitempty" json:"description,omitempty"`
+	Amenities   []string             `bson:"amenities,omitempty" json:"amenities,omitempty"`
+	CheckIn     string               `bson:"checkIn,omitempty" json:"checkIn,omitempty"`
+	CheckOut    string               `bson:"checkOut,omitempty" json:"checkOut,omitempty"`
+	Policies    *HotelPolicies       `bson:"policies,omitempty" json:"policies,omitempty"`
+	Images      []HotelImage         `bson:"images,omitempty" json:"images,omitempty"`
+	Rooms       []primitive.ObjectID `bson:"rooms" json:"rooms"`
+	// Rating is the average overall score of approved reviews, kept up to
+	// date by the review store.
+	Rating      float64 `bson:"rating" json:"rating"`
+	ReviewCount int     `bson:"reviewCount" json:"reviewCount"`
+}
+
+type Address struct {
+	Street     string `bson:"street" json:"street"`
+	City       string `bson:"city" json:"city"`
+	PostalCode string `bson:"postalCode" json:"postalCode"`
+	Region     string `bson:"region,omitempty" json:"region,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code.
+	Country string `bson:"country" json:"country"`
+}
+
+func (a *Address) validate(errors map[string]string) {
+	if len(a.City) == 0 {
+		errors["address.city"] = "city is required"
+	}
+	if len(a.Country) != 2 {
+		errors["address.country"] = "country should be an ISO 3166-1 alpha-2 code"
+	}
+}
+
+// GeoPoint is a GeoJSON point, so MongoDB can index it for geospatial
+// queries. Coordinates are longitude first.
+type GeoPoint struct {
+	Type        string     `bson:"type" json:"type"`
+	Coordinates [2]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+func NewGeoPoint(lat, lng float64) *GeoPoint {
+	return &GeoPoint{
+		Type:        "Point",
+		Coordinates: [2]float64{lng, lat},
+	}
+}
+
+func (p *GeoPoint) Lat() float64 { return p.Coordinates[1] }
+func (p *GeoPoint) Lng() float64 { return p.Coordinates[0] }
+
+// HotelDistance is a hotel found by a geospatial search together with its
+// distance from the searched point.
+type HotelDistance struct {
+	Hotel      `bson:",inline"`
+	DistanceKm float64 `bson:"distanceKm" json:"distanceKm"`
 }
 
+// HotelMatch is a hotel found by a text search together with its relevance
+// score. Scores only compare matches of the same search.
+type HotelMatch struct {
+	Hotel `bson:",inline"`
+	Score float64 `bson:"score" json:"score"`
+}
+
+type HotelPolicies struct {
+	Cancellation    string `bson:"cancellation,omitempty" json:"cancellation,omitempty"`
+	PetsAllowed     bool   `bson:"petsAllowed" json:"petsAllowed"`
+	SmokingAllowed  bool   `bson:"smokingAllowed" json:"smokingAllowed"`
+	ChildrenAllowed bool   `bson:"childrenAllowed" json:"childrenAllowed"`
+	MinCheckInAge   int    `bson:"minCheckInAge,omitempty" json:"minCheckInAge,omitempty"`
+}
+
+// HotelImage is an entry of a hotel's gallery, which is shown in slice order.
+// Key identifies the file in media storage.
+type HotelImage struct {
+	Key         string `bson:"key" json:"key"`
+	URL         string `bson:"url" json:"url"`
+	Caption     string `bson:"caption,omitempty" json:"caption,omitempty"`
+	ContentType string `bson:"contentType" json:"contentType"`
+}
+
+const (
+	AmenityWifi            = "wifi"
+	AmenityParking         = "parking"
+	AmenityPool            = "pool"
+	AmenityGym             = "gym"
+	AmenitySpa             = "spa"
+	AmenityRestaurant      = "restaurant"
+	AmenityBar             = "bar"
+	AmenityRoomService     = "room_service"
+	AmenityAirConditioning = "air_conditioning"
+	AmenityPetFriendly     = "pet_friendly"
+	AmenityAirportShuttle  = "airport_shuttle"
+	AmenityBeachAccess     = "beach_access"
+	AmenityAccessible      = "accessible"
+	AmenityBreakfast       = "breakfast"
+	AmenityEVCharging      = "ev_charging"
+)
+
+// Amenities is the controlled vocabulary hotel amenities are picked from.
+var Amenities = []string{
+	AmenityWifi,
+	AmenityParking,
+	AmenityPool,
+	AmenityGym,
+	AmenitySpa,
+	AmenityRestaurant,
+	AmenityBar,
+	AmenityRoomService,
+	AmenityAirConditioning,
+	AmenityPetFriendly,
+	AmenityAirportShuttle,
+	AmenityBeachAccess,
+	AmenityAccessible,
+	AmenityBreakfast,
+	AmenityEVCharging,
+}
+
+// UpdateHotelParams updates the listing details of a hotel. Fields left out
+// of the request are not changed.
 type UpdateHotelParams struct {
-	Name     string               `bson:"name" json:"name"`
-	Location string               `bson:"location" json:"location"`
-	Rooms    []primitive.ObjectID `bson:"rooms" json:"rooms"`
+	Name        string         `json:"name"`
+	Location    string         `json:"location"`
+	Address     *Address       `json:"address"`
+	Latitude    *float64       `json:"latitude"`
+	Longitude   *float64       `json:"longitude"`
+	Description *string        `json:"description"`
+	Amenities   []string       `json:"amenities"`
+	CheckIn     string         `json:"checkIn"`
+	CheckOut    string         `json:"checkOut"`
+	Policies    *HotelPolicies `json:"policies"`
+}
+
+func (params UpdateHotelParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if params.Address != nil {
+		params.Address.validate(errors)
+	}
+	if (params.Latitude == nil) != (params.Longitude == nil) {
+		errors["latitude"] = "latitude and longitude should be given together"
+	}
+	if params.Latitude != nil && (*params.Latitude < -90 || *params.Latitude > 90) {
+		errors["latitude"] = "latitude should be between -90 and 90"
+	}
+	if params.Longitude != nil && (*params.Longitude < -180 || *params.Longitude > 180) {
+		errors["longitude"] = "longitude should be between -180 and 180"
+	}
+	if params.Description != nil && len(*params.Description) > maxDescriptionLength {
+		errors["description"] = "description should be at most 10000 characters"
+	}
+	for _, amenity := range params.Amenities {
+		if !slices.Contains(Amenities, amenity) {
+			errors["amenities"] = "unknown amenity " + amenity
+		}
+	}
+	if len(params.CheckIn) > 0 && !timeOfDayReg.MatchString(params.CheckIn) {
+		errors["checkIn"] = "checkIn should be a time of day like 15:00"
+	}
+	if len(params.CheckOut) > 0 && !timeOfDayReg.MatchString(params.CheckOut) {
+		errors["checkOut"] = "checkOut should be a time of day like 11:00"
+	}
+	if params.Policies != nil && params.Policies.MinCheckInAge < 0 {
+		errors["policies.minCheckInAge"] = "minCheckInAge should not be negative"
+	}
+	return errors
+}
+
+func (params UpdateHotelParams) ToBson() bson.M {
+	m := bson.M{}
+	if len(params.Name) > 0 {
+		m["name"] = params.Name
+	}
+	if len(params.Location) > 0 {
+		m["location"] = params.Location
+	}
+	if params.Address != nil {
+		m["address"] = params.Address
+	}
+	if params.Latitude != nil && params.Longitude != nil {
+		m["geo"] = NewGeoPoint(*params.Latitude, *params.Longitude)
+	}
+	if params.Description != nil {
+		m["description"] = *params.Description
+	}
+	if params.Amenities != nil {
+		m["amenities"] = params.Amenities
+	}
+	if len(params.CheckIn) > 0 {
+		m["checkIn"] = params.CheckIn
+	}
+	if len(params.CheckOut) > 0 {
+		m["checkOut"] = params.CheckOut
+	}
+	if params.Policies != nil {
+		m["policies"] = params.Policies
+	}
+	return m
 }
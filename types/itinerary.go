@@ -0,0 +1,71 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxItineraryLegs = 10
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending PaymentStatus = "pending"
+	PaymentStatusPaid    PaymentStatus = "paid"
+)
+
+// Itinerary groups several room bookings, possibly at different hotels or
+// dates, that are created, priced and paid together.
+type Itinerary struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID        primitive.ObjectID   `bson:"userID" json:"userID"`
+	BookingIDs    []primitive.ObjectID `bson:"bookingIDs" json:"bookingIDs"`
+	TotalPrice    float64              `bson:"totalPrice" json:"totalPrice"`
+	PaymentStatus PaymentStatus        `bson:"paymentStatus" json:"paymentStatus"`
+	PaidAt        time.Time            `bson:"paidAt,omitempty" json:"paidAt,omitempty"`
+	Canceled      bool                 `bson:"canceled" json:"canceled"`
+	CreatedAt     time.Time            `bson:"createdAt" json:"createdAt"`
+}
+
+type ItineraryLegParams struct {
+	RoomID     string    `json:"roomID"`
+	FromDate   time.Time `json:"fromDate"`
+	TillDate   time.Time `json:"tillDate"`
+	NumPersons int       `json:"numPersons"`
+}
+
+type CreateItineraryParams struct {
+	Legs []ItineraryLegParams `json:"legs"`
+}
+
+func (params CreateItineraryParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(params.Legs) == 0 || len(params.Legs) > maxItineraryLegs {
+		errors["legs"] = fmt.Sprintf("legs should contain between 1 and %d bookings", maxItineraryLegs)
+	}
+	now := time.Now()
+	for i, leg := range params.Legs {
+		key := fmt.Sprintf("legs[%d]", i)
+		if !primitive.IsValidObjectID(leg.RoomID) {
+			errors[key+".roomID"] = "invalid roomID"
+		}
+		if leg.FromDate.Before(now) {
+			errors[key+".fromDate"] = "fromDate should be in the future"
+		}
+		if !leg.FromDate.Before(leg.TillDate) {
+			errors[key+".tillDate"] = "tillDate should be after fromDate"
+		}
+		if leg.NumPersons <= 0 {
+			errors[key+".numPersons"] = "numPersons should be positive"
+		}
+		for j := 0; j < i; j++ {
+			other := params.Legs[j]
+			if other.RoomID == leg.RoomID && !other.FromDate.After(leg.TillDate) && !other.TillDate.Before(leg.FromDate) {
+				errors[key] = fmt.Sprintf("overlaps legs[%d] for the same room", j)
+			}
+		}
+	}
+	return errors
+}
@@ -0,0 +1,120 @@
+package types
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TemplateBookingConfirmation = "booking_confirmation"
+	TemplateBookingModification = "booking_modification"
+	TemplateBookingCancellation = "booking_cancellation"
+	TemplatePreArrivalReminder  = "pre_arrival_reminder"
+	TemplateReceipt             = "receipt"
+	TemplateWaitlistHold        = "waitlist_hold"
+)
+
+// TemplateAccountLocked is a security message. It is not in
+// NotificationTemplates, so users cannot opt out of it.
+const TemplateAccountLocked = "account_locked"
+
+var NotificationTemplates = []string{
+	TemplateBookingConfirmation,
+	TemplateBookingModification,
+	TemplateBookingCancellation,
+	TemplatePreArrivalReminder,
+	TemplateReceipt,
+	TemplateWaitlistHold,
+}
+
+const DefaultLocale = "en"
+
+var Locales = []string{DefaultLocale, "es"}
+
+// NotificationPreferences are a user's choices about the messages we send
+// them. Token identifies the user in unsubscribe links, which work without
+// logging in.
+type NotificationPreferences struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID       primitive.ObjectID `bson:"userID" json:"userID"`
+	Locale       string             `bson:"locale" json:"locale"`
+	OptedOut     []string           `bson:"optedOut" json:"optedOut"`
+	Unsubscribed bool               `bson:"unsubscribed" json:"unsubscribed"`
+	Token        string             `bson:"token" json:"-"`
+}
+
+func (p *NotificationPreferences) Wants(template string) bool {
+	if template == TemplateAccountLocked {
+		return true
+	}
+	if p.Unsubscribed {
+		return false
+	}
+	return !slices.Contains(p.OptedOut, template)
+}
+
+type UpdateNotificationPreferencesParams struct {
+	Locale       string   `json:"locale"`
+	OptedOut     []string `json:"optedOut"`
+	Unsubscribed bool     `json:"unsubscribed"`
+}
+
+func (params UpdateNotificationPreferencesParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(params.Locale) > 0 && !slices.Contains(Locales, params.Locale) {
+		errors["locale"] = "unsupported locale " + params.Locale
+	}
+	for _, template := range params.OptedOut {
+		if !slices.Contains(NotificationTemplates, template) {
+			errors["optedOut"] = "unknown notification " + template
+		}
+	}
+	return errors
+}
+
+func (params UpdateNotificationPreferencesParams) ToBson() map[string]any {
+	m := map[string]any{
+		"optedOut":     params.OptedOut,
+		"unsubscribed": params.Unsubscribed,
+	}
+	if params.OptedOut == nil {
+		m["optedOut"] = []string{}
+	}
+	if len(params.Locale) > 0 {
+		m["locale"] = params.Locale
+	}
+	return m
+}
+
+type NotificationStatus string
+
+const (
+	NotificationStatusPending NotificationStatus = "pending"
+	NotificationStatusSent    NotificationStatus = "sent"
+	NotificationStatusFailed  NotificationStatus = "failed"
+	// NotificationStatusSkipped is logged for messages the user opted out of.
+	NotificationStatusSkipped NotificationStatus = "skipped"
+)
+
+// Notification is a rendered message and its delivery state. Every message is
+// kept, which makes the collection the notification log. Key identifies what
+// the message is about, so the same message is never queued twice.
+type Notification struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Key           string             `bson:"key" json:"key"`
+	UserID        primitive.ObjectID `bson:"userID" json:"userID"`
+	Template      string             `bson:"template" json:"template"`
+	Locale        string             `bson:"locale" json:"locale"`
+	Recipient     string             `bson:"recipient" json:"recipient"`
+	Subject       string             `bson:"subject" json:"subject"`
+	Body          string             `bson:"body" json:"body"`
+	Unsubscribe   string             `bson:"unsubscribe,omitempty" json:"unsubscribe,omitempty"`
+	Status        NotificationStatus `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time          `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	LastError     string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	SentAt        time.Time          `bson:"sentAt,omitempty" json:"sentAt,omitempty"`
+}
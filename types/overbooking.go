@@ -0,0 +1,107 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OverbookingAllowance lets a hotel sell up to Limit bookings beyond the
+// physical rooms of a type for each night in the range, both ends inclusive.
+type OverbookingAllowance struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	HotelID  primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	RoomType string             `bson:"roomType" json:"roomType"`
+	FromDate time.Time          `bson:"fromDate" json:"fromDate"`
+	TillDate time.Time          `bson:"tillDate" json:"tillDate"`
+	Limit    int                `bson:"limit" json:"limit"`
+}
+
+type CreateOverbookingAllowanceParams struct {
+	HotelID  string    `json:"hotelID"`
+	RoomType string    `json:"roomType"`
+	FromDate time.Time `json:"fromDate"`
+	TillDate time.Time `json:"tillDate"`
+	Limit    int       `json:"limit"`
+}
+
+func (params CreateOverbookingAllowanceParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if !primitive.IsValidObjectID(params.HotelID) {
+		errors["hotelID"] = "invalid hotelID"
+	}
+	if len(params.RoomType) == 0 {
+		errors["roomType"] = "roomType is required"
+	}
+	if params.TillDate.Before(params.FromDate) {
+		errors["tillDate"] = "tillDate should not be before fromDate"
+	}
+	if params.Limit <= 0 {
+		errors["limit"] = "limit should be positive"
+	}
+	return errors
+}
+
+func NewOverbookingAllowanceFromParams(params CreateOverbookingAllowanceParams) *OverbookingAllowance {
+	hotelID, _ := primitive.ObjectIDFromHex(params.HotelID)
+	return &OverbookingAllowance{
+		HotelID:  hotelID,
+		RoomType: params.RoomType,
+		FromDate: Night(params.FromDate),
+		TillDate: Night(params.TillDate),
+		Limit:    params.Limit,
+	}
+}
+
+// LimitFor returns the overbooking limit for a night, the largest of the
+// allowances covering it.
+func LimitFor(allowances []*OverbookingAllowance, night time.Time) int {
+	limit := 0
+	for _, a := range allowances {
+		if !night.Before(a.FromDate) && !night.After(a.TillDate) && a.Limit > limit {
+			limit = a.Limit
+		}
+	}
+	return limit
+}
+
+// OversoldNight reports the bookings sold for a room type on a night against
+// the rooms physically available.
+type OversoldNight struct {
+	Date     time.Time `json:"date"`
+	RoomType string    `json:"roomType"`
+	Capacity int       `json:"capacity"`
+	Sold     int       `json:"sold"`
+	Oversold int       `json:"oversold"`
+}
+
+// Walk records a guest relocated to a partner hotel because their booking
+// could not be honored.
+type Walk struct {
+	PartnerHotel string    `bson:"partnerHotel" json:"partnerHotel"`
+	Reason       string    `bson:"reason" json:"reason"`
+	Compensation float64   `bson:"compensation" json:"compensation"`
+	WalkedAt     time.Time `bson:"walkedAt" json:"walkedAt"`
+}
+
+type WalkParams struct {
+	PartnerHotel string  `json:"partnerHotel"`
+	Reason       string  `json:"reason"`
+	Compensation float64 `json:"compensation"`
+}
+
+func (params WalkParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(params.PartnerHotel) == 0 {
+		errors["partnerHotel"] = "partnerHotel is required"
+	}
+	if params.Compensation < 0 {
+		errors["compensation"] = "compensation should not be negative"
+	}
+	return errors
+}
+
+// CoversNight reports whether the guest stays over the given night.
+func (b *Booking) CoversNight(night time.Time) bool {
+	return !night.Before(Night(b.FromDate)) && night.Before(Night(b.TillDate))
+}
@@ -0,0 +1,173 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week"
+	GranularityMonth Granularity = "month"
+)
+
+func (g Granularity) IsValid() bool {
+	return g == GranularityDay || g == GranularityWeek || g == GranularityMonth
+}
+
+// PeriodStart truncates t to the start of its period in UTC. Weeks start on
+// Monday, matching $dateTrunc with startOfWeek "monday".
+func (g Granularity) PeriodStart(t time.Time) time.Time {
+	night := Night(t)
+	switch g {
+	case GranularityWeek:
+		offset := (int(night.Weekday()) + 6) % 7
+		return night.AddDate(0, 0, -offset)
+	case GranularityMonth:
+		return time.Date(night.Year(), night.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return night
+	}
+}
+
+func (g Granularity) next(start time.Time) time.Time {
+	switch g {
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// PeriodSales is the room nights sold and the revenue earned in a period. A
+// stay spanning several periods contributes each night to the period it
+// falls in, along with its share of the booking price.
+type PeriodSales struct {
+	Period     time.Time `bson:"_id" json:"period"`
+	SoldNights int       `bson:"soldNights" json:"soldNights"`
+	Revenue    float64   `bson:"revenue" json:"revenue"`
+}
+
+// KPI holds the standard hotel performance indicators for a period.
+// Occupancy is a percentage of available room nights, ADR is the average
+// daily rate and RevPAR the revenue per available room night.
+type KPI struct {
+	Period          time.Time `json:"period"`
+	AvailableNights int       `json:"availableNights"`
+	SoldNights      int       `json:"soldNights"`
+	Revenue         float64   `json:"revenue"`
+	Occupancy       float64   `json:"occupancy"`
+	ADR             float64   `json:"adr"`
+	RevPAR          float64   `json:"revpar"`
+}
+
+func (k *KPI) add(sales PeriodSales) {
+	k.SoldNights += sales.SoldNights
+	k.Revenue += sales.Revenue
+}
+
+func (k *KPI) compute() {
+	if k.AvailableNights > 0 {
+		k.Occupancy = 100 * float64(k.SoldNights) / float64(k.AvailableNights)
+		k.RevPAR = k.Revenue / float64(k.AvailableNights)
+	}
+	if k.SoldNights > 0 {
+		k.ADR = k.Revenue / float64(k.SoldNights)
+	}
+}
+
+// KPIChange is the difference between a period's totals and the prior one.
+type KPIChange struct {
+	Occupancy float64 `json:"occupancy"`
+	ADR       float64 `json:"adr"`
+	RevPAR    float64 `json:"revpar"`
+}
+
+type KPIReport struct {
+	HotelID     primitive.ObjectID `json:"hotelID"`
+	Granularity Granularity        `json:"granularity"`
+	FromDate    time.Time          `json:"fromDate"`
+	TillDate    time.Time          `json:"tillDate"`
+	Periods     []KPI              `json:"periods"`
+	Total       KPI                `json:"total"`
+	Previous    KPI                `json:"previous"`
+	Change      KPIChange          `json:"change"`
+}
+
+// BuildKPIReport computes per period and total indicators for the nights in
+// [from, till) of a hotel with the given number of rooms. previous holds the
+// sales of the equally long range right before from.
+func BuildKPIReport(hotelID primitive.ObjectID, granularity Granularity, rooms int, from, till time.Time, current, previous []PeriodSales) *KPIReport {
+	from, till = Night(from), Night(till)
+	report := &KPIReport{
+		HotelID:     hotelID,
+		Granularity: granularity,
+		FromDate:    from,
+		TillDate:    till,
+		Periods:     []KPI{},
+	}
+
+	byPeriod := map[time.Time]PeriodSales{}
+	for _, sales := range current {
+		byPeriod[sales.Period.UTC()] = sales
+	}
+	for start := granularity.PeriodStart(from); start.Before(till); start = granularity.next(start) {
+		end := granularity.next(start)
+		kpi := KPI{
+			Period:          start,
+			AvailableNights: rooms * nightsBetween(maxTime(start, from), minTime(end, till)),
+		}
+		kpi.add(byPeriod[start])
+		kpi.compute()
+		report.Periods = append(report.Periods, kpi)
+
+		report.Total.AvailableNights += kpi.AvailableNights
+		report.Total.add(byPeriod[start])
+	}
+	report.Total.Period = from
+	report.Total.compute()
+
+	length := nightsBetween(from, till)
+	report.Previous = KPI{
+		Period:          from.AddDate(0, 0, -length),
+		AvailableNights: rooms * length,
+	}
+	for _, sales := range previous {
+		report.Previous.add(sales)
+	}
+	report.Previous.compute()
+
+	report.Change = KPIChange{
+		Occupancy: report.Total.Occupancy - report.Previous.Occupancy,
+		ADR:       report.Total.ADR - report.Previous.ADR,
+		RevPAR:    report.Total.RevPAR - report.Previous.RevPAR,
+	}
+
+	return report
+}
+
+func nightsBetween(from, till time.Time) int {
+	if !from.Before(till) {
+		return 0
+	}
+	return int(till.Sub(from).Hours() / 24)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
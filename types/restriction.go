@@ -0,0 +1,121 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StayRestriction limits how a hotel's rooms can be sold over a range of
+// nights, both ends inclusive. It applies to a single room when RoomID is
+// set, to all rooms of a type when RoomType is set, and to the whole hotel
+// otherwise.
+type StayRestriction struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	HotelID           primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	RoomID            primitive.ObjectID `bson:"roomID,omitempty" json:"roomID,omitempty"`
+	RoomType          string             `bson:"roomType,omitempty" json:"roomType,omitempty"`
+	FromDate          time.Time          `bson:"fromDate" json:"fromDate"`
+	TillDate          time.Time          `bson:"tillDate" json:"tillDate"`
+	MinStay           int                `bson:"minStay,omitempty" json:"minStay,omitempty"`
+	MaxStay           int                `bson:"maxStay,omitempty" json:"maxStay,omitempty"`
+	ClosedToArrival   bool               `bson:"closedToArrival" json:"closedToArrival"`
+	ClosedToDeparture bool               `bson:"closedToDeparture" json:"closedToDeparture"`
+	StopSell          bool               `bson:"stopSell" json:"stopSell"`
+}
+
+type CreateStayRestrictionParams struct {
+	HotelID           string    `json:"hotelID"`
+	RoomID            string    `json:"roomID"`
+	RoomType          string    `json:"roomType"`
+	FromDate          time.Time `json:"fromDate"`
+	TillDate          time.Time `json:"tillDate"`
+	MinStay           int       `json:"minStay"`
+	MaxStay           int       `json:"maxStay"`
+	ClosedToArrival   bool      `json:"closedToArrival"`
+	ClosedToDeparture bool      `json:"closedToDeparture"`
+	StopSell          bool      `json:"stopSell"`
+}
+
+func (params CreateStayRestrictionParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if !primitive.IsValidObjectID(params.HotelID) {
+		errors["hotelID"] = "invalid hotelID"
+	}
+	if len(params.RoomID) > 0 && !primitive.IsValidObjectID(params.RoomID) {
+		errors["roomID"] = "invalid roomID"
+	}
+	if params.TillDate.Before(params.FromDate) {
+		errors["tillDate"] = "tillDate should not be before fromDate"
+	}
+	if params.MinStay < 0 || params.MaxStay < 0 {
+		errors["minStay"] = "minStay and maxStay should not be negative"
+	}
+	if params.MaxStay > 0 && params.MinStay > params.MaxStay {
+		errors["maxStay"] = "maxStay should not be less than minStay"
+	}
+	return errors
+}
+
+func NewStayRestrictionFromParams(params CreateStayRestrictionParams) *StayRestriction {
+	restriction := &StayRestriction{
+		RoomType:          params.RoomType,
+		FromDate:          Night(params.FromDate),
+		TillDate:          Night(params.TillDate),
+		MinStay:           params.MinStay,
+		MaxStay:           params.MaxStay,
+		ClosedToArrival:   params.ClosedToArrival,
+		ClosedToDeparture: params.ClosedToDeparture,
+		StopSell:          params.StopSell,
+	}
+	restriction.HotelID, _ = primitive.ObjectIDFromHex(params.HotelID)
+	restriction.RoomID, _ = primitive.ObjectIDFromHex(params.RoomID)
+	return restriction
+}
+
+func (r *StayRestriction) covers(night time.Time) bool {
+	return !night.Before(r.FromDate) && !night.After(r.TillDate)
+}
+
+// CheckStay returns the reason a stay breaks the given restrictions, or an
+// empty string when it is allowed. Length of stay and closed to arrival are
+// checked on the arrival night, closed to departure on the departure day and
+// stop-sell on every night of the stay.
+func CheckStay(restrictions []*StayRestriction, from, till time.Time) string {
+	var (
+		arrival   = Night(from)
+		departure = Night(till)
+		nights    = Nights(from, till)
+	)
+	for _, r := range restrictions {
+		if r.covers(arrival) {
+			if r.ClosedToArrival {
+				return fmt.Sprintf("arrivals are closed on %s", arrival.Format(time.DateOnly))
+			}
+			if r.MinStay > 0 && nights < r.MinStay {
+				return fmt.Sprintf("a minimum stay of %d nights is required when arriving on %s", r.MinStay, arrival.Format(time.DateOnly))
+			}
+			if r.MaxStay > 0 && nights > r.MaxStay {
+				return fmt.Sprintf("a maximum stay of %d nights is allowed when arriving on %s", r.MaxStay, arrival.Format(time.DateOnly))
+			}
+		}
+		if r.ClosedToDeparture && r.covers(departure) {
+			return fmt.Sprintf("departures are closed on %s", departure.Format(time.DateOnly))
+		}
+		if r.StopSell {
+			for night := arrival; night.Before(departure); night = night.AddDate(0, 0, 1) {
+				if r.covers(night) {
+					return fmt.Sprintf("the room is not sold on %s", night.Format(time.DateOnly))
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Night truncates t to midnight UTC, the key restrictions are stored under.
+func Night(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	minReviewScore   = 1
+	maxReviewScore   = 5
+	maxReviewTextLen = 5000
+	maxReplyTextLen  = 2000
+)
+
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "pending"
+	ReviewStatusApproved ReviewStatus = "approved"
+	ReviewStatusRejected ReviewStatus = "rejected"
+)
+
+// Review is a guest's review of a completed stay. Only approved reviews are
+// public and count towards the hotel's rating.
+type Review struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	HotelID        primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	BookingID      primitive.ObjectID `bson:"bookingID" json:"bookingID"`
+	UserID         primitive.ObjectID `bson:"userID" json:"userID"`
+	Cleanliness    int                `bson:"cleanliness" json:"cleanliness"`
+	Location       int                `bson:"location" json:"location"`
+	Service        int                `bson:"service" json:"service"`
+	Overall        float64            `bson:"overall" json:"overall"`
+	Text           string             `bson:"text" json:"text"`
+	PhotoRef       string             `bson:"photoRef,omitempty" json:"photoRef,omitempty"`
+	Status         ReviewStatus       `bson:"status" json:"status"`
+	ModerationNote string             `bson:"moderationNote,omitempty" json:"moderationNote,omitempty"`
+	Reply          *ReviewReply       `bson:"reply,omitempty" json:"reply,omitempty"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// ReviewReply is the hotel's public answer to a review.
+type ReviewReply struct {
+	Text      string    `bson:"text" json:"text"`
+	RepliedAt time.Time `bson:"repliedAt" json:"repliedAt"`
+}
+
+type CreateReviewParams struct {
+	BookingID   string `json:"bookingID"`
+	Cleanliness int    `json:"cleanliness"`
+	Location    int    `json:"location"`
+	Service     int    `json:"service"`
+	Text        string `json:"text"`
+	PhotoRef    string `json:"photoRef"`
+}
+
+func (params CreateReviewParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if !primitive.IsValidObjectID(params.BookingID) {
+		errors["bookingID"] = "invalid bookingID"
+	}
+	scores := map[string]int{
+		"cleanliness": params.Cleanliness,
+		"location":    params.Location,
+		"service":     params.Service,
+	}
+	for field, score := range scores {
+		if score < minReviewScore || score > maxReviewScore {
+			errors[field] = "score should be between 1 and 5"
+		}
+	}
+	if len(params.Text) > maxReviewTextLen {
+		errors["text"] = "text should be at most 5000 characters"
+	}
+	return errors
+}
+
+func NewReviewFromParams(params CreateReviewParams, booking *Booking, hotelID primitive.ObjectID) *Review {
+	return &Review{
+		HotelID:     hotelID,
+		BookingID:   booking.ID,
+		UserID:      booking.UserID,
+		Cleanliness: params.Cleanliness,
+		Location:    params.Location,
+		Service:     params.Service,
+		Overall:     float64(params.Cleanliness+params.Location+params.Service) / 3,
+		Text:        params.Text,
+		PhotoRef:    params.PhotoRef,
+		Status:      ReviewStatusPending,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+type ModerateReviewParams struct {
+	Status ReviewStatus `json:"status"`
+	Note   string       `json:"note"`
+}
+
+func (params ModerateReviewParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if params.Status != ReviewStatusApproved && params.Status != ReviewStatusRejected {
+		errors["status"] = "status should be approved or rejected"
+	}
+	return errors
+}
+
+type ReplyReviewParams struct {
+	Text string `json:"text"`
+}
+
+func (params ReplyReviewParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(params.Text) == 0 {
+		errors["text"] = "text is required"
+	}
+	if len(params.Text) > maxReplyTextLen {
+		errors["text"] = "text should be at most 2000 characters"
+	}
+	return errors
+}
+
+// CanBeReviewed reports whether the booking is a stay its guest completed.
+func (b *Booking) CanBeReviewed(now time.Time) bool {
+	return !b.Canceled && b.Walk == nil && !b.UserID.IsZero() && b.TillDate.Before(now)
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -13,6 +15,11 @@ type Room struct {
 	HotelID primitive.ObjectID `bson:"hotelID" json:"hotelID"`
 }
 
+// PriceFor returns the price of staying in the room between two dates.
+func (r *Room) PriceFor(from, till time.Time) float64 {
+	return float64(Nights(from, till)) * r.Price
+}
+
 type UpdateRoomParams struct {
 	BasePrice float64 `bson:"basePrice" json:"basePrice"`
 	Price     float64 `bson:"price" json:"price"`
@@ -0,0 +1,20 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SecurityPolicy holds the security settings admins can change at runtime.
+// RequireAdminTwoFactor keeps admin endpoints closed to admins who did not
+// log in with two-factor authentication.
+type SecurityPolicy struct {
+	RequireAdminTwoFactor bool               `bson:"requireAdminTwoFactor" json:"requireAdminTwoFactor"`
+	UpdatedBy             primitive.ObjectID `bson:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+	UpdatedAt             time.Time          `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
+
+type UpdateSecurityPolicyParams struct {
+	RequireAdminTwoFactor *bool `json:"requireAdminTwoFactor"`
+}
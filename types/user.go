@@ -2,7 +2,9 @@ package types
 
 import (
 	"fmt"
+	"log/slog"
 	"regexp"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -24,6 +26,29 @@ type User struct {
 	Email          string             `bson:"email" json:"email"`
 	HashedPassword string             `bson:"hashed_password" json:"-"`
 	IsAdmin        bool               `bson:"isAdmin" json:"isAdmin"`
+	// LockedUntil is set when the account is locked after too many failed
+	// logins. UnlockToken is the hash of the token in the unlock email.
+	LockedUntil time.Time `bson:"lockedUntil,omitempty" json:"-"`
+	UnlockToken string    `bson:"unlockToken,omitempty" json:"-"`
+	// TOTPSecret is set on enrollment and TwoFactorEnabled once a code
+	// confirmed it. TOTPLastStep is the period of the last accepted code and
+	// RecoveryCodes the hashes of the recovery codes not used yet.
+	TwoFactorEnabled bool     `bson:"twoFactorEnabled" json:"twoFactorEnabled"`
+	TOTPSecret       string   `bson:"totpSecret,omitempty" json:"-"`
+	TOTPLastStep     int64    `bson:"totpLastStep,omitempty" json:"-"`
+	RecoveryCodes    []string `bson:"recoveryCodes,omitempty" json:"-"`
+}
+
+func (u *User) IsLocked(now time.Time) bool {
+	return now.Before(u.LockedUntil)
+}
+
+// LogValue keeps the password hash and personal details out of logs.
+func (u *User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", u.ID.Hex()),
+		slog.Bool("isAdmin", u.IsAdmin),
+	)
 }
 
 type CreateUserParams struct {
@@ -0,0 +1,87 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting   WaitlistStatus = "waiting"
+	WaitlistStatusOffered   WaitlistStatus = "offered"
+	WaitlistStatusBooked    WaitlistStatus = "booked"
+	WaitlistStatusExpired   WaitlistStatus = "expired"
+	WaitlistStatusWithdrawn WaitlistStatus = "withdrawn"
+)
+
+// WaitlistEntry is a guest's request for a sold-out room, or for any room of a
+// given type (size) at a hotel. When inventory frees up the entry is offered an
+// exclusive hold on a concrete room until HoldExpiresAt.
+type WaitlistEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID        primitive.ObjectID `bson:"userID" json:"userID"`
+	RoomID        primitive.ObjectID `bson:"roomID,omitempty" json:"roomID,omitempty"`
+	HotelID       primitive.ObjectID `bson:"hotelID,omitempty" json:"hotelID,omitempty"`
+	RoomType      string             `bson:"roomType,omitempty" json:"roomType,omitempty"`
+	FromDate      time.Time          `bson:"fromDate" json:"fromDate"`
+	TillDate      time.Time          `bson:"tillDate" json:"tillDate"`
+	NumPersons    int                `bson:"numPersons" json:"numPersons"`
+	Status        WaitlistStatus     `bson:"status" json:"status"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	HoldRoomID    primitive.ObjectID `bson:"holdRoomID,omitempty" json:"holdRoomID,omitempty"`
+	HoldExpiresAt time.Time          `bson:"holdExpiresAt,omitempty" json:"holdExpiresAt,omitempty"`
+}
+
+type CreateWaitlistEntryParams struct {
+	RoomID     string    `json:"roomID"`
+	HotelID    string    `json:"hotelID"`
+	RoomType   string    `json:"roomType"`
+	FromDate   time.Time `json:"fromDate"`
+	TillDate   time.Time `json:"tillDate"`
+	NumPersons int       `json:"numPersons"`
+}
+
+func (params CreateWaitlistEntryParams) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(params.RoomID) == 0 && (len(params.HotelID) == 0 || len(params.RoomType) == 0) {
+		errors["roomID"] = "either roomID or hotelID and roomType are required"
+	}
+	if len(params.RoomID) > 0 && !primitive.IsValidObjectID(params.RoomID) {
+		errors["roomID"] = "invalid roomID"
+	}
+	if len(params.HotelID) > 0 && !primitive.IsValidObjectID(params.HotelID) {
+		errors["hotelID"] = "invalid hotelID"
+	}
+	if params.FromDate.Before(time.Now()) {
+		errors["fromDate"] = "fromDate should be in the future"
+	}
+	if !params.FromDate.Before(params.TillDate) {
+		errors["tillDate"] = "tillDate should be after fromDate"
+	}
+	if params.NumPersons <= 0 {
+		errors["numPersons"] = "numPersons should be positive"
+	}
+	return errors
+}
+
+func NewWaitlistEntryFromParams(userID primitive.ObjectID, params CreateWaitlistEntryParams) *WaitlistEntry {
+	entry := &WaitlistEntry{
+		UserID:     userID,
+		RoomType:   params.RoomType,
+		FromDate:   params.FromDate,
+		TillDate:   params.TillDate,
+		NumPersons: params.NumPersons,
+		Status:     WaitlistStatusWaiting,
+		CreatedAt:  time.Now(),
+	}
+	entry.RoomID, _ = primitive.ObjectIDFromHex(params.RoomID)
+	entry.HotelID, _ = primitive.ObjectIDFromHex(params.HotelID)
+	return entry
+}
+
+// IsActive reports whether the entry is still waiting or holding a room.
+func (e *WaitlistEntry) IsActive() bool {
+	return e.Status == WaitlistStatusWaiting || e.Status == WaitlistStatusOffered
+}
@@ -0,0 +1,84 @@
+package types
+
+import (
+	"net/url"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookEvents are the event types webhooks can subscribe to.
+var WebhookEvents = []string{
+	EventBookingCreated,
+	EventBookingCanceled,
+	EventBookingModified,
+	EventUserCreated,
+}
+
+// WebhookSubscription is a receiver of events. Secret signs the deliveries
+// and is only shown once, when the subscription is created.
+type WebhookSubscription struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	URL       string             `bson:"url" json:"url"`
+	Events    []string           `bson:"events" json:"events"`
+	Secret    string             `bson:"secret" json:"-"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type CreateWebhookSubscriptionParams struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+func (params CreateWebhookSubscriptionParams) Validate() map[string]string {
+	errors := map[string]string{}
+	u, err := url.Parse(params.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		errors["url"] = "url should be an absolute http or https URL"
+	}
+	if len(params.Events) == 0 {
+		errors["events"] = "at least one event is required"
+	}
+	for _, event := range params.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			errors["events"] = "unknown event " + event
+		}
+	}
+	return errors
+}
+
+func NewWebhookSubscriptionFromParams(params CreateWebhookSubscriptionParams, secret string) *WebhookSubscription {
+	return &WebhookSubscription{
+		URL:       params.URL,
+		Events:    params.Events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	// DeliveryStatusDead marks deliveries that ran out of attempts. Together
+	// they form the dead-letter queue and are only retried by hand.
+	DeliveryStatusDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one event sent to one subscription, kept as the
+// delivery log.
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	SubscriptionID primitive.ObjectID `bson:"subscriptionID" json:"subscriptionID"`
+	EventID        primitive.ObjectID `bson:"eventID" json:"eventID"`
+	EventType      string             `bson:"eventType" json:"eventType"`
+	Status         DeliveryStatus     `bson:"status" json:"status"`
+	Attempts       int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time          `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	ResponseStatus int                `bson:"responseStatus,omitempty" json:"responseStatus,omitempty"`
+	LastError      string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	DeliveredAt    time.Time          `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+}
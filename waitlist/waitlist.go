@@ -0,0 +1,158 @@
+package waitlist
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/availability"
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const DefaultHoldDuration = 2 * time.Hour
+
+// Notifier tells a waitlisted guest that a room is being held for them.
+type Notifier interface {
+	NotifyHold(context.Context, *types.WaitlistEntry) error
+}
+
+// LogNotifier writes hold offers to the default logger.
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyHold(ctx context.Context, entry *types.WaitlistEntry) error {
+	slog.InfoContext(ctx, "waitlist hold offered", "roomID", entry.HoldRoomID.Hex(), "userID", entry.UserID.Hex(), "until", entry.HoldExpiresAt)
+	return nil
+}
+
+// Manager offers freed inventory to waitlisted guests in FIFO order.
+type Manager struct {
+	store        *db.Store
+	checker      *availability.Checker
+	notifier     Notifier
+	holdDuration time.Duration
+}
+
+func NewManager(store *db.Store, notifier Notifier, holdDuration time.Duration) *Manager {
+	return &Manager{
+		store:        store,
+		checker:      availability.NewChecker(store),
+		notifier:     notifier,
+		holdDuration: holdDuration,
+	}
+}
+
+// Release is called when a room frees up. The oldest waiting entry for that
+// room, or for its type at the same hotel when the entry names no room,
+// whose dates now fit gets a hold.
+func (m *Manager) Release(ctx context.Context, roomID primitive.ObjectID) error {
+	room, err := m.store.Room.GetRoomByID(ctx, roomID.Hex())
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"status": types.WaitlistStatusWaiting,
+		"$or": bson.A{
+			bson.M{"roomID": room.ID},
+			bson.M{"hotelID": room.HotelID, "roomType": room.Size, "roomID": bson.M{"$exists": false}},
+		},
+	}
+	entries, err := m.store.Waitlist.GetEntries(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		ok, err := m.checker.IsRoomAvailable(ctx, availability.Request{
+			RoomID:     room.ID,
+			UserID:     entry.UserID,
+			FromDate:   entry.FromDate,
+			TillDate:   entry.TillDate,
+			NumPersons: entry.NumPersons,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		return m.offer(ctx, entry, room.ID)
+	}
+
+	return nil
+}
+
+func (m *Manager) offer(ctx context.Context, entry *types.WaitlistEntry, roomID primitive.ObjectID) error {
+	entry.Status = types.WaitlistStatusOffered
+	entry.HoldRoomID = roomID
+	entry.HoldExpiresAt = time.Now().Add(m.holdDuration)
+	update := bson.M{
+		"status":        entry.Status,
+		"holdRoomID":    entry.HoldRoomID,
+		"holdExpiresAt": entry.HoldExpiresAt,
+	}
+	if err := m.store.Waitlist.UpdateEntry(ctx, entry.ID, update); err != nil {
+		return err
+	}
+
+	return m.notifier.NotifyHold(ctx, entry)
+}
+
+// Claim marks the user's hold on the room as used once they have booked it.
+func (m *Manager) Claim(ctx context.Context, userID, roomID primitive.ObjectID) error {
+	entries, err := m.store.Waitlist.GetEntries(ctx, bson.M{
+		"status":     types.WaitlistStatusOffered,
+		"userID":     userID,
+		"holdRoomID": roomID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := m.store.Waitlist.UpdateEntry(ctx, entry.ID, bson.M{"status": types.WaitlistStatusBooked}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Withdraw removes an entry from the waitlist. A held room is passed on to
+// the next guest in line.
+func (m *Manager) Withdraw(ctx context.Context, entry *types.WaitlistEntry) error {
+	if err := m.store.Waitlist.UpdateEntry(ctx, entry.ID, bson.M{"status": types.WaitlistStatusWithdrawn}); err != nil {
+		return err
+	}
+	if entry.Status == types.WaitlistStatusOffered {
+		return m.Release(ctx, entry.HoldRoomID)
+	}
+
+	return nil
+}
+
+// ExpireHolds ends holds that were not used in time and offers the rooms to
+// the next guests in line. It is meant to be run by the scheduler.
+func (m *Manager) ExpireHolds(ctx context.Context) error {
+	entries, err := m.store.Waitlist.GetEntries(ctx, bson.M{
+		"status":        types.WaitlistStatusOffered,
+		"holdExpiresAt": bson.M{"$lte": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := m.store.Waitlist.UpdateEntry(ctx, entry.ID, bson.M{"status": types.WaitlistStatusExpired}); err != nil {
+			return err
+		}
+		if err := m.Release(ctx, entry.HoldRoomID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+// Package webhook delivers booking and user lifecycle events to subscribed
+// URLs. The Dispatcher receives events from the event broker, turns them into
+// one delivery per subscription and sends them with retries.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aboronilov/go-hotel-reservation/db"
+	"github.com/aboronilov/go-hotel-reservation/retry"
+	"github.com/aboronilov/go-hotel-reservation/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	MaxAttempts    = 8
+	requestTimeout = 10 * time.Second
+	baseBackoff    = 30 * time.Second
+	maxBackoff     = 2 * time.Hour
+	batchSize      = 50
+)
+
+// Payload is the JSON body POSTed to subscribers.
+type Payload struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type Dispatcher struct {
+	store  *db.Store
+	client *http.Client
+}
+
+func NewDispatcher(store *db.Store) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// HandleEvent turns an event into a delivery for each subscription that
+// wants it. It is subscribed to the event broker; seeing an event twice does
+// not create duplicate deliveries.
+func (d *Dispatcher) HandleEvent(ctx context.Context, event *types.Event) error {
+	subs, err := d.store.Webhook.GetSubscriptions(ctx, bson.M{"events": event.Type})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, sub := range subs {
+		err := d.store.Webhook.EnqueueDelivery(ctx, &types.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Status:         types.DeliveryStatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeliverDue sends pending deliveries whose next attempt is due. It is meant
+// to be run by the scheduler.
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	deliveries, err := d.store.Webhook.GetDueDeliveries(ctx, time.Now(), batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if _, err := d.Deliver(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deliver makes one attempt at a delivery and records the outcome. A failed
+// attempt is rescheduled with exponential backoff until MaxAttempts is reached,
+// after which the delivery is moved to the dead-letter queue.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery *types.WebhookDelivery) (*types.WebhookDelivery, error) {
+	status, sendErr := d.send(ctx, delivery)
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+	switch {
+	case sendErr == nil:
+		delivery.Status = types.DeliveryStatusDelivered
+		delivery.DeliveredAt = now
+	case delivery.Attempts >= MaxAttempts || errors.Is(sendErr, errSubscriptionGone):
+		delivery.Status = types.DeliveryStatusDead
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.Status = types.DeliveryStatusPending
+		delivery.NextAttemptAt = now.Add(retry.Backoff(delivery.Attempts, baseBackoff, maxBackoff))
+		delivery.LastError = sendErr.Error()
+	}
+
+	update := bson.M{
+		"status":         delivery.Status,
+		"attempts":       delivery.Attempts,
+		"nextAttemptAt":  delivery.NextAttemptAt,
+		"responseStatus": delivery.ResponseStatus,
+		"lastError":      delivery.LastError,
+	}
+	if delivery.Status == types.DeliveryStatusDelivered {
+		update["deliveredAt"] = delivery.DeliveredAt
+	}
+	if err := d.store.Webhook.UpdateDelivery(ctx, delivery.ID, update); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Redeliver sends a delivery again right away, whatever its status. Dead
+// deliveries get a fresh set of attempts.
+func (d *Dispatcher) Redeliver(ctx context.Context, delivery *types.WebhookDelivery) (*types.WebhookDelivery, error) {
+	if delivery.Status == types.DeliveryStatusDead {
+		delivery.Attempts = 0
+	}
+	return d.Deliver(ctx, delivery)
+}
+
+var errSubscriptionGone = errors.New("subscription no longer exists")
+
+func (d *Dispatcher) send(ctx context.Context, delivery *types.WebhookDelivery) (int, error) {
+	subs, err := d.store.Webhook.GetSubscriptions(ctx, bson.M{"_id": delivery.SubscriptionID})
+	if err != nil {
+		return 0, err
+	}
+	if len(subs) == 0 {
+		return 0, errSubscriptionGone
+	}
+	sub := subs[0]
+
+	event, err := d.store.Event.GetEventByID(ctx, delivery.EventID)
+	if err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(Payload{
+		ID:         event.ID.Hex(),
+		Type:       event.Type,
+		OccurredAt: event.OccurredAt,
+		Data:       event.Data,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderDelivery, delivery.ID.Hex())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
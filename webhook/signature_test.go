@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	var (
+		body = []byte(`{"type":"booking.created"}`)
+		now  = time.Now().Unix()
+		ts   = strconv.FormatInt(now, 10)
+		sig  = Sign("secret", now, body)
+	)
+
+	if !Verify("secret", sig, ts, body, time.Minute) {
+		t.Fatal("expected a fresh signature to verify")
+	}
+	if Verify("other", sig, ts, body, time.Minute) {
+		t.Fatal("expected a signature made with another secret to be rejected")
+	}
+	if Verify("secret", sig, ts, []byte(`{"type":"booking.canceled"}`), time.Minute) {
+		t.Fatal("expected a tampered body to be rejected")
+	}
+
+	old := now - 3600
+	if Verify("secret", Sign("secret", old, body), strconv.FormatInt(old, 10), body, time.Minute) {
+		t.Fatal("expected a stale timestamp to be rejected")
+	}
+}